@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	daemon "github.com/openshift/dpu-operator/internal/daemon"
+	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
 	"github.com/openshift/dpu-operator/internal/platform"
 	"go.uber.org/zap/zapcore"
 
@@ -21,10 +25,63 @@ func main() {
 		Development: true,
 		Level:       zapcore.DebugLevel,
 	}
+	probe := flag.Bool("probe-device-plugin", false, "Check that the Device Plugin is registered and serving, then exit. Meant for the readiness probe.")
+	validate := flag.Bool("validate-device-plugin", false, "Check that the Device Plugin can listen on its socket directory and reach Kubelet without registering, print each step, then exit.")
+	logLevel := flag.String("log-level", envOrDefault("LOG_LEVEL", "debug"), "Log level: info, debug or a verbosity such as 2, defaults to $LOG_LEVEL or else debug.")
+	logFormat := flag.String("log-format", envOrDefault("LOG_FORMAT", "console"), "Log format: console, or json for the zap production configuration, defaults to $LOG_FORMAT or else console.")
+	vendorPluginSocket := flag.String("vendor-plugin-socket", os.Getenv("VENDOR_PLUGIN_SOCKET"), "Unix socket of the vendor plugin, defaults to $VENDOR_PLUGIN_SOCKET or else /var/run/dpu-daemon/vendor-plugin/vendor-plugin.sock.")
+	dpFlags := bindDevicePluginFlags(flag.CommandLine)
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+
+	level, err := parseLogLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// The zap flags, if given, take precedence.
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if !setFlags["zap-log-level"] {
+		opts.Level = level
+	}
+	switch *logFormat {
+	case "console":
+	case "json":
+		if !setFlags["zap-devel"] {
+			opts.Development = false
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown log format %q, expected console or json\n", *logFormat)
+		os.Exit(2)
+	}
+
+	if *probe {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := deviceplugin.ProbeHealth(ctx, utils.NewPathManager("/").PluginEndpoint()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if *validate {
+		report := deviceplugin.ValidateRegistration(*utils.NewPathManager("/"))
+		for _, step := range report.Steps {
+			if step.Error != "" {
+				fmt.Printf("%s: FAILED: %s\n", step.Name, step.Error)
+			} else {
+				fmt.Printf("%s: ok\n", step.Name)
+			}
+		}
+		if report.Err() != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	log := ctrl.Log.WithName("Daemon Init")
 	log.Info("Daemon init")
 
@@ -33,9 +90,46 @@ func main() {
 	nodeName := os.Getenv("K8S_NODE")
 
 	platform := &platform.HardwarePlatform{}
-	d := daemon.NewDaemon(afero.NewOsFs(), platform, ctrl.GetConfigOrDie(), imageManager, utils.NewPathManager("/"), nodeName)
+	var pathOpts []func(*utils.PathManager)
+	if *vendorPluginSocket != "" {
+		log.Info("Using the vendor plugin socket override", "path", *vendorPluginSocket)
+		pathOpts = append(pathOpts, utils.WithVendorPluginSocket(*vendorPluginSocket))
+	}
+	d := daemon.NewDaemon(afero.NewOsFs(), platform, ctrl.GetConfigOrDie(), imageManager, utils.NewPathManager("/", pathOpts...), nodeName)
+	devicePluginOpts, vendorPluginOpts := dpFlags.options(setFlags)
+	d.WithDevicePluginOptions(devicePluginOpts...).WithVendorPluginOptions(vendorPluginOpts...)
+	if dpFlags.metricsBindAddress != "0" {
+		go func() {
+			if err := deviceplugin.ServeMetrics(context.Background(), dpFlags.metricsBindAddress); err != nil {
+				log.Error(err, "Failed to serve the Device Plugin metrics")
+			}
+		}()
+	}
 	if err := d.PrepareAndServe(context.Background()); err != nil {
 		log.Error(err, "Failed to run daemon")
 		panic(err)
 	}
 }
+
+func envOrDefault(name, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return value
+}
+
+// parseLogLevel parses info, debug, or the verbosity of the V(n) messages
+// to log.
+func parseLogLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "debug":
+		return zapcore.DebugLevel, nil
+	}
+	verbosity, err := strconv.Atoi(level)
+	if err != nil || verbosity < 0 {
+		return 0, fmt.Errorf("invalid log level %q, expected info, debug or a verbosity", level)
+	}
+	return zapcore.Level(-verbosity), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+)
+
+// devicePluginFlags are the settings of the Device Plugins and of the vendor
+// plugin clients. Flags left unset keep the defaults of the Device Plugin.
+type devicePluginFlags struct {
+	freezeFile           string
+	readinessFile        string
+	readinessTimeout     time.Duration
+	readinessProceed     bool
+	allocationTTL        time.Duration
+	reclaimExpired       bool
+	validatorURL         string
+	validatorTimeout     time.Duration
+	validatorFailOpen    bool
+	reachabilityTarget   string
+	reachabilityInterval time.Duration
+	reservedDevices      string
+	reservedPercent      int
+	resetDevices         bool
+	eventSocket          string
+	healthCacheTTL       time.Duration
+	deviceReplicas       bool
+	resyncInterval       time.Duration
+
+	// metricsBindAddress is where the metrics are served, "0" disables
+	// serving them.
+	metricsBindAddress string
+}
+
+func bindDevicePluginFlags(fs *flag.FlagSet) *devicePluginFlags {
+	f := &devicePluginFlags{}
+	fs.StringVar(&f.freezeFile, "device-plugin-freeze-file", "", "Reject allocations while this file exists.")
+	fs.StringVar(&f.readinessFile, "device-plugin-readiness-file", "", "Don't register with Kubelet until this file exists.")
+	fs.DurationVar(&f.readinessTimeout, "device-plugin-readiness-timeout", 0, "How long to wait for the readiness file, 0 waits forever.")
+	fs.BoolVar(&f.readinessProceed, "device-plugin-readiness-proceed", false, "Register anyway once the readiness timeout expired.")
+	fs.DurationVar(&f.allocationTTL, "device-plugin-allocation-ttl", 0, "Flag allocations whose lease isn't renewed within this duration as expired, 0 disables leases.")
+	fs.BoolVar(&f.reclaimExpired, "device-plugin-reclaim-expired", false, "Release expired allocations.")
+	fs.StringVar(&f.validatorURL, "device-plugin-validator-url", "", "URL of an HTTP service that approves each allocation.")
+	fs.DurationVar(&f.validatorTimeout, "device-plugin-validator-timeout", 2*time.Second, "Timeout of the allocation validator.")
+	fs.BoolVar(&f.validatorFailOpen, "device-plugin-validator-fail-open", false, "Allow allocations when the validator can't be reached.")
+	fs.StringVar(&f.reachabilityTarget, "device-plugin-reachability-target", "", "Report devices that can't ping this address through their interface as unhealthy.")
+	fs.DurationVar(&f.reachabilityInterval, "device-plugin-reachability-interval", 30*time.Second, "How often each device pings the reachability target.")
+	fs.StringVar(&f.reservedDevices, "device-plugin-reserved-devices", "", "Comma-separated IDs of devices kept for the host.")
+	fs.IntVar(&f.reservedPercent, "device-plugin-reserved-percent", 0, "Percentage of the remaining devices kept for the host.")
+	fs.BoolVar(&f.resetDevices, "device-plugin-reset-devices", false, "Reset devices through the vendor plugin before their container starts.")
+	fs.StringVar(&f.eventSocket, "device-plugin-event-socket", "", "Unix datagram socket to send allocation and health events to.")
+	fs.DurationVar(&f.healthCacheTTL, "device-plugin-health-cache-ttl", 0, "How long the devices reported by the vendor plugin are reused, defaults to half the poll interval, 0 disables the cache.")
+	fs.DurationVar(&f.resyncInterval, "device-plugin-resync-interval", 0, "How often the devices are resynced with the vendor plugin besides the regular poll, defaults to 30s, a negative interval disables the resync.")
+	fs.BoolVar(&f.deviceReplicas, "device-replicas", false, "Share the devices the vendor plugin reports replicas for between that many containers.")
+	fs.StringVar(&f.metricsBindAddress, "device-plugin-metrics-bind-address", deviceplugin.DefaultMetricsBindAddress, "Address the Prometheus metrics of the Device Plugins are served on, 0 disables serving them.")
+	return f
+}
+
+// options returns the options of the given flags, set are the flags given on
+// the command line.
+func (f *devicePluginFlags) options(set map[string]bool) ([]deviceplugin.Option, []func(*plugin.GrpcPlugin)) {
+	var opts []deviceplugin.Option
+	if f.freezeFile != "" {
+		opts = append(opts, deviceplugin.WithFreezeFile(f.freezeFile))
+	}
+	if f.readinessFile != "" {
+		opts = append(opts, deviceplugin.WithReadinessFile(f.readinessFile, f.readinessTimeout, f.readinessProceed))
+	}
+	if f.allocationTTL > 0 {
+		opts = append(opts, deviceplugin.WithAllocationTTL(f.allocationTTL, f.reclaimExpired))
+	}
+	if f.validatorURL != "" {
+		opts = append(opts, deviceplugin.WithAllocateValidator(deviceplugin.NewHTTPAllocateValidator(f.validatorURL, f.validatorTimeout, f.validatorFailOpen)))
+	}
+	if f.reachabilityTarget != "" {
+		opts = append(opts, deviceplugin.WithHealthSource(deviceplugin.NewReachabilityHealthSource(f.reachabilityTarget, f.reachabilityInterval)))
+	}
+	if f.reservedDevices != "" {
+		opts = append(opts, deviceplugin.WithReservedDevices(strings.Split(f.reservedDevices, ",")...))
+	}
+	if f.reservedPercent > 0 {
+		opts = append(opts, deviceplugin.WithReservedPercentage(f.reservedPercent))
+	}
+	if f.resetDevices {
+		opts = append(opts, deviceplugin.WithDeviceReset(true))
+	}
+	if f.eventSocket != "" {
+		opts = append(opts, deviceplugin.WithEventSink(deviceplugin.NewDatagramEventSink(f.eventSocket)))
+	}
+	if set["device-plugin-health-cache-ttl"] {
+		opts = append(opts, deviceplugin.WithHealthCacheTTL(f.healthCacheTTL))
+	}
+	if f.resyncInterval != 0 {
+		opts = append(opts, deviceplugin.WithDevicesResync(f.resyncInterval))
+	}
+
+	var vendorOpts []func(*plugin.GrpcPlugin)
+	if f.deviceReplicas {
+		vendorOpts = append(vendorOpts, plugin.WithDeviceReplicas(true))
+	}
+	return opts, vendorOpts
+}
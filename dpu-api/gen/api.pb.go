@@ -73,6 +73,51 @@ func (x *InitRequest) GetDpuIdentifier() string {
 	return ""
 }
 
+type VersionInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// version is a semantic version, e.g. "1.2.0".
+	Version       string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionInfo) Reset() {
+	*x = VersionInfo{}
+	mi := &file_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionInfo) ProtoMessage() {}
+
+func (x *VersionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionInfo.ProtoReflect.Descriptor instead.
+func (*VersionInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VersionInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
 type IpPort struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
@@ -83,7 +128,7 @@ type IpPort struct {
 
 func (x *IpPort) Reset() {
 	*x = IpPort{}
-	mi := &file_api_proto_msgTypes[1]
+	mi := &file_api_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -95,7 +140,7 @@ func (x *IpPort) String() string {
 func (*IpPort) ProtoMessage() {}
 
 func (x *IpPort) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[1]
+	mi := &file_api_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -108,7 +153,7 @@ func (x *IpPort) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IpPort.ProtoReflect.Descriptor instead.
 func (*IpPort) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{1}
+	return file_api_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *IpPort) GetIp() string {
@@ -135,7 +180,7 @@ type NFRequest struct {
 
 func (x *NFRequest) Reset() {
 	*x = NFRequest{}
-	mi := &file_api_proto_msgTypes[2]
+	mi := &file_api_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -147,7 +192,7 @@ func (x *NFRequest) String() string {
 func (*NFRequest) ProtoMessage() {}
 
 func (x *NFRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[2]
+	mi := &file_api_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -160,7 +205,7 @@ func (x *NFRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NFRequest.ProtoReflect.Descriptor instead.
 func (*NFRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *NFRequest) GetInput() string {
@@ -185,7 +230,7 @@ type Empty struct {
 
 func (x *Empty) Reset() {
 	*x = Empty{}
-	mi := &file_api_proto_msgTypes[3]
+	mi := &file_api_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -197,7 +242,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[3]
+	mi := &file_api_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -210,7 +255,373 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{3}
+	return file_api_proto_rawDescGZIP(), []int{4}
+}
+
+// AnnotationTemplate maps annotation keys to values. Both are Go templates
+// rendered per allocated device with {{.ID}}, {{.PCIAddress}} and {{.Name}},
+// the ID made safe for use in annotation keys.
+type AnnotationTemplate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Annotations   map[string]string      `protobuf:"bytes,1,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnnotationTemplate) Reset() {
+	*x = AnnotationTemplate{}
+	mi := &file_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnnotationTemplate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnotationTemplate) ProtoMessage() {}
+
+func (x *AnnotationTemplate) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnotationTemplate.ProtoReflect.Descriptor instead.
+func (*AnnotationTemplate) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AnnotationTemplate) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+type AllocateInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateInfoRequest) Reset() {
+	*x = AllocateInfoRequest{}
+	mi := &file_api_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateInfoRequest) ProtoMessage() {}
+
+func (x *AllocateInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateInfoRequest.ProtoReflect.Descriptor instead.
+func (*AllocateInfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AllocateInfoRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type ResetDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetDeviceRequest) Reset() {
+	*x = ResetDeviceRequest{}
+	mi := &file_api_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetDeviceRequest) ProtoMessage() {}
+
+func (x *ResetDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetDeviceRequest.ProtoReflect.Descriptor instead.
+func (*ResetDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResetDeviceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+// DeviceNode is a device node of the host made available in the container.
+type DeviceNode struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	HostPath string                 `protobuf:"bytes,1,opt,name=host_path,json=hostPath,proto3" json:"host_path,omitempty"`
+	// container_path defaults to host_path.
+	ContainerPath string `protobuf:"bytes,2,opt,name=container_path,json=containerPath,proto3" json:"container_path,omitempty"`
+	// permissions are the cgroup permissions, any of "r", "w" and "m",
+	// defaulting to "rw".
+	Permissions   string `protobuf:"bytes,3,opt,name=permissions,proto3" json:"permissions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceNode) Reset() {
+	*x = DeviceNode{}
+	mi := &file_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceNode) ProtoMessage() {}
+
+func (x *DeviceNode) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceNode.ProtoReflect.Descriptor instead.
+func (*DeviceNode) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeviceNode) GetHostPath() string {
+	if x != nil {
+		return x.HostPath
+	}
+	return ""
+}
+
+func (x *DeviceNode) GetContainerPath() string {
+	if x != nil {
+		return x.ContainerPath
+	}
+	return ""
+}
+
+func (x *DeviceNode) GetPermissions() string {
+	if x != nil {
+		return x.Permissions
+	}
+	return ""
+}
+
+// Mount is a path of the host mounted into the container, e.g. sysfs entries
+// of the device.
+type Mount struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	HostPath string                 `protobuf:"bytes,1,opt,name=host_path,json=hostPath,proto3" json:"host_path,omitempty"`
+	// container_path defaults to host_path.
+	ContainerPath string `protobuf:"bytes,2,opt,name=container_path,json=containerPath,proto3" json:"container_path,omitempty"`
+	ReadOnly      bool   `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Mount) Reset() {
+	*x = Mount{}
+	mi := &file_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Mount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mount) ProtoMessage() {}
+
+func (x *Mount) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mount.ProtoReflect.Descriptor instead.
+func (*Mount) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Mount) GetHostPath() string {
+	if x != nil {
+		return x.HostPath
+	}
+	return ""
+}
+
+func (x *Mount) GetContainerPath() string {
+	if x != nil {
+		return x.ContainerPath
+	}
+	return ""
+}
+
+func (x *Mount) GetReadOnly() bool {
+	if x != nil {
+		return x.ReadOnly
+	}
+	return false
+}
+
+type AllocateInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*DeviceNode          `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	Mounts        []*Mount               `protobuf:"bytes,2,rep,name=mounts,proto3" json:"mounts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateInfo) Reset() {
+	*x = AllocateInfo{}
+	mi := &file_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateInfo) ProtoMessage() {}
+
+func (x *AllocateInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateInfo.ProtoReflect.Descriptor instead.
+func (*AllocateInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AllocateInfo) GetDevices() []*DeviceNode {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *AllocateInfo) GetMounts() []*Mount {
+	if x != nil {
+		return x.Mounts
+	}
+	return nil
+}
+
+type DeviceListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceListRequest) Reset() {
+	*x = DeviceListRequest{}
+	mi := &file_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceListRequest) ProtoMessage() {}
+
+func (x *DeviceListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceListRequest.ProtoReflect.Descriptor instead.
+func (*DeviceListRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeviceListRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *DeviceListRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
 }
 
 type VfCount struct {
@@ -222,7 +633,7 @@ type VfCount struct {
 
 func (x *VfCount) Reset() {
 	*x = VfCount{}
-	mi := &file_api_proto_msgTypes[4]
+	mi := &file_api_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -234,7 +645,7 @@ func (x *VfCount) String() string {
 func (*VfCount) ProtoMessage() {}
 
 func (x *VfCount) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[4]
+	mi := &file_api_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -247,7 +658,7 @@ func (x *VfCount) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VfCount.ProtoReflect.Descriptor instead.
 func (*VfCount) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *VfCount) GetVfCnt() int32 {
@@ -266,7 +677,7 @@ type TopologyInfo struct {
 
 func (x *TopologyInfo) Reset() {
 	*x = TopologyInfo{}
-	mi := &file_api_proto_msgTypes[5]
+	mi := &file_api_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -278,7 +689,7 @@ func (x *TopologyInfo) String() string {
 func (*TopologyInfo) ProtoMessage() {}
 
 func (x *TopologyInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[5]
+	mi := &file_api_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -291,7 +702,7 @@ func (x *TopologyInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TopologyInfo.ProtoReflect.Descriptor instead.
 func (*TopologyInfo) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *TopologyInfo) GetNode() string {
@@ -302,17 +713,47 @@ func (x *TopologyInfo) GetNode() string {
 }
 
 type Device struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ID            string                 `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Health        string                 `protobuf:"bytes,2,opt,name=health,proto3" json:"health,omitempty"`
-	Topology      *TopologyInfo          `protobuf:"bytes,3,opt,name=topology,proto3" json:"topology,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ID       string                 `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Health   string                 `protobuf:"bytes,2,opt,name=health,proto3" json:"health,omitempty"`
+	Topology *TopologyInfo          `protobuf:"bytes,3,opt,name=topology,proto3" json:"topology,omitempty"`
+	// attributes are free-form properties of the device. They are passed to
+	// the containers the device is allocated to and recorded in its device
+	// info file, so that the CNI can match on them. The well-known keys are
+	// "linkSpeed" (Mb/s), "pfIndex", "vfIndex", "pciAddress" and "pool".
+	Attributes map[string]string `protobuf:"bytes,4,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// capabilities are the kernel or firmware features the device supports,
+	// e.g. "ipsec-offload".
+	Capabilities []string `protobuf:"bytes,5,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// numa_node is the NUMA node the device is attached to, unset when the
+	// affinity is unknown.
+	NumaNode *int32 `protobuf:"varint,6,opt,name=numa_node,json=numaNode,proto3,oneof" json:"numa_node,omitempty"`
+	// locality_group groups devices that perform best when allocated
+	// together, e.g. the devices behind the same PCIe switch. Empty when the
+	// device isn't part of a group.
+	LocalityGroup string `protobuf:"bytes,7,opt,name=locality_group,json=localityGroup,proto3" json:"locality_group,omitempty"`
+	// pool is the resource the device is advertised as, openshift.io/<pool>,
+	// e.g. "mgmt" for management NICs and "data" for data-plane VFs. Empty
+	// for the default resource, openshift.io/dpu.
+	Pool string `protobuf:"bytes,8,opt,name=pool,proto3" json:"pool,omitempty"`
+	// role is the function the device serves: "data" for data-plane
+	// functions such as VFs, "management" for the management functions of the
+	// DPU. Empty when the vendor plugin doesn't tell. See
+	// plugin.WithDeviceRoles to only advertise some roles.
+	Role string `protobuf:"bytes,9,opt,name=role,proto3" json:"role,omitempty"`
+	// replicas is how many containers may share the device, e.g. one per
+	// queue pair. The device is then advertised as that many logical devices
+	// "<ID>_r<n>", which are allocated like exclusive devices but get the
+	// device nodes and mounts of the shared device. 0 and 1 mean the device
+	// isn't shared. Only honored with plugin.WithDeviceReplicas.
+	Replicas      int32 `protobuf:"varint,10,opt,name=replicas,proto3" json:"replicas,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Device) Reset() {
 	*x = Device{}
-	mi := &file_api_proto_msgTypes[6]
+	mi := &file_api_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -324,7 +765,7 @@ func (x *Device) String() string {
 func (*Device) ProtoMessage() {}
 
 func (x *Device) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[6]
+	mi := &file_api_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -337,7 +778,7 @@ func (x *Device) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Device.ProtoReflect.Descriptor instead.
 func (*Device) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *Device) GetID() string {
@@ -361,16 +802,67 @@ func (x *Device) GetTopology() *TopologyInfo {
 	return nil
 }
 
+func (x *Device) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *Device) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Device) GetNumaNode() int32 {
+	if x != nil && x.NumaNode != nil {
+		return *x.NumaNode
+	}
+	return 0
+}
+
+func (x *Device) GetLocalityGroup() string {
+	if x != nil {
+		return x.LocalityGroup
+	}
+	return ""
+}
+
+func (x *Device) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *Device) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Device) GetReplicas() int32 {
+	if x != nil {
+		return x.Replicas
+	}
+	return 0
+}
+
 type DeviceListResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Devices       map[string]*Device     `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Devices map[string]*Device     `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// next_page_token is empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeviceListResponse) Reset() {
 	*x = DeviceListResponse{}
-	mi := &file_api_proto_msgTypes[7]
+	mi := &file_api_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -382,7 +874,7 @@ func (x *DeviceListResponse) String() string {
 func (*DeviceListResponse) ProtoMessage() {}
 
 func (x *DeviceListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[7]
+	mi := &file_api_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -395,7 +887,7 @@ func (x *DeviceListResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceListResponse.ProtoReflect.Descriptor instead.
 func (*DeviceListResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *DeviceListResponse) GetDevices() map[string]*Device {
@@ -405,6 +897,13 @@ func (x *DeviceListResponse) GetDevices() map[string]*Device {
 	return nil
 }
 
+func (x *DeviceListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 type PingRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
@@ -415,7 +914,7 @@ type PingRequest struct {
 
 func (x *PingRequest) Reset() {
 	*x = PingRequest{}
-	mi := &file_api_proto_msgTypes[8]
+	mi := &file_api_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -427,7 +926,7 @@ func (x *PingRequest) String() string {
 func (*PingRequest) ProtoMessage() {}
 
 func (x *PingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[8]
+	mi := &file_api_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -440,7 +939,7 @@ func (x *PingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
 func (*PingRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *PingRequest) GetTimestamp() int64 {
@@ -468,7 +967,7 @@ type PingResponse struct {
 
 func (x *PingResponse) Reset() {
 	*x = PingResponse{}
-	mi := &file_api_proto_msgTypes[9]
+	mi := &file_api_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -480,7 +979,7 @@ func (x *PingResponse) String() string {
 func (*PingResponse) ProtoMessage() {}
 
 func (x *PingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[9]
+	mi := &file_api_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -493,7 +992,7 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
 func (*PingResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{9}
+	return file_api_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *PingResponse) GetTimestamp() int64 {
@@ -524,24 +1023,67 @@ const file_api_proto_rawDesc = "" +
 	"\tapi.proto\x12\x06Vendor\"O\n" +
 	"\vInitRequest\x12\x19\n" +
 	"\bdpu_mode\x18\x01 \x01(\bR\adpuMode\x12%\n" +
-	"\x0edpu_identifier\x18\x02 \x01(\tR\rdpuIdentifier\",\n" +
+	"\x0edpu_identifier\x18\x02 \x01(\tR\rdpuIdentifier\"'\n" +
+	"\vVersionInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\",\n" +
 	"\x06IpPort\x12\x0e\n" +
 	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x12\n" +
 	"\x04port\x18\x02 \x01(\x05R\x04port\"9\n" +
 	"\tNFRequest\x12\x14\n" +
 	"\x05input\x18\x01 \x01(\tR\x05input\x12\x16\n" +
 	"\x06output\x18\x02 \x01(\tR\x06output\"\a\n" +
-	"\x05Empty\" \n" +
+	"\x05Empty\"\xa3\x01\n" +
+	"\x12AnnotationTemplate\x12M\n" +
+	"\vannotations\x18\x01 \x03(\v2+.Vendor.AnnotationTemplate.AnnotationsEntryR\vannotations\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"2\n" +
+	"\x13AllocateInfoRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\"1\n" +
+	"\x12ResetDeviceRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\"r\n" +
+	"\n" +
+	"DeviceNode\x12\x1b\n" +
+	"\thost_path\x18\x01 \x01(\tR\bhostPath\x12%\n" +
+	"\x0econtainer_path\x18\x02 \x01(\tR\rcontainerPath\x12 \n" +
+	"\vpermissions\x18\x03 \x01(\tR\vpermissions\"h\n" +
+	"\x05Mount\x12\x1b\n" +
+	"\thost_path\x18\x01 \x01(\tR\bhostPath\x12%\n" +
+	"\x0econtainer_path\x18\x02 \x01(\tR\rcontainerPath\x12\x1b\n" +
+	"\tread_only\x18\x03 \x01(\bR\breadOnly\"c\n" +
+	"\fAllocateInfo\x12,\n" +
+	"\adevices\x18\x01 \x03(\v2\x12.Vendor.DeviceNodeR\adevices\x12%\n" +
+	"\x06mounts\x18\x02 \x03(\v2\r.Vendor.MountR\x06mounts\"O\n" +
+	"\x11DeviceListRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\" \n" +
 	"\aVfCount\x12\x15\n" +
 	"\x06vf_cnt\x18\x01 \x01(\x05R\x05vfCnt\"\"\n" +
 	"\fTopologyInfo\x12\x12\n" +
-	"\x04node\x18\x01 \x01(\tR\x04node\"b\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\"\xa0\x03\n" +
 	"\x06Device\x12\x0e\n" +
 	"\x02ID\x18\x01 \x01(\tR\x02ID\x12\x16\n" +
 	"\x06health\x18\x02 \x01(\tR\x06health\x120\n" +
-	"\btopology\x18\x03 \x01(\v2\x14.Vendor.TopologyInfoR\btopology\"\xa3\x01\n" +
+	"\btopology\x18\x03 \x01(\v2\x14.Vendor.TopologyInfoR\btopology\x12>\n" +
+	"\n" +
+	"attributes\x18\x04 \x03(\v2\x1e.Vendor.Device.AttributesEntryR\n" +
+	"attributes\x12\"\n" +
+	"\fcapabilities\x18\x05 \x03(\tR\fcapabilities\x12 \n" +
+	"\tnuma_node\x18\x06 \x01(\x05H\x00R\bnumaNode\x88\x01\x01\x12%\n" +
+	"\x0elocality_group\x18\a \x01(\tR\rlocalityGroup\x12\x12\n" +
+	"\x04pool\x18\b \x01(\tR\x04pool\x12\x12\n" +
+	"\x04role\x18\t \x01(\tR\x04role\x12\x1a\n" +
+	"\breplicas\x18\n" +
+	" \x01(\x05R\breplicas\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\f\n" +
+	"\n" +
+	"_numa_node\"\xcb\x01\n" +
 	"\x12DeviceListResponse\x12A\n" +
-	"\adevices\x18\x01 \x03(\v2'.Vendor.DeviceListResponse.DevicesEntryR\adevices\x1aJ\n" +
+	"\adevices\x18\x01 \x03(\v2'.Vendor.DeviceListResponse.DevicesEntryR\adevices\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x1aJ\n" +
 	"\fDevicesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
 	"\x05value\x18\x02 \x01(\v2\x0e.Vendor.DeviceR\x05value:\x028\x01\"H\n" +
@@ -551,16 +1093,23 @@ const file_api_proto_rawDesc = "" +
 	"\fPingResponse\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12!\n" +
 	"\fresponder_id\x18\x02 \x01(\tR\vresponderId\x12\x18\n" +
-	"\ahealthy\x18\x03 \x01(\bR\ahealthy2?\n" +
+	"\ahealthy\x18\x03 \x01(\bR\ahealthy2q\n" +
 	"\x10LifeCycleService\x12+\n" +
-	"\x04Init\x12\x13.Vendor.InitRequest\x1a\x0e.Vendor.IpPort2\x8e\x01\n" +
+	"\x04Init\x12\x13.Vendor.InitRequest\x1a\x0e.Vendor.IpPort\x120\n" +
+	"\n" +
+	"GetVersion\x12\r.Vendor.Empty\x1a\x13.Vendor.VersionInfo2\x8e\x01\n" +
 	"\x16NetworkFunctionService\x129\n" +
 	"\x15CreateNetworkFunction\x12\x11.Vendor.NFRequest\x1a\r.Vendor.Empty\x129\n" +
-	"\x15DeleteNetworkFunction\x12\x11.Vendor.NFRequest\x1a\r.Vendor.Empty2w\n" +
+	"\x15DeleteNetworkFunction\x12\x11.Vendor.NFRequest\x1a\r.Vendor.Empty2\xd1\x03\n" +
 	"\rDeviceService\x127\n" +
 	"\n" +
 	"GetDevices\x12\r.Vendor.Empty\x1a\x1a.Vendor.DeviceListResponse\x12-\n" +
-	"\tSetNumVfs\x12\x0f.Vendor.VfCount\x1a\x0f.Vendor.VfCount2E\n" +
+	"\tSetNumVfs\x12\x0f.Vendor.VfCount\x1a\x0f.Vendor.VfCount\x12G\n" +
+	"\x0eGetDevicesPage\x12\x19.Vendor.DeviceListRequest\x1a\x1a.Vendor.DeviceListResponse\x12K\n" +
+	"\x10GetDevicesStream\x12\x19.Vendor.DeviceListRequest\x1a\x1a.Vendor.DeviceListResponse0\x01\x12B\n" +
+	"\x15GetAnnotationTemplate\x12\r.Vendor.Empty\x1a\x1a.Vendor.AnnotationTemplate\x12D\n" +
+	"\x0fGetAllocateInfo\x12\x1b.Vendor.AllocateInfoRequest\x1a\x14.Vendor.AllocateInfo\x128\n" +
+	"\vResetDevice\x12\x1a.Vendor.ResetDeviceRequest\x1a\r.Vendor.Empty2E\n" +
 	"\x10HeartbeatService\x121\n" +
 	"\x04Ping\x12\x13.Vendor.PingRequest\x1a\x14.Vendor.PingResponseB/Z-github.com/openshift/dpu-operator/api/dpu-apib\x06proto3"
 
@@ -576,41 +1125,67 @@ func file_api_proto_rawDescGZIP() []byte {
 	return file_api_proto_rawDescData
 }
 
-var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_api_proto_goTypes = []any{
-	(*InitRequest)(nil),        // 0: Vendor.InitRequest
-	(*IpPort)(nil),             // 1: Vendor.IpPort
-	(*NFRequest)(nil),          // 2: Vendor.NFRequest
-	(*Empty)(nil),              // 3: Vendor.Empty
-	(*VfCount)(nil),            // 4: Vendor.VfCount
-	(*TopologyInfo)(nil),       // 5: Vendor.TopologyInfo
-	(*Device)(nil),             // 6: Vendor.Device
-	(*DeviceListResponse)(nil), // 7: Vendor.DeviceListResponse
-	(*PingRequest)(nil),        // 8: Vendor.PingRequest
-	(*PingResponse)(nil),       // 9: Vendor.PingResponse
-	nil,                        // 10: Vendor.DeviceListResponse.DevicesEntry
+	(*InitRequest)(nil),         // 0: Vendor.InitRequest
+	(*VersionInfo)(nil),         // 1: Vendor.VersionInfo
+	(*IpPort)(nil),              // 2: Vendor.IpPort
+	(*NFRequest)(nil),           // 3: Vendor.NFRequest
+	(*Empty)(nil),               // 4: Vendor.Empty
+	(*AnnotationTemplate)(nil),  // 5: Vendor.AnnotationTemplate
+	(*AllocateInfoRequest)(nil), // 6: Vendor.AllocateInfoRequest
+	(*ResetDeviceRequest)(nil),  // 7: Vendor.ResetDeviceRequest
+	(*DeviceNode)(nil),          // 8: Vendor.DeviceNode
+	(*Mount)(nil),               // 9: Vendor.Mount
+	(*AllocateInfo)(nil),        // 10: Vendor.AllocateInfo
+	(*DeviceListRequest)(nil),   // 11: Vendor.DeviceListRequest
+	(*VfCount)(nil),             // 12: Vendor.VfCount
+	(*TopologyInfo)(nil),        // 13: Vendor.TopologyInfo
+	(*Device)(nil),              // 14: Vendor.Device
+	(*DeviceListResponse)(nil),  // 15: Vendor.DeviceListResponse
+	(*PingRequest)(nil),         // 16: Vendor.PingRequest
+	(*PingResponse)(nil),        // 17: Vendor.PingResponse
+	nil,                         // 18: Vendor.AnnotationTemplate.AnnotationsEntry
+	nil,                         // 19: Vendor.Device.AttributesEntry
+	nil,                         // 20: Vendor.DeviceListResponse.DevicesEntry
 }
 var file_api_proto_depIdxs = []int32{
-	5,  // 0: Vendor.Device.topology:type_name -> Vendor.TopologyInfo
-	10, // 1: Vendor.DeviceListResponse.devices:type_name -> Vendor.DeviceListResponse.DevicesEntry
-	6,  // 2: Vendor.DeviceListResponse.DevicesEntry.value:type_name -> Vendor.Device
-	0,  // 3: Vendor.LifeCycleService.Init:input_type -> Vendor.InitRequest
-	2,  // 4: Vendor.NetworkFunctionService.CreateNetworkFunction:input_type -> Vendor.NFRequest
-	2,  // 5: Vendor.NetworkFunctionService.DeleteNetworkFunction:input_type -> Vendor.NFRequest
-	3,  // 6: Vendor.DeviceService.GetDevices:input_type -> Vendor.Empty
-	4,  // 7: Vendor.DeviceService.SetNumVfs:input_type -> Vendor.VfCount
-	8,  // 8: Vendor.HeartbeatService.Ping:input_type -> Vendor.PingRequest
-	1,  // 9: Vendor.LifeCycleService.Init:output_type -> Vendor.IpPort
-	3,  // 10: Vendor.NetworkFunctionService.CreateNetworkFunction:output_type -> Vendor.Empty
-	3,  // 11: Vendor.NetworkFunctionService.DeleteNetworkFunction:output_type -> Vendor.Empty
-	7,  // 12: Vendor.DeviceService.GetDevices:output_type -> Vendor.DeviceListResponse
-	4,  // 13: Vendor.DeviceService.SetNumVfs:output_type -> Vendor.VfCount
-	9,  // 14: Vendor.HeartbeatService.Ping:output_type -> Vendor.PingResponse
-	9,  // [9:15] is the sub-list for method output_type
-	3,  // [3:9] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	18, // 0: Vendor.AnnotationTemplate.annotations:type_name -> Vendor.AnnotationTemplate.AnnotationsEntry
+	8,  // 1: Vendor.AllocateInfo.devices:type_name -> Vendor.DeviceNode
+	9,  // 2: Vendor.AllocateInfo.mounts:type_name -> Vendor.Mount
+	13, // 3: Vendor.Device.topology:type_name -> Vendor.TopologyInfo
+	19, // 4: Vendor.Device.attributes:type_name -> Vendor.Device.AttributesEntry
+	20, // 5: Vendor.DeviceListResponse.devices:type_name -> Vendor.DeviceListResponse.DevicesEntry
+	14, // 6: Vendor.DeviceListResponse.DevicesEntry.value:type_name -> Vendor.Device
+	0,  // 7: Vendor.LifeCycleService.Init:input_type -> Vendor.InitRequest
+	4,  // 8: Vendor.LifeCycleService.GetVersion:input_type -> Vendor.Empty
+	3,  // 9: Vendor.NetworkFunctionService.CreateNetworkFunction:input_type -> Vendor.NFRequest
+	3,  // 10: Vendor.NetworkFunctionService.DeleteNetworkFunction:input_type -> Vendor.NFRequest
+	4,  // 11: Vendor.DeviceService.GetDevices:input_type -> Vendor.Empty
+	12, // 12: Vendor.DeviceService.SetNumVfs:input_type -> Vendor.VfCount
+	11, // 13: Vendor.DeviceService.GetDevicesPage:input_type -> Vendor.DeviceListRequest
+	11, // 14: Vendor.DeviceService.GetDevicesStream:input_type -> Vendor.DeviceListRequest
+	4,  // 15: Vendor.DeviceService.GetAnnotationTemplate:input_type -> Vendor.Empty
+	6,  // 16: Vendor.DeviceService.GetAllocateInfo:input_type -> Vendor.AllocateInfoRequest
+	7,  // 17: Vendor.DeviceService.ResetDevice:input_type -> Vendor.ResetDeviceRequest
+	16, // 18: Vendor.HeartbeatService.Ping:input_type -> Vendor.PingRequest
+	2,  // 19: Vendor.LifeCycleService.Init:output_type -> Vendor.IpPort
+	1,  // 20: Vendor.LifeCycleService.GetVersion:output_type -> Vendor.VersionInfo
+	4,  // 21: Vendor.NetworkFunctionService.CreateNetworkFunction:output_type -> Vendor.Empty
+	4,  // 22: Vendor.NetworkFunctionService.DeleteNetworkFunction:output_type -> Vendor.Empty
+	15, // 23: Vendor.DeviceService.GetDevices:output_type -> Vendor.DeviceListResponse
+	12, // 24: Vendor.DeviceService.SetNumVfs:output_type -> Vendor.VfCount
+	15, // 25: Vendor.DeviceService.GetDevicesPage:output_type -> Vendor.DeviceListResponse
+	15, // 26: Vendor.DeviceService.GetDevicesStream:output_type -> Vendor.DeviceListResponse
+	5,  // 27: Vendor.DeviceService.GetAnnotationTemplate:output_type -> Vendor.AnnotationTemplate
+	10, // 28: Vendor.DeviceService.GetAllocateInfo:output_type -> Vendor.AllocateInfo
+	4,  // 29: Vendor.DeviceService.ResetDevice:output_type -> Vendor.Empty
+	17, // 30: Vendor.HeartbeatService.Ping:output_type -> Vendor.PingResponse
+	19, // [19:31] is the sub-list for method output_type
+	7,  // [7:19] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_init() }
@@ -618,13 +1193,14 @@ func file_api_proto_init() {
 	if File_api_proto != nil {
 		return
 	}
+	file_api_proto_msgTypes[14].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_rawDesc), len(file_api_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   4,
 		},
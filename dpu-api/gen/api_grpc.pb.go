@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	LifeCycleService_Init_FullMethodName = "/Vendor.LifeCycleService/Init"
+	LifeCycleService_Init_FullMethodName       = "/Vendor.LifeCycleService/Init"
+	LifeCycleService_GetVersion_FullMethodName = "/Vendor.LifeCycleService/GetVersion"
 )
 
 // LifeCycleServiceClient is the client API for LifeCycleService service.
@@ -27,6 +28,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type LifeCycleServiceClient interface {
 	Init(ctx context.Context, in *InitRequest, opts ...grpc.CallOption) (*IpPort, error)
+	// GetVersion returns the version of the vendor plugin, so that the daemon
+	// can refuse to work with known-broken vendor plugins.
+	GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionInfo, error)
 }
 
 type lifeCycleServiceClient struct {
@@ -47,11 +51,24 @@ func (c *lifeCycleServiceClient) Init(ctx context.Context, in *InitRequest, opts
 	return out, nil
 }
 
+func (c *lifeCycleServiceClient) GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionInfo)
+	err := c.cc.Invoke(ctx, LifeCycleService_GetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // LifeCycleServiceServer is the server API for LifeCycleService service.
 // All implementations must embed UnimplementedLifeCycleServiceServer
 // for forward compatibility.
 type LifeCycleServiceServer interface {
 	Init(context.Context, *InitRequest) (*IpPort, error)
+	// GetVersion returns the version of the vendor plugin, so that the daemon
+	// can refuse to work with known-broken vendor plugins.
+	GetVersion(context.Context, *Empty) (*VersionInfo, error)
 	mustEmbedUnimplementedLifeCycleServiceServer()
 }
 
@@ -65,6 +82,9 @@ type UnimplementedLifeCycleServiceServer struct{}
 func (UnimplementedLifeCycleServiceServer) Init(context.Context, *InitRequest) (*IpPort, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Init not implemented")
 }
+func (UnimplementedLifeCycleServiceServer) GetVersion(context.Context, *Empty) (*VersionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
 func (UnimplementedLifeCycleServiceServer) mustEmbedUnimplementedLifeCycleServiceServer() {}
 func (UnimplementedLifeCycleServiceServer) testEmbeddedByValue()                          {}
 
@@ -104,6 +124,24 @@ func _LifeCycleService_Init_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LifeCycleService_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LifeCycleServiceServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LifeCycleService_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LifeCycleServiceServer).GetVersion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // LifeCycleService_ServiceDesc is the grpc.ServiceDesc for LifeCycleService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +153,10 @@ var LifeCycleService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Init",
 			Handler:    _LifeCycleService_Init_Handler,
 		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _LifeCycleService_GetVersion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
@@ -262,8 +304,13 @@ var NetworkFunctionService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	DeviceService_GetDevices_FullMethodName = "/Vendor.DeviceService/GetDevices"
-	DeviceService_SetNumVfs_FullMethodName  = "/Vendor.DeviceService/SetNumVfs"
+	DeviceService_GetDevices_FullMethodName            = "/Vendor.DeviceService/GetDevices"
+	DeviceService_SetNumVfs_FullMethodName             = "/Vendor.DeviceService/SetNumVfs"
+	DeviceService_GetDevicesPage_FullMethodName        = "/Vendor.DeviceService/GetDevicesPage"
+	DeviceService_GetDevicesStream_FullMethodName      = "/Vendor.DeviceService/GetDevicesStream"
+	DeviceService_GetAnnotationTemplate_FullMethodName = "/Vendor.DeviceService/GetAnnotationTemplate"
+	DeviceService_GetAllocateInfo_FullMethodName       = "/Vendor.DeviceService/GetAllocateInfo"
+	DeviceService_ResetDevice_FullMethodName           = "/Vendor.DeviceService/ResetDevice"
 )
 
 // DeviceServiceClient is the client API for DeviceService service.
@@ -272,6 +319,26 @@ const (
 type DeviceServiceClient interface {
 	GetDevices(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DeviceListResponse, error)
 	SetNumVfs(ctx context.Context, in *VfCount, opts ...grpc.CallOption) (*VfCount, error)
+	// GetDevicesPage is the paginated variant of GetDevices for vendor plugins
+	// exposing large inventories. Vendor plugins that don't implement it are
+	// queried with GetDevices instead.
+	GetDevicesPage(ctx context.Context, in *DeviceListRequest, opts ...grpc.CallOption) (*DeviceListResponse, error)
+	// GetDevicesStream sends the inventory in batches of up to page_size
+	// devices, page_token is ignored. It's preferred over GetDevicesPage as
+	// the vendor plugin doesn't need to keep state between requests.
+	GetDevicesStream(ctx context.Context, in *DeviceListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DeviceListResponse], error)
+	// GetAnnotationTemplate returns the annotations to set on containers for
+	// each allocated device, e.g. to trigger OCI hooks of the container runtime.
+	GetAnnotationTemplate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AnnotationTemplate, error)
+	// GetAllocateInfo returns the device nodes and mounts a container needs to
+	// use the device. Vendor plugins that don't implement it pass nothing but
+	// the environment.
+	GetAllocateInfo(ctx context.Context, in *AllocateInfoRequest, opts ...grpc.CallOption) (*AllocateInfo, error)
+	// ResetDevice brings an allocated device back to a clean state, e.g.
+	// clears its queues and firmware context, right before a container using
+	// it starts. Failing it keeps the container from starting. Only called
+	// when the Device Plugin is configured to reset devices.
+	ResetDevice(ctx context.Context, in *ResetDeviceRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type deviceServiceClient struct {
@@ -302,12 +369,91 @@ func (c *deviceServiceClient) SetNumVfs(ctx context.Context, in *VfCount, opts .
 	return out, nil
 }
 
+func (c *deviceServiceClient) GetDevicesPage(ctx context.Context, in *DeviceListRequest, opts ...grpc.CallOption) (*DeviceListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeviceListResponse)
+	err := c.cc.Invoke(ctx, DeviceService_GetDevicesPage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deviceServiceClient) GetDevicesStream(ctx context.Context, in *DeviceListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DeviceListResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DeviceService_ServiceDesc.Streams[0], DeviceService_GetDevicesStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DeviceListRequest, DeviceListResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeviceService_GetDevicesStreamClient = grpc.ServerStreamingClient[DeviceListResponse]
+
+func (c *deviceServiceClient) GetAnnotationTemplate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AnnotationTemplate, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnnotationTemplate)
+	err := c.cc.Invoke(ctx, DeviceService_GetAnnotationTemplate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deviceServiceClient) GetAllocateInfo(ctx context.Context, in *AllocateInfoRequest, opts ...grpc.CallOption) (*AllocateInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateInfo)
+	err := c.cc.Invoke(ctx, DeviceService_GetAllocateInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deviceServiceClient) ResetDevice(ctx context.Context, in *ResetDeviceRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, DeviceService_ResetDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DeviceServiceServer is the server API for DeviceService service.
 // All implementations must embed UnimplementedDeviceServiceServer
 // for forward compatibility.
 type DeviceServiceServer interface {
 	GetDevices(context.Context, *Empty) (*DeviceListResponse, error)
 	SetNumVfs(context.Context, *VfCount) (*VfCount, error)
+	// GetDevicesPage is the paginated variant of GetDevices for vendor plugins
+	// exposing large inventories. Vendor plugins that don't implement it are
+	// queried with GetDevices instead.
+	GetDevicesPage(context.Context, *DeviceListRequest) (*DeviceListResponse, error)
+	// GetDevicesStream sends the inventory in batches of up to page_size
+	// devices, page_token is ignored. It's preferred over GetDevicesPage as
+	// the vendor plugin doesn't need to keep state between requests.
+	GetDevicesStream(*DeviceListRequest, grpc.ServerStreamingServer[DeviceListResponse]) error
+	// GetAnnotationTemplate returns the annotations to set on containers for
+	// each allocated device, e.g. to trigger OCI hooks of the container runtime.
+	GetAnnotationTemplate(context.Context, *Empty) (*AnnotationTemplate, error)
+	// GetAllocateInfo returns the device nodes and mounts a container needs to
+	// use the device. Vendor plugins that don't implement it pass nothing but
+	// the environment.
+	GetAllocateInfo(context.Context, *AllocateInfoRequest) (*AllocateInfo, error)
+	// ResetDevice brings an allocated device back to a clean state, e.g.
+	// clears its queues and firmware context, right before a container using
+	// it starts. Failing it keeps the container from starting. Only called
+	// when the Device Plugin is configured to reset devices.
+	ResetDevice(context.Context, *ResetDeviceRequest) (*Empty, error)
 	mustEmbedUnimplementedDeviceServiceServer()
 }
 
@@ -324,6 +470,21 @@ func (UnimplementedDeviceServiceServer) GetDevices(context.Context, *Empty) (*De
 func (UnimplementedDeviceServiceServer) SetNumVfs(context.Context, *VfCount) (*VfCount, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetNumVfs not implemented")
 }
+func (UnimplementedDeviceServiceServer) GetDevicesPage(context.Context, *DeviceListRequest) (*DeviceListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDevicesPage not implemented")
+}
+func (UnimplementedDeviceServiceServer) GetDevicesStream(*DeviceListRequest, grpc.ServerStreamingServer[DeviceListResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method GetDevicesStream not implemented")
+}
+func (UnimplementedDeviceServiceServer) GetAnnotationTemplate(context.Context, *Empty) (*AnnotationTemplate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAnnotationTemplate not implemented")
+}
+func (UnimplementedDeviceServiceServer) GetAllocateInfo(context.Context, *AllocateInfoRequest) (*AllocateInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllocateInfo not implemented")
+}
+func (UnimplementedDeviceServiceServer) ResetDevice(context.Context, *ResetDeviceRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetDevice not implemented")
+}
 func (UnimplementedDeviceServiceServer) mustEmbedUnimplementedDeviceServiceServer() {}
 func (UnimplementedDeviceServiceServer) testEmbeddedByValue()                       {}
 
@@ -381,6 +542,89 @@ func _DeviceService_SetNumVfs_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DeviceService_GetDevicesPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).GetDevicesPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_GetDevicesPage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).GetDevicesPage(ctx, req.(*DeviceListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeviceService_GetDevicesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeviceListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeviceServiceServer).GetDevicesStream(m, &grpc.GenericServerStream[DeviceListRequest, DeviceListResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeviceService_GetDevicesStreamServer = grpc.ServerStreamingServer[DeviceListResponse]
+
+func _DeviceService_GetAnnotationTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).GetAnnotationTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_GetAnnotationTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).GetAnnotationTemplate(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeviceService_GetAllocateInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).GetAllocateInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_GetAllocateInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).GetAllocateInfo(ctx, req.(*AllocateInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeviceService_ResetDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).ResetDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_ResetDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).ResetDevice(ctx, req.(*ResetDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DeviceService_ServiceDesc is the grpc.ServiceDesc for DeviceService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -396,8 +640,30 @@ var DeviceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetNumVfs",
 			Handler:    _DeviceService_SetNumVfs_Handler,
 		},
+		{
+			MethodName: "GetDevicesPage",
+			Handler:    _DeviceService_GetDevicesPage_Handler,
+		},
+		{
+			MethodName: "GetAnnotationTemplate",
+			Handler:    _DeviceService_GetAnnotationTemplate_Handler,
+		},
+		{
+			MethodName: "GetAllocateInfo",
+			Handler:    _DeviceService_GetAllocateInfo_Handler,
+		},
+		{
+			MethodName: "ResetDevice",
+			Handler:    _DeviceService_ResetDevice_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetDevicesStream",
+			Handler:       _DeviceService_GetDevicesStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
 }
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	configv1 "github.com/openshift/dpu-operator/api/v1"
+	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	"github.com/openshift/dpu-operator/internal/images"
 	"github.com/openshift/dpu-operator/internal/platform"
@@ -56,6 +57,9 @@ type Daemon struct {
 	dpuDetectorManger *platform.DpuDetectorManager
 	managedDpus       map[string]*ManagedDpu
 	nodeName          string
+
+	devicePluginOpts []deviceplugin.Option
+	vendorPluginOpts []func(*plugin.GrpcPlugin)
 }
 
 func NewDaemon(fs afero.Fs, p platform.Platform, config *rest.Config, imageManager images.ImageManager, pathManager *utils.PathManager, nodeName string) Daemon {
@@ -79,6 +83,19 @@ func (d *Daemon) WithNodeName(nodeName string) *Daemon {
 	return d
 }
 
+// WithDevicePluginOptions configures the Device Plugin of every resource of
+// every DPU.
+func (d *Daemon) WithDevicePluginOptions(opts ...deviceplugin.Option) *Daemon {
+	d.devicePluginOpts = opts
+	return d
+}
+
+// WithVendorPluginOptions configures the vendor plugin client of every DPU.
+func (d *Daemon) WithVendorPluginOptions(opts ...func(*plugin.GrpcPlugin)) *Daemon {
+	d.vendorPluginOpts = opts
+	return d
+}
+
 func (d *Daemon) PrepareAndServe(ctx context.Context) error {
 	err := d.Prepare()
 
@@ -248,13 +265,13 @@ func (d *Daemon) shutdown(cancelManagers context.CancelFunc, managerDone []chan
 
 func (d *Daemon) createSideManager(dpuCR *configv1.DataProcessingUnit, dpuPlugin *plugin.GrpcPlugin) (SideManager, error) {
 	if dpuCR.Spec.IsDpuSide {
-		dsm, err := NewDpuSideManager(dpuPlugin, d.config, WithPathManager(*d.pm))
+		dsm, err := NewDpuSideManager(dpuPlugin, d.config, WithPathManager(*d.pm), WithDpuDevicePluginOptions(d.devicePluginOpts...))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create DpuSideManager: %v", err)
 		}
 		return dsm, nil
 	} else {
-		hsm, err := NewHostSideManager(dpuPlugin, WithPathManager2(d.pm))
+		hsm, err := NewHostSideManager(dpuPlugin, WithPathManager2(d.pm), WithHostDevicePluginOptions(d.devicePluginOpts...))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create HostSideManager: %v", err)
 		}
@@ -410,6 +427,9 @@ func (d *Daemon) updateManagedDpus(detectedDpusList []*platform.DetectedDpuWithP
 	// possible to have the identifier point to a DPU that morphed into another DPU
 	for identifier, detected := range currentlyDetected {
 		if _, exists := d.managedDpus[identifier]; !exists {
+			for _, opt := range d.vendorPluginOpts {
+				opt(detected.Plugin)
+			}
 			// Create new ManagedDpu entry
 			d.managedDpus[identifier] = &ManagedDpu{
 				DpuCR:   detected.DpuCR,
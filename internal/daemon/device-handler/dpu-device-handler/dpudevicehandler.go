@@ -1,9 +1,14 @@
 package dpudevicehandler
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
 	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
@@ -21,6 +26,16 @@ type dpuDeviceHandler struct {
 	setupDevicesDone chan struct{}
 	dpuMode          bool
 	vsp              plugin.VendorPlugin
+
+	attributesMutex sync.RWMutex
+	attributes      map[string]map[string]string
+	capabilities    map[string][]string
+	localityGroups  map[string]string
+	pools           map[string]string
+	// physical maps the logical devices of shared devices to them.
+	physical map[string]string
+	// replicas are the replicas reported for the shared devices.
+	replicas map[string]int
 }
 
 func NewDpuDeviceHandler(vsp plugin.VendorPlugin, opts ...func(*dpuDeviceHandler)) *dpuDeviceHandler {
@@ -45,36 +60,198 @@ func validatePciDevice(device string) (string, error) {
 	return device, fmt.Errorf("netdev %s is not a valid PCI device", device)
 }
 
-func (d *dpuDeviceHandler) GetDevices() (*dh.DeviceList, error) {
+// deviceHealth maps the health reported by the vendor plugin to the health
+// advertised to Kubelet. Vendor plugins that don't report health leave it
+// empty, their devices are considered healthy. Any other state than healthy,
+// e.g. a degraded PCIe link or overheating, makes the device unhealthy.
+func deviceHealth(vendorHealth string) string {
+	if vendorHealth == "" || strings.EqualFold(vendorHealth, pluginapi.Healthy) {
+		return pluginapi.Healthy
+	}
+	return pluginapi.Unhealthy
+}
+
+// deviceTopology returns the NUMA node the vendor plugin reports for the
+// device, falling back to sysfs for devices identified by their PCI address.
+// Unknown affinity yields nil rather than claiming NUMA node 0, so that the
+// Topology Manager doesn't rely on it.
+func deviceTopology(device *pb.Device, pciAddr string) *pluginapi.TopologyInfo {
+	node := int64(-1)
+	if device.NumaNode != nil {
+		node = int64(device.GetNumaNode())
+	} else if pciAddr != "" {
+		node = int64(dh.GetNumaNode(pciAddr))
+	}
+	if node < 0 {
+		return nil
+	}
+	return &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+}
+
+// GetDevices returns the devices of the vendor plugin with their health.
+// Failing to reach the vendor plugin is an error rather than unhealthy
+// devices, so that the last advertised devices are kept during an outage. A
+// hung vendor plugin fails the call once the RPC timeout of the plugin runs
+// out, or ctx is done.
+func (d *dpuDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
 	// Wait for devices to be done initializing
-	<-d.setupDevicesDone
+	select {
+	case <-d.setupDevicesDone:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to handle GetDevices request: %v", ctx.Err())
+	}
 
-	Devices, err := d.vsp.GetDevices()
+	Devices, err := d.vsp.GetDevices(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to handle GetDevices request: %v", err)
 	}
+	if Devices == nil {
+		return nil, fmt.Errorf("failed to handle GetDevices request: no response from the vendor plugin")
+	}
 
 	devices := make(dh.DeviceList)
+	attributes := make(map[string]map[string]string)
+	capabilities := make(map[string][]string)
+	localityGroups := make(map[string]string)
+	pools := make(map[string]string)
+	physical := make(map[string]string)
+	replicas := make(map[string]int)
 
 	// In terms of the API boundaries between components, the host side requires pci-addresses
 	// when handling devices, however the dpu side requires a higher level of abstraction. For
 	// now, we will just enforce PCI addresses as the device ID on the host only.
-	for _, device := range Devices.Devices {
-		if d.dpuMode {
-			devices[device.ID] = pluginapi.Device{ID: device.ID, Health: pluginapi.Healthy}
+	// Devices are visited in the order of their keys so that of devices
+	// sharing an ID, the same one is kept on every call.
+	keys := make([]string, 0, len(Devices.Devices))
+	for key := range Devices.Devices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	invalid := 0
+	for _, key := range keys {
+		device := Devices.Devices[key]
+		if device == nil || device.ID == "" {
+			d.log.Info("Warning: ignoring device without ID reported by the vendor plugin", "key", key)
+			invalid++
 			continue
 		}
+		health := deviceHealth(device.Health)
+		if health != pluginapi.Healthy {
+			d.log.V(1).Info("Vendor plugin reports device as unhealthy", "id", device.ID, "health", device.Health)
+		}
+		id := device.ID
+		topology := deviceTopology(device, "")
+		if !d.dpuMode {
+			devPciId, err := validatePciDevice(device.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Error in deviceHandler: device %s from GetDevice request: %v", device.ID, err)
+			}
+			id = devPciId
+			topology = deviceTopology(device, devPciId)
+		}
 
-		devPciId, err := validatePciDevice(device.ID)
-		if err != nil {
-			return nil, fmt.Errorf("Error in deviceHandler: device %s from GetDevice request: %v", device.ID, err)
+		// A shared device is advertised as one logical device per replica.
+		ids := []string{id}
+		if device.Replicas > 1 {
+			ids = make([]string, 0, device.Replicas)
+			for replica := 0; replica < int(device.Replicas); replica++ {
+				ids = append(ids, dh.ReplicaID(id, replica))
+			}
+		}
+		if duplicate := firstKnownID(devices, ids); duplicate != "" {
+			d.log.Info("Warning: ignoring device with duplicate ID reported by the vendor plugin", "key", key, "id", duplicate)
+			invalid++
+			continue
+		}
+		if device.Replicas > 1 {
+			replicas[id] = int(device.Replicas)
+		}
+		for _, logicalId := range ids {
+			devices[logicalId] = pluginapi.Device{ID: logicalId, Health: health, Topology: topology}
+			attributes[logicalId] = device.Attributes
+			capabilities[logicalId] = device.Capabilities
+			localityGroups[logicalId] = device.LocalityGroup
+			pools[logicalId] = device.Pool
+			if logicalId != id {
+				physical[logicalId] = id
+			}
 		}
-		devices[devPciId] = pluginapi.Device{ID: devPciId, Health: pluginapi.Healthy}
 	}
 
+	if len(devices) == 0 && invalid > 0 {
+		return nil, fmt.Errorf("failed to handle GetDevices request: all %d devices reported by the vendor plugin have empty or duplicate IDs", invalid)
+	}
+
+	d.attributesMutex.Lock()
+	d.attributes = attributes
+	d.capabilities = capabilities
+	d.localityGroups = localityGroups
+	d.pools = pools
+	d.physical = physical
+	d.replicas = replicas
+	d.attributesMutex.Unlock()
+
 	return &devices, nil
 }
 
+// firstKnownID returns the first of the IDs that is already in devices.
+func firstKnownID(devices dh.DeviceList, ids []string) string {
+	for _, id := range ids {
+		if _, ok := devices[id]; ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// GetDeviceAttributes returns the attributes the vendor plugin reported for
+// the device in the last GetDevices call.
+func (d *dpuDeviceHandler) GetDeviceAttributes(id string) map[string]string {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.attributes[id]
+}
+
+// GetDeviceCapabilities returns the capabilities the vendor plugin reported
+// for the device in the last GetDevices call.
+func (d *dpuDeviceHandler) GetDeviceCapabilities(id string) []string {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.capabilities[id]
+}
+
+// GetDeviceLocalityGroup returns the locality group the vendor plugin
+// reported for the device in the last GetDevices call.
+func (d *dpuDeviceHandler) GetDeviceLocalityGroup(id string) string {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.localityGroups[id]
+}
+
+// GetDevicePool returns the pool the vendor plugin reported for the device in
+// the last GetDevices call.
+func (d *dpuDeviceHandler) GetDevicePool(id string) string {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.pools[id]
+}
+
+// GetPhysicalDevice returns the shared device of a logical device advertised
+// in the last GetDevices call, empty for devices that aren't shared.
+func (d *dpuDeviceHandler) GetPhysicalDevice(id string) string {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.physical[id]
+}
+
+// GetDeviceReplicas returns the replicas reported for a shared device in the
+// last GetDevices call, zero for devices that aren't shared.
+func (d *dpuDeviceHandler) GetDeviceReplicas(physical string) int {
+	d.attributesMutex.RLock()
+	defer d.attributesMutex.RUnlock()
+	return d.replicas[physical]
+}
+
 // TODO: When changing the SRIOV numVfs, we should do the following:
 // 1) Drain all pods running on the node with a drain controller running
 // on the control plane. The nodes will be marked for draining and read by
@@ -86,7 +263,7 @@ func (d *dpuDeviceHandler) SetupDevices() error {
 
 	defer close(d.setupDevicesDone)
 
-	numVfs, err := d.vsp.SetNumVfs(8)
+	numVfs, err := d.vsp.SetNumVfs(context.Background(), 8)
 	if err != nil {
 		// Currently NF devices do not require any setup outside the VSP
 		// ignore the error if we are in DPU mode.
@@ -1,6 +1,9 @@
 package devicehandler
 
 import (
+	"context"
+	"fmt"
+
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -8,5 +11,50 @@ type DeviceList map[string]pluginapi.Device
 
 type DeviceHandler interface {
 	SetupDevices() error
-	GetDevices() (*DeviceList, error)
+	GetDevices(ctx context.Context) (*DeviceList, error)
+}
+
+// AttributeHandler is a DeviceHandler that also knows the attributes the
+// vendor plugin reported for the devices returned by the last GetDevices.
+type AttributeHandler interface {
+	DeviceHandler
+	GetDeviceAttributes(id string) map[string]string
+}
+
+// CapabilityHandler is a DeviceHandler that also knows the capabilities the
+// vendor plugin reported for the devices returned by the last GetDevices.
+type CapabilityHandler interface {
+	DeviceHandler
+	GetDeviceCapabilities(id string) []string
+}
+
+// LocalityHandler is a DeviceHandler that also knows the locality groups the
+// vendor plugin reported for the devices returned by the last GetDevices.
+type LocalityHandler interface {
+	DeviceHandler
+	GetDeviceLocalityGroup(id string) string
+}
+
+// ReplicaHandler is a DeviceHandler that advertises devices shared by several
+// containers as several logical devices, see ReplicaID.
+type ReplicaHandler interface {
+	DeviceHandler
+	// GetPhysicalDevice returns the device shared by a logical device, empty
+	// when the device isn't shared.
+	GetPhysicalDevice(id string) string
+	// GetDeviceReplicas returns the number of replicas the vendor plugin
+	// reported for a shared device, zero when the device isn't shared.
+	GetDeviceReplicas(physical string) int
+}
+
+// ReplicaID returns the ID of the given logical device of a shared device.
+func ReplicaID(physical string, replica int) string {
+	return fmt.Sprintf("%s_r%d", physical, replica)
+}
+
+// PoolHandler is a DeviceHandler that also knows the pools the vendor plugin
+// reported for the devices returned by the last GetDevices.
+type PoolHandler interface {
+	DeviceHandler
+	GetDevicePool(id string) string
 }
@@ -0,0 +1,91 @@
+package deviceplugin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Advertised devices", func() {
+	var (
+		dp   *dpServer
+		logs []string
+	)
+
+	BeforeEach(func() {
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(GinkgoT().TempDir()))
+		logs = nil
+		dp.log = funcr.New(func(prefix, args string) {
+			logs = append(logs, args)
+		}, funcr.Options{})
+	})
+
+	advertise := func(devices dh.DeviceList) []string {
+		stream := &fakeListAndWatchServer{}
+		Expect(dp.sendDevices(stream, dp.advertisedDevices(&devices))).To(Succeed())
+		dp.setDeviceCache(&devices)
+		Expect(stream.sent).To(HaveLen(1))
+		return sentIDs(stream.sent[0])
+	}
+
+	It("should accept in Allocate exactly the IDs it advertises", func() {
+		devices := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+			"dev1": {ID: "0000:03:00.1", Health: pluginapi.Healthy},
+		}
+		ids := advertise(devices)
+		Expect(ids).To(ConsistOf("dev0", "dev1"))
+
+		for _, id := range ids {
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{id}))
+			Expect(err).NotTo(HaveOccurred())
+			dp.releaseAllocation(id)
+		}
+		Expect(strings.Join(logs, "\n")).To(ContainSubstring(`"msg"="Advertising device under its key"`))
+	})
+
+	It("should reject and log an ID it never advertised", func() {
+		advertise(dh.DeviceList{"dev0": {ID: "dev0", Health: pluginapi.Healthy}})
+
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"0000:03:00.1"}))
+		Expect(err).To(MatchError(ContainSubstring("0000:03:00.1 was never advertised")))
+		Expect(logs).To(ContainElement(SatisfyAll(
+			ContainSubstring(`"msg"="Allocate received a device ID that was never advertised"`),
+			ContainSubstring(`"id"="0000:03:00.1"`),
+		)))
+		Expect(dp.GetAllocations()).To(BeEmpty())
+	})
+
+	Context("while no devices are known", func() {
+		It("should ask Kubelet to retry when configured to", func() {
+			WithUnavailableWhileEmpty(true)(dp)
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+			advertise(dh.DeviceList{"dev0": {ID: "dev0", Health: pluginapi.Healthy}})
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should still reject unknown devices once devices are known", func() {
+			WithUnavailableWhileEmpty(true)(dp)
+			advertise(dh.DeviceList{"dev0": {ID: "dev0", Health: pluginapi.Healthy}})
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+			Expect(err).To(MatchError(ContainSubstring("dev1 was never advertised")))
+			Expect(status.Code(err)).NotTo(Equal(codes.Unavailable))
+		})
+
+		It("should reject the devices by default", func() {
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).To(MatchError(ContainSubstring("dev0 was never advertised")))
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// defaultDeviceNodePermissions is the cgroup permission of a device node the
+// vendor plugin doesn't give one for.
+const defaultDeviceNodePermissions = "rw"
+
+// addAllocateInfo adds the device nodes and mounts the vendor plugin needs for
+// the devices to the response, so that the container can actually open them.
+// Host paths are checked here, Kubelet would otherwise only fail when creating
+// the container.
+func (dp *dpServer) addAllocateInfo(ctx context.Context, resp *pluginapi.ContainerAllocateResponse, ids []string) error {
+	if dp.vsp == nil {
+		return nil
+	}
+	for _, id := range ids {
+		info, err := dp.vsp.GetAllocateInfo(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get allocate info of device %s: %v", id, err)
+		}
+		for _, node := range info.Devices {
+			if err := checkHostPath(id, node.HostPath); err != nil {
+				return err
+			}
+			spec := &pluginapi.DeviceSpec{
+				HostPath:      node.HostPath,
+				ContainerPath: node.ContainerPath,
+				Permissions:   node.Permissions,
+			}
+			if spec.ContainerPath == "" {
+				spec.ContainerPath = spec.HostPath
+			}
+			if spec.Permissions == "" {
+				spec.Permissions = defaultDeviceNodePermissions
+			}
+			resp.Devices = append(resp.Devices, spec)
+		}
+		for _, mount := range info.Mounts {
+			if err := checkHostPath(id, mount.HostPath); err != nil {
+				return err
+			}
+			containerPath := mount.ContainerPath
+			if containerPath == "" {
+				containerPath = mount.HostPath
+			}
+			resp.Mounts = append(resp.Mounts, &pluginapi.Mount{
+				HostPath:      mount.HostPath,
+				ContainerPath: containerPath,
+				ReadOnly:      mount.ReadOnly,
+			})
+		}
+	}
+	return nil
+}
+
+func checkHostPath(id, path string) error {
+	if path == "" {
+		return fmt.Errorf("device %s: vendor plugin returned an empty host path", id)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("device %s: host path %s doesn't exist: %v", id, path, err)
+	}
+	return nil
+}
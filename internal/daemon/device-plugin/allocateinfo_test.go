@@ -0,0 +1,77 @@
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// allocateInfoVendorPlugin returns the given allocate info of each device.
+type allocateInfoVendorPlugin struct {
+	plugin.VendorPlugin
+	info map[string]*pb.AllocateInfo
+}
+
+func (v allocateInfoVendorPlugin) GetAnnotationTemplate(ctx context.Context) (*pb.AnnotationTemplate, error) {
+	return &pb.AnnotationTemplate{}, nil
+}
+
+func (v allocateInfoVendorPlugin) GetAllocateInfo(ctx context.Context, deviceID string) (*pb.AllocateInfo, error) {
+	if info, ok := v.info[deviceID]; ok {
+		return info, nil
+	}
+	return &pb.AllocateInfo{}, nil
+}
+
+var _ = Describe("Allocate info", func() {
+	var (
+		dp      *dpServer
+		hostDir string
+	)
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		hostDir = GinkgoT().TempDir()
+		for _, name := range []string{"vfio0", "vfio1", "sysfs0"} {
+			Expect(os.WriteFile(filepath.Join(hostDir, name), nil, 0o644)).To(Succeed())
+		}
+	})
+
+	It("should pass the device nodes and mounts of the vendor plugin", func() {
+		dp.vsp = allocateInfoVendorPlugin{info: map[string]*pb.AllocateInfo{
+			"dev0": {
+				Devices: []*pb.DeviceNode{{HostPath: filepath.Join(hostDir, "vfio0")}},
+				Mounts:  []*pb.Mount{{HostPath: filepath.Join(hostDir, "sysfs0"), ContainerPath: "/sys/dev0", ReadOnly: true}},
+			},
+			"dev1": {
+				Devices: []*pb.DeviceNode{{HostPath: filepath.Join(hostDir, "vfio1"), ContainerPath: "/dev/vfio/1", Permissions: "rwm"}},
+			},
+		}}
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		containerResp := resp.ContainerResponses[0]
+		Expect(containerResp.Devices).To(Equal([]*pluginapi.DeviceSpec{
+			{HostPath: filepath.Join(hostDir, "vfio0"), ContainerPath: filepath.Join(hostDir, "vfio0"), Permissions: "rw"},
+			{HostPath: filepath.Join(hostDir, "vfio1"), ContainerPath: "/dev/vfio/1", Permissions: "rwm"},
+		}))
+		Expect(containerResp.Mounts).To(Equal([]*pluginapi.Mount{
+			{HostPath: filepath.Join(hostDir, "sysfs0"), ContainerPath: "/sys/dev0", ReadOnly: true},
+		}))
+		Expect(containerResp.Envs).To(HaveKey("NF-DEV"))
+	})
+
+	It("should fail when a host path doesn't exist", func() {
+		dp.vsp = allocateInfoVendorPlugin{info: map[string]*pb.AllocateInfo{
+			"dev1": {Devices: []*pb.DeviceNode{{HostPath: filepath.Join(hostDir, "missing")}}},
+		}}
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).To(MatchError(ContainSubstring("device dev1: host path")))
+	})
+})
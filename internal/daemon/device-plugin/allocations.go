@@ -0,0 +1,142 @@
+package deviceplugin
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Allocation records a device handed out to a container by Allocate.
+type Allocation struct {
+	DeviceID    string    `json:"deviceID"`
+	AllocatedAt time.Time `json:"allocatedAt"`
+	// Stale is set when the device disappeared (e.g. was hot-unplugged) while
+	// still allocated. The record is kept so that tooling can act on it.
+	Stale      bool       `json:"stale,omitempty"`
+	StaleSince *time.Time `json:"staleSince,omitempty"`
+	// RefreshedAt is when the consuming container was last seen alive. An
+	// allocation not refreshed within the allocation TTL is flagged as
+	// Expired, it was most likely leaked by a pod that died.
+	RefreshedAt time.Time `json:"refreshedAt"`
+	Expired     bool      `json:"expired,omitempty"`
+	// Owner helps tracing who holds the device.
+	Owner *AllocationOwner `json:"owner,omitempty"`
+}
+
+// allocationStore keeps track of the devices handed out by Allocate. Kubelet
+// never tells a Device Plugin when a device is no longer used, so entries stay
+// until they are explicitly released.
+type allocationStore struct {
+	mu          sync.RWMutex
+	allocations map[string]Allocation
+}
+
+func newAllocationStore() *allocationStore {
+	return &allocationStore{
+		allocations: make(map[string]Allocation),
+	}
+}
+
+func (s *allocationStore) record(ids []string, owner *AllocationOwner, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.allocations[id] = Allocation{DeviceID: id, AllocatedAt: now, RefreshedAt: now, Owner: owner}
+	}
+}
+
+// refresh extends the lease of the allocated devices among ids.
+func (s *allocationStore) refresh(ids []string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if a, ok := s.allocations[id]; ok {
+			a.RefreshedAt = now
+			a.Expired = false
+			s.allocations[id] = a
+		}
+	}
+}
+
+// markExpired flags the allocations not refreshed within ttl and returns the
+// newly expired ones.
+func (s *allocationStore) markExpired(ttl time.Duration, now time.Time) []Allocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var newlyExpired []Allocation
+	for id, a := range s.allocations {
+		if a.Expired || now.Sub(a.RefreshedAt) < ttl {
+			continue
+		}
+		a.Expired = true
+		s.allocations[id] = a
+		newlyExpired = append(newlyExpired, a)
+	}
+	sort.Slice(newlyExpired, func(i, j int) bool {
+		return newlyExpired[i].DeviceID < newlyExpired[j].DeviceID
+	})
+	return newlyExpired
+}
+
+func (s *allocationStore) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.allocations, id)
+}
+
+// markStale flags the allocations of devices not present in devices anymore
+// and returns the newly stale ones. Allocations of devices that came back are
+// no longer considered stale.
+func (s *allocationStore) markStale(present func(id string) bool, now time.Time) []Allocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var newlyStale []Allocation
+	for id, a := range s.allocations {
+		switch {
+		case !present(id) && !a.Stale:
+			a.Stale = true
+			a.StaleSince = &now
+			newlyStale = append(newlyStale, a)
+		case present(id) && a.Stale:
+			a.Stale = false
+			a.StaleSince = nil
+		default:
+			continue
+		}
+		s.allocations[id] = a
+	}
+	return newlyStale
+}
+
+// restore replaces the allocations with the given ones.
+func (s *allocationStore) restore(allocations []Allocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allocations = make(map[string]Allocation, len(allocations))
+	for _, a := range allocations {
+		s.allocations[a.DeviceID] = a
+	}
+}
+
+func (s *allocationStore) isAllocated(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.allocations[id]
+	return ok
+}
+
+// list returns all allocations sorted by device ID.
+func (s *allocationStore) list() []Allocation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	allocations := make([]Allocation, 0, len(s.allocations))
+	for _, a := range s.allocations {
+		allocations = append(allocations, a)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].DeviceID < allocations[j].DeviceID
+	})
+	return allocations
+}
@@ -0,0 +1,150 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Allocations", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should flag the allocation of a removed device as stale", func() {
+		before := counterValue(staleAllocationsTotal.WithLabelValues(dp.resourceName))
+
+		devices := dh.DeviceList{"dev1": {ID: "dev1", Health: pluginapi.Healthy}}
+		dp.checkStaleAllocations(&devices)
+
+		allocations := dp.GetAllocations()
+		Expect(allocations).To(HaveLen(2))
+		Expect(allocations[0].DeviceID).To(Equal("dev0"))
+		Expect(allocations[0].Stale).To(BeTrue())
+		Expect(allocations[0].StaleSince).NotTo(BeNil())
+		Expect(allocations[1].Stale).To(BeFalse())
+		Expect(counterValue(staleAllocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(before + 1))
+
+		// Only newly stale allocations are reported
+		dp.checkStaleAllocations(&devices)
+		Expect(counterValue(staleAllocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(before + 1))
+	})
+
+	It("should not flag the allocation of a device that is only no longer advertised", func() {
+		before := counterValue(staleAllocationsTotal.WithLabelValues(dp.resourceName))
+		WithDeviceHandler(&changingDeviceHandler{ids: []string{"dev0", "dev1"}})(dp)
+		WithReservedDevices("dev0")(dp)
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &lockedListAndWatchServer{ctx: ctx}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(dp.ListAndWatch(&pluginapi.Empty{}, stream)).To(Succeed())
+		}()
+		Eventually(stream.sends).Should(Equal([][]string{{"dev1"}}))
+		cancel()
+		Eventually(done).Should(BeClosed())
+
+		Expect(dp.GetAllocations()[0].Stale).To(BeFalse())
+		Expect(counterValue(staleAllocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(before))
+	})
+
+	It("should clear the stale flag when the device comes back", func() {
+		devices := dh.DeviceList{"dev1": {ID: "dev1", Health: pluginapi.Healthy}}
+		dp.checkStaleAllocations(&devices)
+
+		devices["dev0"] = pluginapi.Device{ID: "dev0", Health: pluginapi.Healthy}
+		dp.checkStaleAllocations(&devices)
+		Expect(dp.GetAllocations()[0].Stale).To(BeFalse())
+	})
+
+	Context("leases", func() {
+		var clock *clocktesting.FakeClock
+
+		BeforeEach(func() {
+			clock = clocktesting.NewFakeClock(time.Now())
+			dp = newTestDevicePlugin("dev0", "dev1")
+			WithClock(clock)(dp)
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1"}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should flag an allocation that wasn't refreshed for reclaim", func() {
+			WithAllocationTTL(time.Minute, false)(dp)
+			before := counterValue(expiredAllocationsTotal.WithLabelValues(dp.resourceName))
+
+			clock.SetTime(clock.Now().Add(30 * time.Second))
+			_, err := dp.PreStartContainer(context.Background(), &pluginapi.PreStartContainerRequest{DevicesIDs: []string{"dev1"}})
+			Expect(err).NotTo(HaveOccurred())
+			clock.SetTime(clock.Now().Add(30 * time.Second))
+			dp.checkExpiredAllocations()
+
+			allocations := dp.GetAllocations()
+			Expect(allocations).To(HaveLen(2))
+			Expect(allocations[0].Expired).To(BeTrue())
+			Expect(allocations[1].Expired).To(BeFalse())
+			Expect(counterValue(expiredAllocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(before + 1))
+
+			// Only newly expired allocations are reported
+			dp.checkExpiredAllocations()
+			Expect(counterValue(expiredAllocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(before + 1))
+
+			dp.RefreshAllocations("dev0")
+			Expect(dp.GetAllocations()[0].Expired).To(BeFalse())
+		})
+
+		It("should reclaim expired allocations when configured to", func() {
+			WithAllocationTTL(time.Minute, true)(dp)
+			clock.SetTime(clock.Now().Add(time.Minute))
+			dp.checkExpiredAllocations()
+			Expect(dp.GetAllocations()).To(BeEmpty())
+		})
+
+		It("should not expire allocations without a TTL", func() {
+			clock.SetTime(clock.Now().Add(time.Hour))
+			dp.checkExpiredAllocations()
+			Expect(dp.GetAllocations()).To(HaveLen(2))
+		})
+	})
+
+	Context("ownership", func() {
+		It("should record the container holding the devices", func() {
+			dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1", "dev2"}))
+			Expect(err).NotTo(HaveOccurred())
+
+			allocations := dp.GetAllocations()
+			Expect(allocations[0].Owner).To(Equal(&AllocationOwner{ContainerIndex: 0, Devices: []string{"dev0"}}))
+			Expect(allocations[1].Owner).To(Equal(&AllocationOwner{ContainerIndex: 1, Devices: []string{"dev1", "dev2"}}))
+			Expect(allocations[2].Owner).To(Equal(allocations[1].Owner))
+		})
+
+		It("should guess the pod only when a single pending container matches", func() {
+			now := time.Now()
+			dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+			WithDeviceSelectors(fakePodLister{
+				pendingPod("single", 1, "", now),
+				pendingPod("pair-a", 2, "", now),
+				pendingPod("pair-b", 2, "", now),
+			})(dp)
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1", "dev2"}))
+			Expect(err).NotTo(HaveOccurred())
+
+			allocations := dp.GetAllocations()
+			Expect(allocations[0].Owner.Pod).To(Equal("default/single"))
+			Expect(allocations[0].Owner.Container).To(Equal("app"))
+			Expect(allocations[1].Owner.Pod).To(BeEmpty())
+			Expect(allocations[1].Owner.Container).To(BeEmpty())
+		})
+	})
+})
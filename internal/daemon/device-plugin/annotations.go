@@ -0,0 +1,135 @@
+package deviceplugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var annotationKeyReplacer = strings.NewReplacer(":", "-", "/", "-")
+
+// annotationData is what the annotation templates are rendered with.
+type annotationData struct {
+	ID string
+	// Name is the ID with the characters that aren't allowed in annotation
+	// keys, such as the colons of PCI addresses, replaced by dashes.
+	Name string
+	// PCIAddress is empty when the device ID isn't a PCI address, e.g. on
+	// the DPU where devices are identified by their interface name.
+	PCIAddress string
+}
+
+type annotationPair struct {
+	key   *template.Template
+	value *template.Template
+}
+
+// annotationTemplate renders the container annotations for allocated devices,
+// which lets runtimes inject devices through OCI hooks instead of parsing
+// environment variables.
+type annotationTemplate struct {
+	pairs []annotationPair
+}
+
+func newAnnotationTemplate(annotations map[string]string) (*annotationTemplate, error) {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	t := &annotationTemplate{}
+	for _, key := range keys {
+		keyTemplate, err := template.New("key").Option("missingkey=error").Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation key template %q: %v", key, err)
+		}
+		valueTemplate, err := template.New("value").Option("missingkey=error").Parse(annotations[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation value template %q: %v", annotations[key], err)
+		}
+		t.pairs = append(t.pairs, annotationPair{key: keyTemplate, value: valueTemplate})
+	}
+	return t, nil
+}
+
+// render returns the annotations for the given devices. Devices rendering the
+// same key must render the same value, otherwise one would silently be lost.
+func (t *annotationTemplate) render(ids []string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for _, id := range ids {
+		data := annotationData{ID: id, Name: annotationKeyReplacer.Replace(id)}
+		if sriovutils.IsValidPCIAddress(id) {
+			data.PCIAddress = id
+		}
+		for _, pair := range t.pairs {
+			key, err := execute(pair.key, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render annotation key for device %s: %v", id, err)
+			}
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid annotation key %q for device %s: %s", key, id, strings.Join(errs, ", "))
+			}
+			value, err := execute(pair.value, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render annotation %s for device %s: %v", key, id, err)
+			}
+			if existing, ok := annotations[key]; ok && existing != value {
+				return nil, fmt.Errorf("annotation %s rendered to conflicting values %q and %q", key, existing, value)
+			}
+			annotations[key] = value
+		}
+	}
+	return annotations, nil
+}
+
+func execute(t *template.Template, data annotationData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// getAnnotationTemplate fetches the annotation template from the vendor
+// plugin on first use. Failures are not cached so that a vendor plugin which
+// isn't ready yet is asked again on the next Allocate.
+func (dp *dpServer) getAnnotationTemplate(ctx context.Context) (*annotationTemplate, error) {
+	dp.annotationMutex.Lock()
+	defer dp.annotationMutex.Unlock()
+
+	if dp.annotations != nil {
+		return dp.annotations, nil
+	}
+	if dp.vsp == nil {
+		dp.annotations = &annotationTemplate{}
+		return dp.annotations, nil
+	}
+
+	resp, err := dp.vsp.GetAnnotationTemplate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotation template from vendor plugin: %v", err)
+	}
+	t, err := newAnnotationTemplate(resp.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	dp.annotations = t
+	return t, nil
+}
+
+// containerAnnotations returns the annotations of a container allocated the
+// given devices.
+func (dp *dpServer) containerAnnotations(ctx context.Context, ids []string) (map[string]string, error) {
+	t, err := dp.getAnnotationTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.render(ids)
+}
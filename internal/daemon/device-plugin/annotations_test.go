@@ -0,0 +1,64 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Annotations", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("0000:3b:00.1", "0000:3b:00.2", "eth0")
+	})
+
+	useTemplate := func(annotations map[string]string) {
+		t, err := newAnnotationTemplate(annotations)
+		Expect(err).NotTo(HaveOccurred())
+		dp.annotations = t
+	}
+
+	It("should not set annotations without a vendor plugin", func() {
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.1"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Annotations).To(BeNil())
+	})
+
+	It("should render the annotations of each device", func() {
+		useTemplate(map[string]string{
+			"dpu.example.com/dev-{{.Name}}": "pci={{.PCIAddress}}",
+			"dpu.example.com/inject":        "true",
+		})
+
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.1", "0000:3b:00.2"}, []string{"eth0"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Annotations).To(Equal(map[string]string{
+			"dpu.example.com/dev-0000-3b-00.1": "pci=0000:3b:00.1",
+			"dpu.example.com/dev-0000-3b-00.2": "pci=0000:3b:00.2",
+			"dpu.example.com/inject":           "true",
+		}))
+		Expect(resp.ContainerResponses[1].Annotations).To(Equal(map[string]string{
+			"dpu.example.com/dev-eth0": "pci=",
+			"dpu.example.com/inject":   "true",
+		}))
+	})
+
+	It("should reject invalid templates", func() {
+		_, err := newAnnotationTemplate(map[string]string{"dpu.example.com/dev": "{{.ID"})
+		Expect(err).To(MatchError(ContainSubstring("invalid annotation value template")))
+	})
+
+	It("should fail the allocation when rendering fails", func() {
+		useTemplate(map[string]string{"dpu.example.com/dev": "{{.Unknown}}"})
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"eth0"}))
+		Expect(err).To(MatchError(ContainSubstring("failed to render annotation dpu.example.com/dev for device eth0")))
+	})
+
+	It("should fail the allocation when devices render conflicting values", func() {
+		useTemplate(map[string]string{"dpu.example.com/dev": "{{.ID}}"})
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.1", "eth0"}))
+		Expect(err).To(MatchError(ContainSubstring("conflicting values")))
+	})
+})
@@ -0,0 +1,57 @@
+package deviceplugin
+
+import "time"
+
+const (
+	defaultPollInterval        = 5 * time.Second
+	defaultMaxReconcileBackoff = 2 * time.Minute
+	// defaultMinPollInterval protects the vendor plugin from being polled in
+	// a tight loop by a misconfigured poll interval.
+	defaultMinPollInterval = time.Second
+)
+
+// reconcileBackoff computes how long the ListAndWatch loop waits before the
+// next reconcile. Every consecutive failure doubles the wait up to maxInterval,
+// a success resets it back to the regular poll interval.
+type reconcileBackoff struct {
+	pollInterval time.Duration
+	maxInterval  time.Duration
+	current      time.Duration
+	failures     int
+}
+
+func newReconcileBackoff(pollInterval, maxInterval time.Duration) *reconcileBackoff {
+	if maxInterval < pollInterval {
+		maxInterval = pollInterval
+	}
+	return &reconcileBackoff{
+		pollInterval: pollInterval,
+		maxInterval:  maxInterval,
+		current:      pollInterval,
+	}
+}
+
+// failure records a failed reconcile and returns the wait before the next one.
+func (b *reconcileBackoff) failure() time.Duration {
+	b.failures++
+	if b.failures > 1 {
+		b.current *= 2
+	}
+	if b.current > b.maxInterval {
+		b.current = b.maxInterval
+	}
+	return b.current
+}
+
+// success resets the backoff and returns the regular poll interval.
+func (b *reconcileBackoff) success() time.Duration {
+	b.failures = 0
+	b.current = b.pollInterval
+	return b.current
+}
+
+// shouldLog rate limits the logging of persistent failures: only the 1st, 2nd,
+// 4th, 8th... consecutive failure is logged.
+func (b *reconcileBackoff) shouldLog() bool {
+	return b.failures&(b.failures-1) == 0
+}
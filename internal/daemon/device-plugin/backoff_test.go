@@ -0,0 +1,136 @@
+package deviceplugin
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Reconcile backoff", func() {
+	It("should grow on sustained failures up to the cap and reset after a success", func() {
+		b := newReconcileBackoff(time.Second, 5*time.Second)
+
+		Expect(b.failure()).To(Equal(1 * time.Second))
+		Expect(b.failure()).To(Equal(2 * time.Second))
+		Expect(b.failure()).To(Equal(4 * time.Second))
+		Expect(b.failure()).To(Equal(5 * time.Second))
+		Expect(b.failure()).To(Equal(5 * time.Second))
+
+		Expect(b.success()).To(Equal(time.Second))
+		Expect(b.failure()).To(Equal(time.Second))
+	})
+
+	It("should log persistent failures at a reduced rate", func() {
+		b := newReconcileBackoff(time.Second, time.Minute)
+		var logged []int
+		for i := 1; i <= 10; i++ {
+			b.failure()
+			if b.shouldLog() {
+				logged = append(logged, i)
+			}
+		}
+		Expect(logged).To(Equal([]int{1, 2, 4, 8}))
+	})
+})
+
+var _ = Describe("Poll interval", func() {
+	It("should clamp an interval below the floor and log it", func() {
+		dp := newTestDevicePlugin()
+		var logs []string
+		dp.log = funcr.New(func(prefix, args string) {
+			logs = append(logs, args)
+		}, funcr.Options{})
+
+		WithPollInterval(time.Millisecond)(dp)
+		dp.clampPollInterval()
+		Expect(dp.pollInterval).To(Equal(defaultMinPollInterval))
+		Expect(logs).To(ConsistOf(And(
+			ContainSubstring("Poll interval is below the minimum"),
+			ContainSubstring(`"configured"="1ms"`),
+			ContainSubstring(`"minimum"="1s"`),
+		)))
+	})
+
+	It("should honour a configured floor and intervals above it", func() {
+		pm := *utils.NewPathManager(GinkgoT().TempDir())
+		dp := NewDevicePlugin(nil, true, pm, WithPollInterval(100*time.Millisecond), WithMinPollInterval(2*time.Second))
+		Expect(dp.pollInterval).To(Equal(2 * time.Second))
+
+		dp = NewDevicePlugin(nil, true, pm, WithPollInterval(3*time.Second))
+		Expect(dp.pollInterval).To(Equal(3 * time.Second))
+	})
+})
+
+// countingDeviceHandler counts how often the devices are polled.
+type countingDeviceHandler struct {
+	changingDeviceHandler
+	polls atomic.Int32
+}
+
+func (h *countingDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	h.polls.Add(1)
+	return h.changingDeviceHandler.GetDevices(ctx)
+}
+
+var _ = Describe("Poll interval", func() {
+	var (
+		dp      *dpServer
+		handler *countingDeviceHandler
+		stream  *lockedListAndWatchServer
+		cancel  context.CancelFunc
+		done    chan struct{}
+	)
+
+	listAndWatch := func(interval time.Duration) {
+		dp.pollInterval = interval
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(dp.ListAndWatch(&pluginapi.Empty{}, stream)).To(Succeed())
+		}()
+		Eventually(stream.sends).Should(HaveLen(1))
+	}
+
+	BeforeEach(func() {
+		handler = &countingDeviceHandler{changingDeviceHandler: changingDeviceHandler{ids: []string{"dev0"}}}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		stream = &lockedListAndWatchServer{ctx: ctx}
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		done = make(chan struct{})
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	It("should poll at the configured interval", func() {
+		listAndWatch(20 * time.Millisecond)
+		Eventually(handler.polls.Load).Should(BeNumerically(">=", 5))
+	})
+
+	It("should not poll before the configured interval passed", func() {
+		listAndWatch(time.Hour)
+		Consistently(handler.polls.Load, 300*time.Millisecond).Should(BeEquivalentTo(1))
+	})
+
+	It("should return without waiting out the interval when Kubelet closes the stream", func() {
+		listAndWatch(time.Hour)
+		cancel()
+		Eventually(done, time.Second).Should(BeClosed())
+	})
+
+	It("should return without waiting out the interval when stopped", func() {
+		listAndWatch(time.Hour)
+		Expect(dp.Stop()).To(Succeed())
+		Eventually(done, time.Second).Should(BeClosed())
+	})
+})
@@ -0,0 +1,92 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// CapacityStatus is the capacity a resource advertises to Kubelet, so that
+// the daemon can report it to the operator without looking at the Node. It's
+// written to a file per resource in DevicePluginCapacityDir whenever it
+// changes after getting the devices from the vendor plugin. Drained devices
+// count as unhealthy, as that's how they are advertised.
+type CapacityStatus struct {
+	ResourceName string `json:"resourceName"`
+	Total        int    `json:"total"`
+	Healthy      int    `json:"healthy"`
+	// Available are the healthy devices that aren't allocated.
+	Available int       `json:"available"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// capacityPath returns the capacity file of a resource.
+func capacityPath(pm utils.PathManager, resourceName string) string {
+	return filepath.Join(pm.DevicePluginCapacityDir(), strings.ReplaceAll(resourceName, "/", "_")+".json")
+}
+
+// writeCapacity writes the capacity of the advertised devices if it changed.
+// Failures are only logged, the capacity is written again on the next change.
+func (dp *dpServer) writeCapacity(advertised *dh.DeviceList) {
+	capacity := CapacityStatus{ResourceName: dp.resourceName}
+	for id, dev := range *advertised {
+		capacity.Total++
+		if dev.Health != pluginapi.Healthy {
+			continue
+		}
+		capacity.Healthy++
+		if !dp.allocations.isAllocated(id) {
+			capacity.Available++
+		}
+	}
+
+	dp.capacityMutex.Lock()
+	defer dp.capacityMutex.Unlock()
+	if last := dp.capacity; last != nil && last.Total == capacity.Total && last.Healthy == capacity.Healthy && last.Available == capacity.Available {
+		return
+	}
+	capacity.UpdatedAt = dp.clock.Now()
+	path := capacityPath(dp.pathManager, dp.resourceName)
+	if err := writeFileAtomic(path, capacity); err != nil {
+		dp.log.Error(err, "Failed to write the capacity", "path", path)
+		return
+	}
+	dp.capacity = &capacity
+}
+
+// ReadCapacity returns the capacity of every resource of the node, sorted by
+// resource name, e.g. for the daemon to report to the operator.
+func ReadCapacity(pm utils.PathManager) ([]CapacityStatus, error) {
+	entries, err := os.ReadDir(pm.DevicePluginCapacityDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the capacity files: %v", err)
+	}
+	var statuses []CapacityStatus
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(pm.DevicePluginCapacityDir(), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read capacity file %s: %v", entry.Name(), err)
+		}
+		var status CapacityStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			return nil, fmt.Errorf("failed to parse capacity file %s: %v", entry.Name(), err)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ResourceName < statuses[j].ResourceName })
+	return statuses, nil
+}
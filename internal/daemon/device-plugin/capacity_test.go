@@ -0,0 +1,57 @@
+package deviceplugin
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Capacity status", func() {
+	var (
+		dp         *dpServer
+		advertised dh.DeviceList
+	)
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+		advertised = dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Healthy},
+			"dev2": {ID: "dev2", Health: pluginapi.Unhealthy},
+		}
+	})
+
+	It("should report the advertised capacity", func() {
+		Expect(ReadCapacity(dp.pathManager)).To(BeEmpty())
+
+		dp.writeCapacity(&advertised)
+		statuses, err := ReadCapacity(dp.pathManager)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].ResourceName).To(Equal(dp.resourceName))
+		Expect(statuses[0].Total).To(Equal(3))
+		Expect(statuses[0].Healthy).To(Equal(2))
+		Expect(statuses[0].Available).To(Equal(1))
+	})
+
+	It("should only write the capacity when it changes", func() {
+		dp.writeCapacity(&advertised)
+		path := capacityPath(dp.pathManager, dp.resourceName)
+		Expect(os.Remove(path)).To(Succeed())
+
+		dp.writeCapacity(&advertised)
+		Expect(path).NotTo(BeAnExistingFile())
+
+		dp.releaseAllocation("dev0")
+		dp.writeCapacity(&advertised)
+		statuses, err := ReadCapacity(dp.pathManager)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses[0].Available).To(Equal(2))
+	})
+})
@@ -0,0 +1,117 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/dpu-operator/internal/utils"
+)
+
+// checkpointVersion is bumped when the checkpoint format changes
+// incompatibly, older checkpoints are then ignored.
+const checkpointVersion = 1
+
+// allocationCheckpoint is what is persisted of the allocations of a resource,
+// so that they survive restarts of the daemon.
+type allocationCheckpoint struct {
+	Version      int          `json:"version"`
+	ResourceName string       `json:"resourceName"`
+	Allocations  []Allocation `json:"allocations"`
+}
+
+// checkpointPath returns the checkpoint file of a resource. Resources are
+// checkpointed separately since a device may be in several pools.
+func checkpointPath(pm utils.PathManager, resourceName string) string {
+	return filepath.Join(pm.DevicePluginCheckpointDir(), strings.ReplaceAll(resourceName, "/", "_")+".json")
+}
+
+// readCheckpoint returns the checkpointed allocations of the resource, none
+// when there is no checkpoint.
+func readCheckpoint(path, resourceName string) ([]Allocation, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allocation checkpoint: %v", err)
+	}
+	var checkpoint allocationCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse allocation checkpoint: %v", err)
+	}
+	if checkpoint.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported allocation checkpoint version %d, expected %d", checkpoint.Version, checkpointVersion)
+	}
+	if checkpoint.ResourceName != resourceName {
+		return nil, fmt.Errorf("allocation checkpoint is of resource %s, expected %s", checkpoint.ResourceName, resourceName)
+	}
+	return checkpoint.Allocations, nil
+}
+
+// writeCheckpoint atomically persists the current allocations. Writes are
+// serialized so that an older snapshot never replaces a newer one.
+func (dp *dpServer) writeCheckpoint() {
+	dp.checkpointMutex.Lock()
+	defer dp.checkpointMutex.Unlock()
+	checkpoint := allocationCheckpoint{
+		Version:      checkpointVersion,
+		ResourceName: dp.resourceName,
+		Allocations:  dp.allocations.list(),
+	}
+	path := checkpointPath(dp.pathManager, dp.resourceName)
+	if err := writeFileAtomic(path, checkpoint); err != nil {
+		dp.log.Error(err, "Failed to write the allocation checkpoint", "path", path)
+	}
+}
+
+// restoreCheckpoint restores the allocations from before a restart, so that
+// the devices held by running pods are known to be allocated. Allocations of
+// devices the vendor plugin doesn't report anymore are dropped. A missing or
+// unreadable checkpoint only loses the bookkeeping, Kubelet still knows which
+// devices are in use, so it doesn't prevent starting.
+func (dp *dpServer) restoreCheckpoint() {
+	path := checkpointPath(dp.pathManager, dp.resourceName)
+	allocations, err := readCheckpoint(path, dp.resourceName)
+	if err != nil {
+		dp.log.Error(err, "Ignoring the allocation checkpoint", "path", path)
+		return
+	}
+	if len(allocations) == 0 {
+		return
+	}
+
+	ctx, cancel := dp.stopContext(context.Background())
+	defer cancel()
+	devices, err := dp.getDevices(ctx)
+	if err != nil {
+		dp.log.Error(err, "Failed to get devices, restoring all allocations without checking they still exist")
+	} else {
+		kept := allocations[:0]
+		for _, a := range allocations {
+			if _, ok := (*devices)[a.DeviceID]; ok {
+				kept = append(kept, a)
+			} else {
+				dp.log.Info("Dropping the allocation of a device that no longer exists", "id", a.DeviceID, "allocatedAt", a.AllocatedAt)
+			}
+		}
+		allocations = kept
+	}
+
+	dp.allocations.restore(allocations)
+	dp.log.Info("Restored allocations from the checkpoint", "path", path, "count", len(allocations))
+	dp.writeCheckpoint()
+}
+
+// preferUnallocated orders the candidates that are known to be allocated
+// last. Kubelet only offers devices it considers free, so this only matters
+// when its view and ours disagree. Ties keep their order.
+func (dp *dpServer) preferUnallocated(candidates []string) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return !dp.allocations.isAllocated(candidates[i]) && dp.allocations.isAllocated(candidates[j])
+	})
+}
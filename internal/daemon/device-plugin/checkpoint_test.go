@@ -0,0 +1,78 @@
+package deviceplugin
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Allocation checkpoint", func() {
+	var (
+		dp      *dpServer
+		handler *changingDeviceHandler
+	)
+
+	// restarted returns a Device Plugin for the same resource and paths, as
+	// after a restart of the daemon.
+	restarted := func() *dpServer {
+		restarted := NewDevicePlugin(nil, true, dp.pathManager)
+		WithDeviceHandler(handler)(restarted)
+		restarted.restoreCheckpoint()
+		return restarted
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		handler = &changingDeviceHandler{ids: []string{"dev0", "dev1", "dev2"}}
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should restore the allocations after a restart", func() {
+		allocations := restarted().GetAllocations()
+		Expect(allocations).To(HaveLen(2))
+		Expect(allocations[0].DeviceID).To(Equal("dev0"))
+		Expect(allocations[0].Owner.ContainerIndex).To(Equal(0))
+		Expect(allocations[1].DeviceID).To(Equal("dev1"))
+		Expect(allocations[1].Owner.ContainerIndex).To(Equal(1))
+	})
+
+	It("should not restore released allocations", func() {
+		dp.releaseAllocation("dev0")
+
+		allocations := restarted().GetAllocations()
+		Expect(allocations).To(HaveLen(1))
+		Expect(allocations[0].DeviceID).To(Equal("dev1"))
+	})
+
+	It("should drop the allocations of devices that no longer exist", func() {
+		handler.set("dev1", "dev2")
+
+		allocations := restarted().GetAllocations()
+		Expect(allocations).To(HaveLen(1))
+		Expect(allocations[0].DeviceID).To(Equal("dev1"))
+
+		// The dropped allocation stays dropped
+		Expect(restarted().GetAllocations()).To(HaveLen(1))
+	})
+
+	It("should start without allocations from a corrupt checkpoint", func() {
+		Expect(os.WriteFile(checkpointPath(dp.pathManager, dp.resourceName), []byte("{"), 0o644)).To(Succeed())
+		Expect(restarted().GetAllocations()).To(BeEmpty())
+	})
+
+	It("should prefer devices that aren't allocated", func() {
+		dp = restarted()
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs: []string{"dev0", "dev1", "dev2"},
+				AllocationSize:     1,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].DeviceIDs).To(Equal([]string{"dev2"}))
+	})
+})
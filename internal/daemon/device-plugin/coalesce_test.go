@@ -0,0 +1,141 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// changingDeviceHandler serves a set of devices that tests can change while
+// ListAndWatch is running.
+type changingDeviceHandler struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (h *changingDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (h *changingDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	devices := make(dh.DeviceList)
+	for _, id := range h.ids {
+		devices[id] = pluginapi.Device{ID: id, Health: pluginapi.Healthy}
+	}
+	return &devices, nil
+}
+
+func (h *changingDeviceHandler) set(ids ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ids = ids
+}
+
+// lockedListAndWatchServer records the responses sent by a ListAndWatch
+// running in another goroutine, and fails sends once closed.
+type lockedListAndWatchServer struct {
+	grpc.ServerStream
+	mu     sync.Mutex
+	sent   [][]string
+	closed bool
+	// ctx is the context of the stream, cancelled when Kubelet goes away.
+	ctx context.Context
+}
+
+func (s *lockedListAndWatchServer) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *lockedListAndWatchServer) Send(resp *pluginapi.ListAndWatchResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("stream closed")
+	}
+	s.sent = append(s.sent, sentIDs(resp))
+	return nil
+}
+
+func (s *lockedListAndWatchServer) sends() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.sent...)
+}
+
+var _ = Describe("Send coalescing", func() {
+	var (
+		dp      *dpServer
+		handler *changingDeviceHandler
+		stream  *lockedListAndWatchServer
+		window  time.Duration
+	)
+
+	BeforeEach(func() {
+		window = 300 * time.Millisecond
+	})
+
+	JustBeforeEach(func() {
+		handler = &changingDeviceHandler{ids: []string{"dev0"}}
+		stream = &lockedListAndWatchServer{}
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		WithCoalesceWindow(window)(dp)
+		dp.pollInterval = time.Hour
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			handler.set()
+			dp.triggerUpdate()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(HaveLen(1))
+	})
+
+	It("should send a burst of changes once, with the final state", func() {
+		handler.set("dev0", "dev1")
+		dp.triggerUpdate()
+		handler.set("dev1")
+		dp.triggerUpdate()
+		handler.set("dev1", "dev2")
+		dp.triggerUpdate()
+
+		Eventually(stream.sends).Should(HaveLen(2))
+		Consistently(stream.sends, 500*time.Millisecond).Should(HaveLen(2))
+		Expect(stream.sends()[1]).To(Equal([]string{"dev1", "dev2"}))
+	})
+
+	Context("without a window", func() {
+		BeforeEach(func() {
+			window = 0
+		})
+
+		It("should send every update right away", func() {
+			handler.set("dev1")
+			dp.triggerUpdate()
+			Eventually(stream.sends).Should(HaveLen(2))
+			handler.set("dev2")
+			dp.triggerUpdate()
+			Eventually(stream.sends).Should(HaveLen(3))
+		})
+	})
+})
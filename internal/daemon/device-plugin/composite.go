@@ -0,0 +1,51 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HealthReducer decides how the results of the sources of a composite health
+// source are combined.
+type HealthReducer string
+
+const (
+	// AllHealthy considers a device healthy when every source does.
+	AllHealthy HealthReducer = "all-healthy"
+	// AnyHealthy considers a device healthy when at least one source does.
+	AnyHealthy HealthReducer = "any-healthy"
+)
+
+// NamedHealthSource is a source of a composite health source. The name
+// prefixes the failures of the source, so that it's clear which one failed.
+type NamedHealthSource struct {
+	Name string
+	HealthSource
+}
+
+// compositeHealthSource combines several health sources, e.g. the link state
+// and a vendor check, into one.
+type compositeHealthSource struct {
+	reducer HealthReducer
+	sources []NamedHealthSource
+}
+
+func NewCompositeHealthSource(reducer HealthReducer, sources ...NamedHealthSource) HealthSource {
+	return &compositeHealthSource{reducer: reducer, sources: sources}
+}
+
+// DeviceHealth checks every source, also once the outcome is known, so that
+// the failure lists all failing sources.
+func (s *compositeHealthSource) DeviceHealth(ctx context.Context, id string) error {
+	var reasons []string
+	for _, source := range s.sources {
+		if err := source.DeviceHealth(ctx, id); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", source.Name, err))
+		}
+	}
+	if len(reasons) == 0 || (s.reducer == AnyHealthy && len(reasons) < len(s.sources)) {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(reasons, "; "))
+}
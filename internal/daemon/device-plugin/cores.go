@@ -0,0 +1,74 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"k8s.io/utils/cpuset"
+)
+
+// cpuTopology reads the CPU affinity of devices from sysfs.
+//
+// Kubelet's TopologyInfo only carries NUMA nodes and the Topology Manager
+// aligns CPUs at NUMA granularity, so the NUMA nodes advertised to Kubelet
+// are as fine-grained as the hints can get. The core sets computed here are
+// therefore informative only: they are exposed for monitoring and to let
+// operators pin latency critical workloads by hand.
+type cpuTopology struct {
+	sysfsRoot string
+}
+
+func newCPUTopology() *cpuTopology {
+	return &cpuTopology{sysfsRoot: "/sys"}
+}
+
+func (t *cpuTopology) readCPUList(path string) (cpuset.CPUSet, error) {
+	data, err := os.ReadFile(filepath.Join(t.sysfsRoot, path))
+	if err != nil {
+		return cpuset.New(), err
+	}
+	cpus, err := cpuset.Parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		return cpuset.New(), fmt.Errorf("failed to parse CPU list %s: %v", path, err)
+	}
+	return cpus, nil
+}
+
+// deviceCores returns the CPU cores closest to a device. For PCI devices the
+// cores local to the device are used, which can be narrower than the NUMA
+// node, e.g. on platforms with sub-NUMA clustering. Otherwise the cores of
+// the device's NUMA nodes are used. Unknown affinity yields an empty set.
+func (t *cpuTopology) deviceCores(dev pluginapi.Device) cpuset.CPUSet {
+	if sriovutils.IsValidPCIAddress(dev.ID) {
+		cpus, err := t.readCPUList(filepath.Join("bus/pci/devices", dev.ID, "local_cpulist"))
+		if err == nil && cpus.Size() > 0 {
+			return cpus
+		}
+	}
+
+	cores := cpuset.New()
+	for _, node := range deviceNumaNodes(dev) {
+		cpus, err := t.readCPUList(fmt.Sprintf("devices/system/node/node%d/cpulist", node))
+		if err == nil {
+			cores = cores.Union(cpus)
+		}
+	}
+	return cores
+}
+
+// PreferredCoreSets returns the preferred CPU cores of every known device in
+// the Linux CPU list format, e.g. "0-3,8". Devices with unknown affinity are
+// omitted.
+func (dp *dpServer) PreferredCoreSets() map[string]string {
+	coreSets := make(map[string]string)
+	for id, dev := range dp.cachedDevices() {
+		if cores := dp.cpuTopology.deviceCores(dev); cores.Size() > 0 {
+			coreSets[id] = cores.String()
+		}
+	}
+	return coreSets
+}
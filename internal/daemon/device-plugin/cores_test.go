@@ -0,0 +1,54 @@
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Preferred core sets", func() {
+	var dp *dpServer
+
+	writeSysfs := func(path string, content string) {
+		path = filepath.Join(dp.cpuTopology.sysfsRoot, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content+"\n"), 0o644)).To(Succeed())
+	}
+
+	withNuma := func(id string, nodes ...int64) {
+		dev := dp.devices[id]
+		dev.Topology = &pluginapi.TopologyInfo{}
+		for _, node := range nodes {
+			dev.Topology.Nodes = append(dev.Topology.Nodes, &pluginapi.NUMANode{ID: node})
+		}
+		dp.devices[id] = dev
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("0000:3b:00.1", "eth0", "eth1", "eth2")
+		dp.cpuTopology = &cpuTopology{sysfsRoot: GinkgoT().TempDir()}
+		writeSysfs("devices/system/node/node0/cpulist", "0-7,16-23")
+		writeSysfs("devices/system/node/node1/cpulist", "8-15,24-31")
+	})
+
+	It("should compute the core sets from the device topology", func() {
+		writeSysfs("bus/pci/devices/0000:3b:00.1/local_cpulist", "4-7,20-23")
+		withNuma("0000:3b:00.1", 0)
+		withNuma("eth0", 1)
+		withNuma("eth1", 0, 1)
+
+		Expect(dp.PreferredCoreSets()).To(Equal(map[string]string{
+			"0000:3b:00.1": "4-7,20-23",
+			"eth0":         "8-15,24-31",
+			"eth1":         "0-31",
+		}))
+	})
+
+	It("should fall back to the NUMA node of PCI devices without local CPUs", func() {
+		withNuma("0000:3b:00.1", 1)
+		Expect(dp.PreferredCoreSets()).To(HaveKeyWithValue("0000:3b:00.1", "8-15,24-31"))
+	})
+})
@@ -0,0 +1,103 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// datagramQueueSize is how many events wait to be written before new
+	// ones are dropped.
+	datagramQueueSize = 256
+	// datagramWriteTimeout bounds a write to a receiver that doesn't keep up.
+	datagramWriteTimeout = 100 * time.Millisecond
+)
+
+// DatagramEventSink writes each event as compact JSON to a unix datagram
+// socket, fire-and-forget. Events are written from a goroutine of its own, an
+// event is dropped when the queue is full or the write fails, e.g. because no
+// receiver is bound to the socket.
+type DatagramEventSink struct {
+	log    logr.Logger
+	path   string
+	events chan Event
+	conn   *net.UnixConn
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewDatagramEventSink starts writing events to the unix datagram socket at
+// path. The receiver may bind the socket before or after, it's dialed again
+// after each failure.
+func NewDatagramEventSink(path string) *DatagramEventSink {
+	s := &DatagramEventSink{
+		log:    ctrl.Log.WithName("DatagramEventSink"),
+		path:   path,
+		events: make(chan Event, datagramQueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *DatagramEventSink) Send(event Event) {
+	select {
+	case s.events <- event:
+	default:
+		droppedEventsTotal.WithLabelValues(event.Resource).Inc()
+	}
+}
+
+// Close stops writing events, the queued ones are dropped.
+func (s *DatagramEventSink) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+func (s *DatagramEventSink) run() {
+	defer func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			if err := s.write(event); err != nil {
+				droppedEventsTotal.WithLabelValues(event.Resource).Inc()
+				s.log.V(1).Info("Dropped event", "path", s.path, "type", event.Type, "reason", err)
+			}
+		}
+	}
+}
+
+func (s *DatagramEventSink) write(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if s.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.path, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(datagramWriteTimeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(data); err != nil {
+		// The receiver may have gone away, dial again next time.
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
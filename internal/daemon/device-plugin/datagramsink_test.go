@@ -0,0 +1,97 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Datagram event sink", func() {
+	var (
+		dp   *dpServer
+		path string
+		sink *DatagramEventSink
+	)
+
+	BeforeEach(func() {
+		// Unix socket paths are limited to 108 bytes, too short for
+		// GinkgoT().TempDir().
+		dir, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		path = filepath.Join(dir, "events.sock")
+
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		sink = NewDatagramEventSink(path)
+		DeferCleanup(sink.Close)
+		WithEventSink(sink)(dp)
+	})
+
+	receive := func(conn *net.UnixConn) Event {
+		buf := make([]byte, 4096)
+		Expect(conn.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+		n, err := conn.Read(buf)
+		Expect(err).NotTo(HaveOccurred())
+		var event Event
+		Expect(json.Unmarshal(buf[:n], &event)).To(Succeed())
+		return event
+	}
+
+	It("should send allocation and health events as JSON", func() {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}, []string{}))
+		Expect(err).NotTo(HaveOccurred())
+		event := receive(conn)
+		Expect(event.Type).To(Equal(EventAllocated))
+		Expect(event.Resource).To(Equal(dp.resourceName))
+		Expect(event.Devices).To(Equal([]string{"dev0", "dev1"}))
+
+		old := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Healthy},
+		}
+		new := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Unhealthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Healthy},
+			"dev2": {ID: "dev2", Health: pluginapi.Unhealthy},
+		}
+		dp.sendHealthChanges(&old, &new)
+		event = receive(conn)
+		Expect(event.Type).To(Equal(EventHealthChanged))
+		Expect(event.Devices).To(Equal([]string{"dev0"}))
+		Expect(event.Health).To(Equal(pluginapi.Unhealthy))
+	})
+
+	It("should drop events without blocking while no receiver is bound", func() {
+		dropped := counterValue(droppedEventsTotal.WithLabelValues(dp.resourceName))
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			for i := 0; i < 2*datagramQueueSize; i++ {
+				_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}()
+		Eventually(done).Should(BeClosed())
+		Eventually(func() float64 { return counterValue(droppedEventsTotal.WithLabelValues(dp.resourceName)) }).Should(BeNumerically(">=", dropped+2*datagramQueueSize))
+
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev2"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(receive(conn).Devices).To(Equal([]string{"dev2"}))
+	})
+})
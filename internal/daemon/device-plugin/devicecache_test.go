@@ -0,0 +1,81 @@
+package deviceplugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// flappingDeviceHandler flips the health of dev1 on every GetDevices, so that
+// every reconcile changes the device cache.
+type flappingDeviceHandler struct {
+	mu      sync.Mutex
+	healthy bool
+}
+
+func (h *flappingDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (h *flappingDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.healthy = !h.healthy
+	health := pluginapi.Unhealthy
+	if h.healthy {
+		health = pluginapi.Healthy
+	}
+	return &dh.DeviceList{
+		"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+		"dev1": {ID: "dev1", Health: health},
+	}, nil
+}
+
+// Run with -race to catch unguarded accesses to the device cache.
+var _ = Describe("Device cache", func() {
+	It("should be safe to read while ListAndWatch updates it", func() {
+		dp := newTestDevicePlugin("dev0", "dev1")
+		WithDeviceHandler(&flappingDeviceHandler{})(dp)
+		WithCoalesceWindow(0)(dp)
+		dp.pollInterval = time.Millisecond
+		stream := &lockedListAndWatchServer{}
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			Eventually(done).Should(BeClosed())
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					// dev1 may be unhealthy at the time, only dev0 must
+					// always succeed.
+					_, _ = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+					_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+					Expect(err).NotTo(HaveOccurred())
+					_ = dp.diagnostics("test")
+					_, _ = dp.SimulateAllocate(context.Background(), 1, nil, "")
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(len(stream.sends())).To(BeNumerically(">", 1))
+	})
+})
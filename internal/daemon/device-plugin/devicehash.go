@@ -0,0 +1,52 @@
+package deviceplugin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// deviceListHash is a content hash of the devices, covering their IDs,
+// health and topology, independent of the map order. ListAndWatch compares
+// it across reconciles to only send the devices when they changed.
+func deviceListHash(devices *dh.DeviceList) (string, error) {
+	h := sha256.New()
+	for _, id := range sortedDeviceIDs(devices) {
+		dev := (*devices)[id]
+		data, err := dev.Marshal()
+		if err != nil {
+			return "", err
+		}
+		// The length prefix keeps the encoding of consecutive devices
+		// unambiguous.
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// advertiseNeeded returns whether the advertised devices must be sent to
+// Kubelet, and their hash to compare the next ones against. A device list
+// that can't be hashed is always sent.
+func (dp *dpServer) advertiseNeeded(advertised *dh.DeviceList, lastHash string) (bool, string) {
+	hash, err := deviceListHash(advertised)
+	if err != nil {
+		dp.log.Error(err, "Failed to hash the advertised devices")
+		return true, ""
+	}
+	if hash == lastHash && !dp.advertiseUnchanged {
+		return false, hash
+	}
+	return true, hash
+}
+
+// WithAdvertiseUnchanged sends the devices to Kubelet on every reconcile,
+// even when they are identical to the ones sent last. By default unchanged
+// devices aren't sent again.
+func WithAdvertiseUnchanged(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.advertiseUnchanged = enabled
+	}
+}
@@ -0,0 +1,81 @@
+package deviceplugin
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Unchanged devices", func() {
+	var (
+		dp      *dpServer
+		handler *changingDeviceHandler
+		stream  *lockedListAndWatchServer
+	)
+
+	BeforeEach(func() {
+		handler = &changingDeviceHandler{ids: []string{"dev0", "dev1"}}
+		stream = &lockedListAndWatchServer{}
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		WithCoalesceWindow(0)(dp)
+		dp.pollInterval = time.Hour
+	})
+
+	run := func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			handler.set()
+			dp.triggerUpdate()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(HaveLen(1))
+	}
+
+	It("should hash the same devices the same regardless of order", func() {
+		hash, err := deviceListHash(testDeviceList(3))
+		Expect(err).NotTo(HaveOccurred())
+		again, err := deviceListHash(testDeviceList(3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(hash))
+
+		devices := testDeviceList(3)
+		dev := (*devices)["dev01"]
+		dev.Health = pluginapi.Unhealthy
+		(*devices)["dev01"] = dev
+		changed, err := deviceListHash(devices)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).NotTo(Equal(hash))
+	})
+
+	It("should not send again when discovery returns an identical set", func() {
+		run()
+		for i := 0; i < 3; i++ {
+			handler.set("dev1", "dev0")
+			dp.triggerUpdate()
+		}
+		Consistently(stream.sends, 300*time.Millisecond).Should(HaveLen(1))
+
+		handler.set("dev1")
+		dp.triggerUpdate()
+		Eventually(stream.sends).Should(HaveLen(2))
+	})
+
+	It("should send unchanged devices when configured to", func() {
+		WithAdvertiseUnchanged(true)(dp)
+		run()
+		dp.triggerUpdate()
+		Eventually(stream.sends).Should(HaveLen(2))
+		Expect(stream.sends()[1]).To(Equal(stream.sends()[0]))
+	})
+})
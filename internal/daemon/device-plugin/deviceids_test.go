@@ -0,0 +1,95 @@
+package deviceplugin
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// rawVendorPlugin returns the devices as is, keys included, like a buggy
+// vendor plugin could.
+type rawVendorPlugin struct {
+	plugin.VendorPlugin
+
+	devices map[string]*pb.Device
+}
+
+func (v *rawVendorPlugin) SetNumVfs(ctx context.Context, count int32) (*pb.VfCount, error) {
+	return &pb.VfCount{VfCnt: count}, nil
+}
+
+func (v *rawVendorPlugin) GetDevices(ctx context.Context) (*pb.DeviceListResponse, error) {
+	return &pb.DeviceListResponse{Devices: v.devices}, nil
+}
+
+var _ = Describe("Vendor device IDs", func() {
+	var (
+		dp  *dpServer
+		vsp *rawVendorPlugin
+	)
+
+	BeforeEach(func() {
+		vsp = &rawVendorPlugin{}
+		dp = NewDevicePlugin(vsp, true, *utils.NewPathManager(GinkgoT().TempDir()))
+		Expect(dp.SetupDevices()).To(Succeed())
+	})
+
+	advertised := func() []string {
+		devices, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		return slices.Sorted(maps.Keys(*devices))
+	}
+
+	It("should keep a single device of duplicate IDs", func() {
+		vsp.devices = map[string]*pb.Device{
+			"a": {ID: "dev0", Health: "Healthy"},
+			"b": {ID: "dev0", Health: "LinkDown"},
+			"c": {ID: "dev1"},
+		}
+		for i := 0; i < 5; i++ {
+			devices, err := dp.deviceHandler.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slices.Sorted(maps.Keys(*devices))).To(Equal([]string{"dev0", "dev1"}))
+			Expect((*devices)["dev0"].Health).To(Equal(pluginapi.Healthy))
+		}
+	})
+
+	It("should ignore devices without ID", func() {
+		vsp.devices = map[string]*pb.Device{
+			"dev0": {ID: "dev0"},
+			"":     {ID: ""},
+			"dev1": {},
+			"dev2": nil,
+		}
+		Expect(advertised()).To(Equal([]string{"dev0"}))
+	})
+
+	It("should ignore replicas clashing with another device", func() {
+		vsp.devices = map[string]*pb.Device{
+			"dev0":    {ID: "dev0", Replicas: 2},
+			"dev0_r1": {ID: "dev0_r1"},
+		}
+		Expect(advertised()).To(Equal([]string{"dev0_r0", "dev0_r1"}))
+	})
+
+	It("should fail when no device is valid", func() {
+		vsp.devices = map[string]*pb.Device{
+			"a": {},
+			"b": nil,
+		}
+		_, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("all 2 devices")))
+	})
+
+	It("should not fail without devices", func() {
+		vsp.devices = map[string]*pb.Device{}
+		Expect(advertised()).To(BeEmpty())
+	})
+})
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
-	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,25 +17,259 @@ import (
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	"github.com/openshift/dpu-operator/internal/utils"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 const (
 	DpuResourceName = "openshift.io/dpu"
+
+	// devicesEnvName lists the devices allocated to a container, separated
+	// by commas. It's the original name, shells can't read it, see
+	// defaultDevicesEnvNames.
+	devicesEnvName = "NF-DEV"
+
+	defaultAllocateLatencyThreshold = 5 * time.Second
+
+	defaultCoalesceWindow = 100 * time.Millisecond
+
+	defaultGracefulStopTimeout = 5 * time.Second
+
+	defaultRegisterVerifyTimeout = 10 * time.Second
+	defaultRegisterSettleDelay   = time.Second
+	defaultRegisterAttempts      = 3
 )
 
 // dpServer manages the k8s Device Plugin Server
 type dpServer struct {
-	devices    map[string]pluginapi.Device // for Kubelet DP API
-	grpcServer *grpc.Server
+	resourceName   string
+	pluginEndpoint string
+	// devices is written by ListAndWatch and read by the gRPC handlers,
+	// access it with devicesMutex held or through device and cachedDevices.
+	devices      map[string]pluginapi.Device // for Kubelet DP API
+	devicesMutex sync.RWMutex
+	grpcServer   *grpc.Server
 	pluginapi.DevicePluginServer
 	log           logr.Logger
 	pathManager   utils.PathManager
 	deviceHandler dh.DeviceHandler
 	startedWg     sync.WaitGroup
 	vsp           plugin.VendorPlugin
+	introspection *introspectionServer
+	introspectLis net.Listener
+	// introspectionEnabled serves introspection on introspectionPath, of
+	// which each Device Plugin of a daemon needs its own.
+	introspectionEnabled bool
+	introspectionPath    string
+
+	pollInterval        time.Duration
+	minPollInterval     time.Duration
+	maxReconcileBackoff time.Duration
+
+	// updateCh wakes up ListAndWatch when the advertised devices need to be
+	// re-sent outside of the regular poll interval.
+	updateCh chan struct{}
+	// stopCh is closed when Stop begins, ending the ListAndWatch streams so
+	// that the gRPC server can stop gracefully within gracefulStopTimeout.
+	stopCh              chan struct{}
+	stopOnce            sync.Once
+	gracefulStopTimeout time.Duration
+
+	// strategy can be switched at runtime through SetStrategy.
+	strategyMutex   sync.RWMutex
+	strategy        AllocationStrategy
+	strategyEnabled bool
+
+	// kubeletWatchWg tracks the watch of the Kubelet socket, see watchKubelet.
+	kubeletWatchWg sync.WaitGroup
+	// podResourcesWg tracks the reconcile against the Kubelet PodResources
+	// API, see watchPodResources.
+	podResourcesWg sync.WaitGroup
+	// resyncInterval is how often the devices are resynced with the vendor
+	// plugin, see watchResync. Negative disables the resync.
+	resyncInterval time.Duration
+	resyncWg       sync.WaitGroup
+	// vendorDevices are the IDs the vendor plugin last reported to
+	// ListAndWatch, access them with devicesMutex held.
+	vendorDevices map[string]bool
+	// coalesceWindow is how long ListAndWatch waits for more updates after
+	// being woken up, so that a burst of changes results in a single send.
+	coalesceWindow time.Duration
+	drained        map[string]bool
+	drainMutex     sync.RWMutex
+	allocations    *allocationStore
+	responses      *responseCache
+
+	// checkpointMutex serializes writing the allocation checkpoint.
+	checkpointMutex sync.Mutex
+
+	// capacity is the capacity last written to the capacity file.
+	capacity      *CapacityStatus
+	capacityMutex sync.Mutex
+
+	// healthCacheTTL is how long healthCache is served, zero disables it.
+	healthCacheTTL time.Duration
+	healthCache    healthCache
+
+	// drainAll is set by Drain, it drains every device on top of the ones
+	// drained one by one. Guarded by drainMutex.
+	drainAll bool
+
+	clock clock.Clock
+	// allocateLatencyThreshold is the duration after which an Allocate call
+	// is reported as slow. Zero disables the check.
+	allocateLatencyThreshold time.Duration
+
+	healthSources []HealthSource
+	// healthWorkers is the number of devices checked concurrently by the
+	// health sources.
+	healthWorkers int
+	// healthFailures are the reasons the health sources last gave for the
+	// devices they reported as unhealthy.
+	healthFailures      map[string]string
+	healthFailuresMutex sync.RWMutex
+	// recoveryHysteresis is how long a device must stay healthy after
+	// failing before it is advertised as healthy again.
+	recoveryHysteresis time.Duration
+	// recovering holds when failed devices recovered, zero while failing.
+	recovering map[string]time.Time
+
+	trackStaleAllocations bool
+	// allocationTTL is how long an allocation stays valid without being
+	// refreshed, zero disables the leases. Expired allocations are released
+	// when reclaimExpired is set, and only flagged otherwise.
+	allocationTTL  time.Duration
+	reclaimExpired bool
+	numaEnv        bool
+	payloadEnv     bool
+	// zeroDevicesEnv is the value of the devices environment variables for
+	// containers without devices, nil leaves them unset.
+	zeroDevicesEnv *string
+	// devicesEnvNames are the variables the allocated devices are passed in,
+	// see WithDevicesEnvNames.
+	devicesEnvNames []string
+
+	// maxDevices caps the number of advertised devices, zero means no cap.
+	maxDevices int
+	// dropped counts the discovered devices that aren't advertised per reason.
+	dropped map[string]int
+
+	// requiredCapabilities must all be reported for a device to be advertised.
+	requiredCapabilities []string
+	capabilitySkipped    map[string]bool
+
+	// reservedIDs and reservedPercent keep devices for the host, see
+	// reserveDevices. reserved is the last reserved set, for logging.
+	reservedIDs     []string
+	reservedPercent int
+	reserved        []string
+
+	selfTestEnabled bool
+
+	// logSampler thins out the logs of high-frequency events.
+	logSampler *logSampler
+
+	// annotations is the vendor plugin's annotation template, nil until
+	// it was fetched.
+	annotations     *annotationTemplate
+	annotationMutex sync.Mutex
+
+	sortOrder DeviceSortOrder
+
+	maintenance      *maintenanceWindow
+	maintenanceMutex sync.Mutex
+
+	cpuTopology *cpuTopology
+
+	// kubeletContact is signaled whenever Kubelet calls into the Device
+	// Plugin, which confirms that a registration took effect.
+	kubeletContact        chan struct{}
+	registerVerifyTimeout time.Duration
+	registerSettleDelay   time.Duration
+	registerAttempts      int
+	registerThrottle      *registrationThrottle
+	// registration records the sockets used by the last successful
+	// registration, nil until then.
+	registration      *RegistrationInfo
+	registrationMutex sync.Mutex
+
+	// registerRetryTimeout bounds retrying a registration Kubelet failed
+	// transiently, see registerWithRetry.
+	registerRetryTimeout time.Duration
+
+	deviceInfoFor func(id string) DeviceInfo
+
+	// minVendorVersion is the oldest vendor plugin version the Device Plugin
+	// registers with Kubelet for, empty accepts any vendor plugin.
+	minVendorVersion           string
+	vendorVersionRetryInterval time.Duration
+
+	// expectedDriver is the driver the allocated devices must be bound to,
+	// empty disables the check.
+	expectedDriver string
+
+	// resetOnStart resets the devices through the vendor plugin before a
+	// container using them starts, see WithDeviceReset.
+	resetOnStart bool
+
+	allocateValidator AllocateValidator
+
+	// freeze rejects allocations while its file exists, nil disables it.
+	freeze *allocationFreeze
+	// readiness delays registering until its file exists, nil disables it.
+	readiness *readinessGate
+	// warm tracks recently prepared and released devices to prefer them,
+	// nil disables it.
+	warm *warmDevices
+	// podResources reconciles the allocations against the Kubelet
+	// PodResources API and releases the ones no container holds, by
+	// default. Nil disables it.
+	podResources *podResourcesReconcile
+	// eventSink receives allocation and health events, nil disables them.
+	eventSink EventSink
+	// advertiseUnchanged sends the devices on every reconcile rather than
+	// only when their hash changed.
+	advertiseUnchanged bool
+
+	// unavailableWhileEmpty reports requests for unknown devices as
+	// Unavailable as long as no devices are known.
+	unavailableWhileEmpty bool
+
+	// podLister looks up the pods being allocated devices, nil disables
+	// selecting devices by pod annotations.
+	podLister  PodLister
+	driverName func(pciAddr string) (string, error)
+
+	// listen opens the sockets, retried after listenRetryDelay while out of
+	// file descriptors.
+	listen           func(network, address string) (net.Listener, error)
+	listenRetryDelay time.Duration
+
+	// shuttingDown is set once Stop begins, new requests are rejected from
+	// then on while the ones in flight are waited for.
+	shuttingDown  bool
+	shutdownMutex sync.RWMutex
+	inFlight      sync.WaitGroup
+
+	// healthServer implements the standard gRPC health service, see
+	// updateServingStatus.
+	healthServer    *health.Server
+	registered      bool
+	vendorConnected bool
+	servingMutex    sync.Mutex
+	// serveBroken is set once the gRPC server stopped serving a socket
+	// Kubelet uses, and serveErrors tells Serve about it, see serveFailed.
+	serveBroken bool
+	serveErrors chan error
+
+	errors          errorLog
+	diagnosticsPath string
 }
 
 type DevicePlugin interface {
@@ -46,12 +281,9 @@ type DevicePlugin interface {
 }
 
 func (dp *dpServer) sendDevices(stream pluginapi.DevicePlugin_ListAndWatchServer, devices *dh.DeviceList) error {
-	resp := new(pluginapi.ListAndWatchResponse)
-	for _, dev := range *devices {
-		resp.Devices = append(resp.Devices, &dev)
-	}
+	resp := &pluginapi.ListAndWatchResponse{Devices: dp.orderedDevices(devices)}
 
-	dp.log.Info("SendDevices:", "resp", resp)
+	dp.sampledInfo(dp.log.V(1), "SendDevices:", "resp", resp)
 	if err := stream.Send(resp); err != nil {
 		dp.log.Error(err, "Cannot send devices to ListAndWatch server")
 		dp.grpcServer.Stop()
@@ -60,102 +292,424 @@ func (dp *dpServer) sendDevices(stream pluginapi.DevicePlugin_ListAndWatchServer
 	return nil
 }
 
-func (dp *dpServer) devicesEqual(d1, d2 *dh.DeviceList) bool {
-	if len(*d1) != len(*d2) {
-		return false
-	}
+func (dp *dpServer) setDeviceCache(devices *dh.DeviceList) {
+	dp.devicesMutex.Lock()
+	old := dp.devices
+	dp.devices = *devices
+	dp.devicesMutex.Unlock()
 
-	for d1key, d1value := range *d1 {
-		if d2value, ok := (*d2)[d1key]; !ok || !reflect.DeepEqual(d1value, d2value) {
-			return false
-		}
+	dp.invalidateChangedResponses(old, *devices)
+	for id, dev := range *devices {
+		dp.log.V(1).Info("Cached device", "id", id, "dev.ID", dev.ID)
 	}
+}
 
-	return true
+// device returns the cached device.
+func (dp *dpServer) device(id string) (pluginapi.Device, bool) {
+	dp.devicesMutex.RLock()
+	defer dp.devicesMutex.RUnlock()
+	dev, ok := dp.devices[id]
+	return dev, ok
 }
 
-func (dp *dpServer) setDeviceCache(devices *dh.DeviceList) {
-	dp.devices = *devices
+// cachedDevices returns a copy of the device cache.
+func (dp *dpServer) cachedDevices() dh.DeviceList {
+	dp.devicesMutex.RLock()
+	defer dp.devicesMutex.RUnlock()
+	devices := make(dh.DeviceList, len(dp.devices))
 	for id, dev := range dp.devices {
-		dp.log.Info("Cached device", "id", id, "dev.ID", dev.ID)
+		devices[id] = dev
 	}
+	return devices
+}
+
+// checkAdvertised rejects requests for devices Kubelet can only have learnt
+// about from somewhere else than our ListAndWatch, which points at an ID
+// mismatch between what we advertise and what we accept. While no devices are
+// known at all, e.g. while resyncing, the devices may only be temporarily
+// unknown, which can be reported as retryable instead.
+func (dp *dpServer) checkAdvertised(rqt *pluginapi.AllocateRequest) error {
+	devices := dp.cachedDevices()
+	for _, container := range rqt.ContainerRequests {
+		for _, id := range container.DevicesIDs {
+			if _, ok := devices[id]; ok {
+				continue
+			}
+			if len(devices) == 0 && dp.unavailableWhileEmpty {
+				dp.log.Info("Allocate received a device ID while no devices are known, asking to retry", "id", id)
+				return status.Errorf(codes.Unavailable, "no devices are known yet, device %s may become available", id)
+			}
+			err := status.Errorf(codes.NotFound, "invalid allocation request with non-existing device: %s was never advertised", id)
+			dp.log.Error(err, "Allocate received a device ID that was never advertised", "id", id, "advertised", len(devices))
+			return err
+		}
+	}
+	return nil
 }
 
 func (dp *dpServer) checkCachedDeviceHealth(id string) (bool, error) {
-	dev, ok := dp.devices[id]
+	dev, ok := dp.device(id)
 	if !ok {
-		return false, fmt.Errorf("invalid allocation request with non-existing device: %s", id)
+		return false, status.Errorf(codes.NotFound, "invalid allocation request with non-existing device: %s", id)
 	}
 	return dev.Health == pluginapi.Healthy, nil
 }
 
 func (dp *dpServer) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
+	defer dp.dumpDiagnosticsOnPanic()
+	dp.markKubeletContact()
+	// Stopping the Device Plugin cancels the calls to the vendor plugin too.
+	ctx, cancel := dp.stopContext(stream.Context())
+	defer cancel()
 	oldDevices := make(dh.DeviceList)
+	advertisedHash := ""
+	backoff := newReconcileBackoff(dp.pollInterval, dp.maxReconcileBackoff)
 	for {
-		newDevices, err := dp.deviceHandler.GetDevices()
+		// An unreachable vendor plugin says nothing about the devices, so
+		// the last advertised devices are kept rather than withdrawn, which
+		// would get the pods using them evicted. Only a vendor plugin that
+		// answers with no devices withdraws them.
+		newDevices, err := dp.getDevices(ctx)
+		dp.setVendorConnected(err == nil)
 		if err != nil {
-			dp.log.Error(err, "Failed to get Devices")
-			return err
+			dp.recordError(fmt.Errorf("failed to get devices: %v", err))
+			interval := backoff.failure()
+			if backoff.shouldLog() {
+				dp.log.Error(err, "Failed to get Devices, keeping the advertised devices and backing off", "advertised", len(oldDevices),
+					"failures", backoff.failures, "retryIn", interval)
+			}
+			if !dp.waitForUpdate(ctx, interval) {
+				return nil
+			}
+			continue
+		}
+		if backoff.failures > 0 {
+			dp.log.Info("Getting Devices recovered", "failures", backoff.failures)
 		}
-		if !dp.devicesEqual(&oldDevices, newDevices) {
-			err := dp.sendDevices(stream, newDevices)
+		interval := backoff.success()
+		dp.recordVendorDevices(newDevices)
+		physical := dp.physicalCapacity(newDevices)
+		if physical == 0 && len(oldDevices) > 0 {
+			dp.log.Info("Vendor plugin reports no devices, withdrawing the advertised devices", "advertised", len(oldDevices))
+		}
+		// Devices that are only filtered out below didn't disappear.
+		dp.checkStaleAllocations(newDevices)
+		dp.filterCapabilities(newDevices)
+		dp.reserveDevices(newDevices)
+		dp.capDevices(newDevices)
+		dp.applyHealthSources(newDevices)
+		dp.applyRecoveryHysteresis(newDevices)
+		dp.checkExpiredAllocations()
+		dp.clampToCapacity(physical, newDevices)
+		advertised := dp.advertisedDevices(newDevices)
+		dp.reportNumaAvailability(advertised)
+		dp.reportAdvertisedHealth(advertised)
+		dp.writeCapacity(advertised)
+		send, hash := dp.advertiseNeeded(advertised, advertisedHash)
+		if send {
+			err := dp.sendDevices(stream, advertised)
 			if err != nil {
+				dp.recordError(fmt.Errorf("failed to send devices: %v", err))
 				dp.log.Error(err, "Failed to send Devices")
 				return err
 			}
-			oldDevices = *newDevices
+			dp.sendHealthChanges(&oldDevices, advertised)
+			oldDevices = *advertised
+			advertisedHash = hash
 			dp.setDeviceCache(newDevices)
 		}
-		time.Sleep(5 * time.Second)
+		if !dp.waitForUpdate(ctx, interval) {
+			return nil
+		}
+	}
+}
+
+// RefreshAllocations renews the lease of the allocated devices among ids,
+// for callers that know the consuming containers are still alive, such as
+// the PodResources reconcile.
+func (dp *dpServer) RefreshAllocations(ids ...string) {
+	dp.allocations.refresh(ids, dp.clock.Now())
+}
+
+// checkExpiredAllocations flags the allocations whose lease expired, and
+// reclaims them if configured to.
+func (dp *dpServer) checkExpiredAllocations() {
+	if dp.allocationTTL <= 0 {
+		return
+	}
+	for _, a := range dp.allocations.markExpired(dp.allocationTTL, dp.clock.Now()) {
+		expiredAllocationsTotal.WithLabelValues(dp.resourceName).Inc()
+		if !dp.reclaimExpired {
+			dp.log.Info("Warning: allocation expired, flagging it for reclaim", "id", a.DeviceID, "refreshedAt", a.RefreshedAt)
+			continue
+		}
+		dp.log.Info("Warning: allocation expired, reclaiming it", "id", a.DeviceID, "refreshedAt", a.RefreshedAt)
+		dp.releaseAllocation(a.DeviceID)
+	}
+}
+
+// checkStaleAllocations detects allocated devices that vanished from the
+// vendor plugin, e.g. because they were hot-unplugged. Kubelet keeps the pod
+// running with a dead device in that case, so make it visible.
+func (dp *dpServer) checkStaleAllocations(devices *dh.DeviceList) {
+	if !dp.trackStaleAllocations {
+		return
+	}
+
+	present := func(id string) bool {
+		_, ok := (*devices)[id]
+		return ok
+	}
+	for _, a := range dp.allocations.markStale(present, dp.clock.Now()) {
+		staleAllocationsTotal.WithLabelValues(dp.resourceName).Inc()
+		dp.log.Info("Warning: allocated device disappeared, allocation is stale", "id", a.DeviceID, "allocatedAt", a.AllocatedAt)
+	}
+}
+
+// waitForUpdate blocks for the given interval or until an update is triggered.
+// Updates triggered within the coalescing window of the first one are folded
+// into it, the devices are only evaluated once the window has passed so that
+// the send reflects the latest state. Returns false once the Device Plugin is
+// stopping or Kubelet went away, without waiting out the interval.
+func (dp *dpServer) waitForUpdate(ctx context.Context, interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-dp.stopCh:
+		return false
+	case <-ctx.Done():
+		dp.log.Info("ListAndWatch stream closed by Kubelet")
+		return false
+	case <-timer.C:
+	case <-dp.updateCh:
+		if dp.coalesceWindow <= 0 {
+			return true
+		}
+		time.Sleep(dp.coalesceWindow)
+		select {
+		case <-dp.updateCh:
+		default:
+		}
+	}
+	return true
+}
+
+// triggerUpdate makes ListAndWatch re-evaluate the advertised devices without
+// waiting for the next poll.
+func (dp *dpServer) triggerUpdate() {
+	select {
+	case dp.updateCh <- struct{}{}:
+	default:
+	}
+}
+
+// checkDevicesNotShared makes sure that no device is handed out to more than one
+// container of the same request. Kubelet should never do this, but allocating a
+// device twice would silently break isolation between the containers.
+func (dp *dpServer) checkDevicesNotShared(rqt *pluginapi.AllocateRequest) error {
+	owners := make(map[string]int)
+	for i, container := range rqt.ContainerRequests {
+		for _, id := range container.DevicesIDs {
+			if owner, ok := owners[id]; ok && owner != i {
+				return status.Errorf(codes.InvalidArgument, "invalid allocation request with device %s requested by containers %d and %d", id, owner, i)
+			}
+			owners[id] = i
+		}
 	}
+	return nil
+}
+
+// observeAllocateLatency reports Allocate calls that took longer than the
+// configured threshold. Slow vendor preparation would otherwise only show up
+// as pod start timeouts.
+func (dp *dpServer) observeAllocateLatency(start time.Time, rqt *pluginapi.AllocateRequest) {
+	latency := dp.clock.Since(start)
+	allocateDuration.WithLabelValues(dp.resourceName).Observe(latency.Seconds())
+	if dp.allocateLatencyThreshold <= 0 || latency <= dp.allocateLatencyThreshold {
+		return
+	}
+
+	var ids []string
+	for _, container := range rqt.ContainerRequests {
+		ids = append(ids, container.DevicesIDs...)
+	}
+	allocateSlowTotal.WithLabelValues(dp.resourceName).Inc()
+	dp.log.Info("Warning: Allocate exceeded latency threshold", "latency", latency, "threshold", dp.allocateLatencyThreshold, "devices", ids)
+}
+
+// allocateFailed counts a failed allocation by reason and returns err.
+func (dp *dpServer) allocateFailed(reason string, err error) error {
+	allocationFailuresTotal.WithLabelValues(dp.resourceName, reason).Inc()
+	return err
+}
+
+// beginRequest registers a request in flight, or fails with Unavailable once
+// the Device Plugin is shutting down. The returned function ends the request.
+func (dp *dpServer) beginRequest(method string) (func(), error) {
+	dp.shutdownMutex.RLock()
+	defer dp.shutdownMutex.RUnlock()
+	if dp.shuttingDown {
+		return nil, status.Errorf(codes.Unavailable, "%s rejected, the Device Plugin is shutting down", method)
+	}
+	dp.inFlight.Add(1)
+	return dp.inFlight.Done, nil
 }
 
 // Allocate passes the dev name as an env variable to the requesting container
 func (dp *dpServer) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	defer dp.dumpDiagnosticsOnPanic()
+	done, err := dp.beginRequest("Allocate")
+	if err != nil {
+		return nil, dp.allocateFailed(allocateFailureShuttingDown, err)
+	}
+	defer done()
+
+	if err := dp.checkNotFrozen(); err != nil {
+		return nil, dp.allocateFailed(allocateFailureFrozen, err)
+	}
+
+	inFlight := allocateInFlight.WithLabelValues(dp.resourceName)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	resp, err := dp.allocate(ctx, rqt)
+	if err != nil {
+		dp.recordError(fmt.Errorf("allocate failed: %v", err))
+	} else {
+		allocationsTotal.WithLabelValues(dp.resourceName).Inc()
+	}
+	return resp, err
+}
+
+func (dp *dpServer) allocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	defer dp.observeAllocateLatency(dp.clock.Now(), rqt)
+
+	if err := dp.checkDevicesNotShared(rqt); err != nil {
+		dp.log.Error(err, "Rejecting allocation")
+		return nil, dp.allocateFailed(allocateFailureShared, err)
+	}
+	if err := dp.checkAdvertised(rqt); err != nil {
+		return nil, dp.allocateFailed(allocateFailureNotAdvertised, err)
+	}
+	for _, container := range rqt.ContainerRequests {
+		if err := dp.checkDriverBinding(container.DevicesIDs); err != nil {
+			dp.log.Error(err, "Rejecting allocation")
+			return nil, dp.allocateFailed(allocateFailureDriver, err)
+		}
+	}
+
 	resp := new(pluginapi.AllocateResponse)
-	devName := ""
 	for _, container := range rqt.ContainerRequests {
-		containerResp := new(pluginapi.ContainerAllocateResponse)
 		for _, id := range container.DevicesIDs {
-			dp.log.Info("DeviceID in Allocate:", "id", id)
+			dp.sampledInfo(dp.log.V(1), "DeviceID in Allocate:", "id", id)
 			isHealthy, err := dp.checkCachedDeviceHealth(id)
 			if err != nil {
-				return nil, err
+				return nil, dp.allocateFailed(allocateFailureNotAdvertised, err)
 			}
-			dp.log.Info("DeviceID Health", "id", id, "isHealthy", isHealthy, "err", err)
+			dp.sampledInfo(dp.log.V(1), "DeviceID Health", "id", id, "isHealthy", isHealthy, "err", err)
 
 			if !isHealthy {
-				return nil, fmt.Errorf("invalid allocation request with unhealthy device: %s", id)
+				return nil, dp.allocateFailed(allocateFailureUnhealthy, status.Errorf(codes.FailedPrecondition, "invalid allocation request with unhealthy device: %s", id))
 			}
 
-			devName = devName + id + ","
+			if dp.isDrained(id) {
+				return nil, dp.allocateFailed(allocateFailureDrained, status.Errorf(codes.FailedPrecondition, "invalid allocation request with drained device: %s", id))
+			}
 		}
 
-		dp.log.Info("Device(s) allocated:", "devName", devName)
-		envmap := make(map[string]string)
-		envmap["NF-DEV"] = devName
+		// Each container only gets its own devices, replicas of the same
+		// shared device are passed once.
+		devName := strings.Join(dp.physicalDevices(container.DevicesIDs), ",")
 
-		containerResp.Envs = envmap
+		dp.sampledInfo(dp.log, "Device(s) allocated:", "devName", devName)
+		containerResp, err := dp.containerResponse(ctx, container.DevicesIDs)
+		if err != nil {
+			dp.log.Error(err, "Rejecting allocation")
+			return nil, dp.allocateFailed(allocateFailureResponse, err)
+		}
+		if len(container.DevicesIDs) > 0 {
+			dp.setDevicesEnv(containerResp.Envs, devName)
+			if dp.payloadEnv {
+				payload, err := dp.allocationPayloadEnvValue(container.DevicesIDs)
+				if err != nil {
+					dp.log.Error(err, "Rejecting allocation")
+					return nil, dp.allocateFailed(allocateFailureResponse, err)
+				}
+				containerResp.Envs[payloadEnvName] = payload
+			}
+		} else if dp.zeroDevicesEnv != nil {
+			dp.setDevicesEnv(containerResp.Envs, *dp.zeroDevicesEnv)
+		}
 		resp.ContainerResponses = append(resp.ContainerResponses, containerResp)
 	}
+
+	if err := dp.validateAllocate(ctx, rqt); err != nil {
+		dp.log.Error(err, "Rejecting allocation")
+		return nil, dp.allocateFailed(allocateFailureValidator, err)
+	}
+
+	for i, container := range rqt.ContainerRequests {
+		dp.allocations.record(container.DevicesIDs, dp.allocationOwner(ctx, i, container.DevicesIDs), dp.clock.Now())
+		if len(container.DevicesIDs) > 0 {
+			dp.writeCheckpoint()
+			dp.sendEvent(EventAllocated, container.DevicesIDs, "", "")
+		}
+		for _, id := range container.DevicesIDs {
+			// The environment stays the primary way to pass the devices, so
+			// don't fail the allocation over the info file.
+			if err := dp.writeDeviceInfo(id); err != nil {
+				dp.log.Error(err, "Failed to write device info", "id", id)
+			}
+		}
+	}
 	return resp, nil
 }
 
 func (dp *dpServer) Listen() (net.Listener, error) {
-	pluginEndpoint := dp.pathManager.PluginEndpoint()
+	pluginEndpoint := dp.pluginEndpoint
+
+	if err := validateResourceName(dp.resourceName); err != nil {
+		return nil, err
+	}
+	if err := validateEnvNames(dp.devicesEnvNames); err != nil {
+		return nil, err
+	}
+	if err := dp.validateMinVendorVersion(); err != nil {
+		return nil, err
+	}
 
+	// Check the directory first, its error is more actionable than the one
+	// of the self-test failing to listen in it.
+	if err := checkSocketDir(dp.socketDir()); err != nil {
+		return nil, err
+	}
+	if dp.selfTestEnabled {
+		if err := dp.selfTest(); err != nil {
+			return nil, err
+		}
+	}
 	err := dp.cleanup()
 	if err != nil {
 		return nil, fmt.Errorf("failed to cleanup Device Plugin server endpoint: %v", err)
 	}
+	dp.restoreCheckpoint()
 
 	dp.log.Info("Starting Device Plugin server at:", "pluginEndpoint", pluginEndpoint)
-	lis, err := net.Listen("unix", pluginEndpoint)
+	lis, err := dp.listenWithRetry(pluginEndpoint)
 	if err != nil {
-		return nil, fmt.Errorf("resource %s failed to listen to Device Plugin server: %v", DpuResourceName, err)
+		return nil, fmt.Errorf("resource %s failed to listen to Device Plugin server: %v", dp.resourceName, err)
 	}
 
 	pluginapi.RegisterDevicePluginServer(dp.grpcServer, dp)
+	healthpb.RegisterHealthServer(dp.grpcServer, dp.healthServer)
+
+	if dp.introspectionEnabled {
+		dp.introspectLis, err = dp.introspection.Listen()
+		if err != nil {
+			lis.Close()
+			return nil, err
+		}
+	}
 
 	dp.startedWg.Add(1)
 	return lis, nil
@@ -172,29 +726,50 @@ func (dp *dpServer) Serve(lis net.Listener) error {
 	// 	kubernetes/pkg/kubelet/cm/devicemanager/plugin/v1beta1/client.go (dial() func)
 	//
 	// Therefore we have the following workaround to make sure we start serving which includes trying
-	// to connect to ourselves in "ensureDevicePluginServerStarted" before registering with Kubelet.
+	// to connect to ourselves in "checkServing" before registering with Kubelet.
 	done := make(chan error, 1)
 	var err error
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		err = dp.grpcServer.Serve(lis)
+		// Serve only returns nil once the server is stopped.
+		err := dp.grpcServer.Serve(lis)
+		if err != nil {
+			dp.serveFailed(err)
+		}
 		done <- err
 		wg.Done()
 	}()
 
-	err = dp.ensureDevicePluginServerStarted()
+	if dp.introspectLis != nil {
+		go func() {
+			if err := dp.introspection.Serve(dp.introspectLis); err != nil && err != http.ErrServerClosed {
+				dp.log.Error(err, "Device Plugin introspection server failed")
+			}
+		}()
+	}
+
+	err = dp.checkServing(dp.pluginEndpoint)
 	if err != nil {
 		return fmt.Errorf("failed to ensure Device Plugin server started: %v", err)
 	}
 
-	err = dp.registerWithKubelet()
+	err = dp.registerWhenVendorCompatible()
 	if err != nil {
 		return fmt.Errorf("failed to register the Device Plugin server with Kubelet: %v", err)
 	}
+	dp.watchKubelet()
+	dp.watchPodResources()
+	dp.watchResync()
 
-	err = <-done
-	// The "serve" design paradigm must be a blocking call. Thus we wait here.
+	// The "serve" design paradigm must be a blocking call. Thus we wait here,
+	// also for the server of a socket recreated since failing.
+	select {
+	case err = <-done:
+	case err = <-dp.serveErrors:
+		dp.grpcServer.Stop()
+		<-done
+	}
 	wg.Wait()
 
 	if err != nil {
@@ -226,22 +801,94 @@ func (dp *dpServer) ListenAndServe() error {
 	return nil
 }
 
-func (dp *dpServer) ensureDevicePluginServerStarted() error {
-	pluginEndpoint := dp.pathManager.PluginEndpoint()
-	conn, err := dp.connectWithRetry("unix:" + pluginEndpoint)
+// checkServing makes a test connection to the gRPC server listening on
+// endpoint.
+func (dp *dpServer) checkServing(endpoint string) error {
+	conn, err := dp.connectWithRetry("unix:" + endpoint)
 	if err != nil {
-		return fmt.Errorf("resource %s unable to establish test connection with gRPC server: %v", DpuResourceName, err)
+		return fmt.Errorf("resource %s unable to establish test connection with gRPC server: %v", dp.resourceName, err)
 	}
-	dp.log.Info("Device plugin endpoint started serving:", "DpuResourceName", DpuResourceName)
+	dp.log.Info("Device plugin endpoint started serving:", "resourceName", dp.resourceName, "endpoint", endpoint)
 	conn.Close()
 	return nil
 }
 
+// registerWithKubelet registers the Device Plugin and verifies that Kubelet
+// picked up the registration by waiting for it to call into the plugin.
+// Kubelet may still hold on to an old registration of the same resource, in
+// which case the registration is retried after a short settling delay.
 func (dp *dpServer) registerWithKubelet() error {
+	for attempt := 1; ; attempt++ {
+		dp.clearKubeletContact()
+		if err := dp.throttledRegister(); err != nil {
+			return err
+		}
+		if dp.registerVerifyTimeout <= 0 || dp.waitForKubeletContact(dp.registerVerifyTimeout) {
+			dp.log.Info("Device plugin registered with Kubelet", "resourceName", dp.resourceName, "attempt", attempt)
+			dp.setRegistered(true)
+			return nil
+		}
+		if dp.isStopping() {
+			return fmt.Errorf("resource %s stopped while registering with Kubelet", dp.resourceName)
+		}
+		if attempt >= dp.registerAttempts {
+			return fmt.Errorf("Kubelet did not contact resource %s after %d registrations", dp.resourceName, attempt)
+		}
+		dp.log.Info("Kubelet did not contact the Device Plugin after registering, registering again", "attempt", attempt, "retryIn", dp.registerSettleDelay)
+		time.Sleep(dp.registerSettleDelay)
+	}
+}
+
+func (dp *dpServer) markKubeletContact() {
+	select {
+	case dp.kubeletContact <- struct{}{}:
+	default:
+	}
+}
+
+func (dp *dpServer) clearKubeletContact() {
+	select {
+	case <-dp.kubeletContact:
+	default:
+	}
+}
+
+func (dp *dpServer) waitForKubeletContact(timeout time.Duration) bool {
+	select {
+	case <-dp.kubeletContact:
+		return true
+	case <-dp.stopCh:
+		return false
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (dp *dpServer) isStopping() bool {
+	select {
+	case <-dp.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// dialKubelet returns a connection to the Kubelet registration socket. It's
+// established lazily by the first call.
+func (dp *dpServer) dialKubelet() (*grpc.ClientConn, error) {
 	kubeletEndpoint := filepath.Join("unix:", dp.pathManager.KubeletEndPoint())
 	conn, err := grpc.Dial(kubeletEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		return fmt.Errorf("resource %s unable connect to Kubelet: %v", DpuResourceName, err)
+		return nil, fmt.Errorf("resource %s unable connect to Kubelet: %v", dp.resourceName, err)
+	}
+	return conn, nil
+}
+
+func (dp *dpServer) register() error {
+	kubeletSocket := dp.pathManager.KubeletEndPoint()
+	conn, err := dp.dialKubelet()
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -249,15 +896,21 @@ func (dp *dpServer) registerWithKubelet() error {
 
 	request := &pluginapi.RegisterRequest{
 		Version:      pluginapi.Version,
-		Endpoint:     dp.pathManager.PluginEndpointFilename(),
-		ResourceName: DpuResourceName,
+		Endpoint:     filepath.Base(dp.pluginEndpoint),
+		ResourceName: dp.resourceName,
 	}
 
-	if _, err = client.Register(context.Background(), request); err != nil {
-		return fmt.Errorf("unable to register resource %s with Kubelet: %v", DpuResourceName, err)
+	if err := dp.registerWithRetry(client, request); err != nil {
+		return err
 	}
-	dp.log.Info("Device plugin registered with Kubelet", "DpuResourceName", DpuResourceName)
 
+	dp.registrationMutex.Lock()
+	dp.registration = &RegistrationInfo{
+		KubeletSocket: kubeletSocket,
+		PluginSocket:  dp.pluginEndpoint,
+		Endpoint:      request.Endpoint,
+	}
+	dp.registrationMutex.Unlock()
 	return nil
 }
 
@@ -298,19 +951,69 @@ func (dp *dpServer) connectWithRetry(endpoint string) (*grpc.ClientConn, error)
 
 func (dp *dpServer) Stop() error {
 	dp.log.Info("Stopping Device Plugin...")
+	dp.shutdownMutex.Lock()
+	dp.shuttingDown = true
+	dp.shutdownMutex.Unlock()
+	dp.stopOnce.Do(func() { close(dp.stopCh) })
+	// Report not serving for good right away, the requests in flight may
+	// take a while to finish.
+	dp.healthServer.Shutdown()
+	dp.inFlight.Wait()
+
 	if dp.grpcServer == nil {
 		return nil
 	}
+	defer dp.dumpDiagnostics("stop")
 
-	dp.grpcServer.Stop()
+	dp.introspection.ShutdownAndWait()
+	dp.stopGrpcServer()
 	dp.startedWg.Wait()
+	dp.kubeletWatchWg.Wait()
+	dp.podResourcesWg.Wait()
+	dp.resyncWg.Wait()
 	dp.grpcServer = nil
 
 	return dp.cleanup()
 }
 
+// stopContext returns a context that is cancelled with parent, or once the
+// Device Plugin stops.
+func (dp *dpServer) stopContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-dp.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// stopGrpcServer lets the requests being served finish, but forcibly stops
+// the server if they take longer than gracefulStopTimeout.
+func (dp *dpServer) stopGrpcServer() {
+	stopped := make(chan struct{})
+	go func() {
+		dp.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(dp.gracefulStopTimeout):
+		dp.log.Info("Device Plugin server did not stop gracefully in time, stopping it", "timeout", dp.gracefulStopTimeout)
+		dp.grpcServer.Stop()
+		<-stopped
+	}
+}
+
+// cleanup removes the plugin socket, unless another process is still serving
+// on it.
 func (dp *dpServer) cleanup() error {
-	pluginEndpoint := dp.pathManager.PluginEndpoint()
+	pluginEndpoint := dp.pluginEndpoint
+	if err := checkSocketNotLive(pluginEndpoint); err != nil {
+		return err
+	}
 	if err := os.Remove(pluginEndpoint); err != nil && !os.IsNotExist(err) {
 		return err
 	}
@@ -319,35 +1022,395 @@ func (dp *dpServer) cleanup() error {
 }
 
 func (dp *dpServer) PreStartContainer(ctx context.Context, psRqt *pluginapi.PreStartContainerRequest) (*pluginapi.PreStartContainerResponse, error) {
+	done, err := dp.beginRequest("PreStartContainer")
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// A starting container is alive, renew the lease of its devices.
+	dp.allocations.refresh(psRqt.DevicesIDs, dp.clock.Now())
+
+	// The binding may have changed since the allocation, check it again
+	// right before the container starts.
+	if err := dp.checkDriverBinding(psRqt.DevicesIDs); err != nil {
+		dp.log.Error(err, "Rejecting container start")
+		return nil, err
+	}
+	if err := dp.resetDevices(ctx, psRqt.DevicesIDs); err != nil {
+		dp.log.Error(err, "Rejecting container start")
+		return nil, err
+	}
+	dp.markWarm(psRqt.DevicesIDs...)
 	return &pluginapi.PreStartContainerResponse{}, nil
 }
 
 func (dp *dpServer) GetDevicePluginOptions(ctx context.Context, empty *pluginapi.Empty) (*pluginapi.DevicePluginOptions, error) {
+	dp.markKubeletContact()
 	return &pluginapi.DevicePluginOptions{
-		PreStartRequired: false,
+		PreStartRequired:                dp.expectedDriver != "" || dp.allocationTTL > 0 || dp.resetOnStart,
+		GetPreferredAllocationAvailable: dp.preferredAllocationAvailable(),
 	}, nil
 }
 
+// WithAllocateLatencyThreshold reports Allocate calls taking longer than threshold.
+func WithAllocateLatencyThreshold(threshold time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.allocateLatencyThreshold = threshold
+	}
+}
+
+// WithHealthSource adds a source of device health, e.g. a reachability probe,
+// that must consider a device healthy for it to be advertised as such.
+func WithHealthSource(source HealthSource) func(*dpServer) {
+	return func(d *dpServer) {
+		d.healthSources = append(d.healthSources, source)
+	}
+}
+
+// WithHealthWorkers sets how many devices are checked concurrently by the
+// health sources. The default checks the devices one after the other.
+func WithHealthWorkers(workers int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.healthWorkers = workers
+	}
+}
+
+// WithRecoveryHysteresis sets how long a device that recovered must stay
+// healthy before it is advertised as healthy again. Failures are always
+// advertised immediately.
+func WithRecoveryHysteresis(hysteresis time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.recoveryHysteresis = hysteresis
+	}
+}
+
+// WithAllocationTTL flags allocations that weren't refreshed within ttl as
+// expired, and releases them if reclaim is set. Leases are renewed when the
+// container starts and on every PodResources reconcile, so ttl must be longer
+// than the reconcile interval.
+func WithAllocationTTL(ttl time.Duration, reclaim bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.allocationTTL = ttl
+		d.reclaimExpired = reclaim
+	}
+}
+
+// WithStaleAllocationTracking enables or disables flagging allocations whose
+// device disappeared.
+func WithStaleAllocationTracking(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.trackStaleAllocations = enabled
+	}
+}
+
+// WithMaxDevices caps the number of advertised devices. The devices with the
+// lowest IDs are kept.
+func WithMaxDevices(maxDevices int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.maxDevices = maxDevices
+	}
+}
+
+// WithReservedDevices keeps the devices with the given IDs for the host
+// instead of advertising them.
+func WithReservedDevices(ids ...string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.reservedIDs = ids
+	}
+}
+
+// WithReservedPercentage keeps percent of the devices left after the fixed
+// reservation for the host, rounded down. It is clamped to [0, 100].
+func WithReservedPercentage(percent int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.reservedPercent = min(max(percent, 0), 100)
+	}
+}
+
+// WithRequiredCapabilities only advertises the devices for which the vendor
+// plugin reports all of capabilities.
+func WithRequiredCapabilities(capabilities ...string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.requiredCapabilities = capabilities
+	}
+}
+
+// WithSelfTest enables or disables the loopback self-test run before the
+// Device Plugin starts listening.
+func WithSelfTest(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.selfTestEnabled = enabled
+	}
+}
+
+// WithLogSampling only logs 1 in every occurrences of the high-frequency
+// informational events, such as sending devices to Kubelet or allocating.
+// Errors are always logged.
+func WithLogSampling(every int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.logSampler = newLogSampler(every)
+	}
+}
+
+// WithLogLevel only logs messages up to the given verbosity, e.g. 0 to drop
+// the per-device V(1) messages while the rest of the daemon logs them.
+func WithLogLevel(level int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.log = utils.LimitVerbosity(d.log, level)
+	}
+}
+
+// WithDeviceSortOrder sets the order in which devices are advertised.
+func WithDeviceSortOrder(order DeviceSortOrder) func(*dpServer) {
+	return func(d *dpServer) {
+		d.sortOrder = order
+	}
+}
+
+// WithRegisterVerification sets how long to wait for Kubelet to contact the
+// Device Plugin after registering, and how many times to register before
+// giving up. A zero timeout disables the verification.
+func WithRegisterVerification(timeout time.Duration, attempts int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.registerVerifyTimeout = timeout
+		d.registerAttempts = attempts
+	}
+}
+
+// WithRegisterRetry sets how long registrations Kubelet fails transiently,
+// e.g. while it restarts, are retried. Zero doesn't retry.
+func WithRegisterRetry(timeout time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.registerRetryTimeout = timeout
+	}
+}
+
+// WithRegisterThrottle spaces successful registrations with Kubelet at
+// least minInterval apart, and makes at most maxAttempts attempts per window.
+func WithRegisterThrottle(minInterval, window time.Duration, maxAttempts int) func(*dpServer) {
+	return func(d *dpServer) {
+		d.registerThrottle = newRegistrationThrottle(minInterval, window, maxAttempts)
+	}
+}
+
+// WithDiagnosticsPath sets where the state of the Device Plugin is dumped
+// when it stops or panics. An empty path disables the dump.
+func WithDiagnosticsPath(path string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.diagnosticsPath = path
+	}
+}
+
+// WithCoalesceWindow sets how long to wait for more changes before sending
+// triggered updates to Kubelet. Zero sends every update right away.
+func WithCoalesceWindow(window time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.coalesceWindow = window
+	}
+}
+
+// WithMinVendorVersion keeps the Device Plugin from registering with Kubelet
+// until the vendor plugin is at least version minVersion, e.g. "1.2.0".
+func WithMinVendorVersion(minVersion string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.minVendorVersion = minVersion
+	}
+}
+
+// WithAllocateValidator consults validator before accepting an allocation.
+func WithAllocateValidator(validator AllocateValidator) func(*dpServer) {
+	return func(d *dpServer) {
+		d.allocateValidator = validator
+	}
+}
+
+// WithDeviceSelectors prefers the devices matching the DeviceSelectorAnnotation
+// of the pods listed by lister.
+func WithDeviceSelectors(lister PodLister) func(*dpServer) {
+	return func(d *dpServer) {
+		d.podLister = lister
+	}
+}
+
+// WithExpectedDriver rejects allocating devices that aren't bound to driver,
+// e.g. "vfio-pci".
+func WithExpectedDriver(driver string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.expectedDriver = driver
+	}
+}
+
+// WithUnavailableWhileEmpty makes Allocate fail with the retryable
+// Unavailable status instead of rejecting the devices while no devices are
+// known, e.g. in the window before the first devices are sent.
+func WithUnavailableWhileEmpty(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.unavailableWhileEmpty = enabled
+	}
+}
+
+// WithZeroDevicesEnv sets the devices environment variables to value, e.g. ""
+// or "none", for containers allocated no devices. By default they are only
+// set when there are devices.
+func WithZeroDevicesEnv(value string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.zeroDevicesEnv = &value
+	}
+}
+
+// WithNumaEnv exposes the NUMA node(s) of the allocated devices to the
+// container in the NF_DEV_NUMA environment variable.
+func WithNumaEnv(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.numaEnv = enabled
+	}
+}
+
+func WithClock(clock clock.Clock) func(*dpServer) {
+	return func(d *dpServer) {
+		d.clock = clock
+	}
+}
+
+// WithPollInterval sets how often ListAndWatch gets the devices and checks
+// their health. Intervals below the minimum poll interval are raised to it.
+func WithPollInterval(interval time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.pollInterval = interval
+	}
+}
+
+// WithMinPollInterval sets the floor of the poll interval, one second by
+// default.
+func WithMinPollInterval(floor time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.minPollInterval = floor
+	}
+}
+
+// clampPollInterval raises a poll interval below the floor to the floor.
+func (dp *dpServer) clampPollInterval() {
+	if dp.pollInterval >= dp.minPollInterval {
+		return
+	}
+	dp.log.Info("Poll interval is below the minimum, using the minimum instead", "configured", dp.pollInterval, "minimum", dp.minPollInterval)
+	dp.pollInterval = dp.minPollInterval
+}
+
+// WithMaxReconcileBackoff caps how long ListAndWatch waits between retries
+// when getting the devices keeps failing.
+func WithMaxReconcileBackoff(max time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.maxReconcileBackoff = max
+	}
+}
+
 func WithPathManager(pathManager utils.PathManager) func(*dpServer) {
 	return func(d *dpServer) {
 		d.pathManager = pathManager
 	}
 }
 
+// WithResourceName sets the resource the devices are advertised as, and
+// the socket Kubelet reaches the Device Plugin on.
+func WithResourceName(resourceName string, pluginEndpoint string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.resourceName = resourceName
+		d.pluginEndpoint = pluginEndpoint
+		d.log = d.log.WithValues("resource", resourceName)
+	}
+}
+
+func WithDeviceHandler(deviceHandler dh.DeviceHandler) func(*dpServer) {
+	return func(d *dpServer) {
+		d.deviceHandler = deviceHandler
+	}
+}
+
+// WithIntrospection enables or disables the introspection socket.
+func WithIntrospection(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.introspectionEnabled = enabled
+	}
+}
+
+// WithIntrospectionPath serves introspection on the given unix socket
+// instead of the one of the path manager.
+func WithIntrospectionPath(path string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.introspectionPath = path
+	}
+}
+
 func NewDevicePlugin(vsp plugin.VendorPlugin, dpuMode bool, pm utils.PathManager, opts ...func(*dpServer)) *dpServer {
-	dh := dpudevicehandler.NewDpuDeviceHandler(vsp, dpudevicehandler.WithDpuMode(dpuMode), dpudevicehandler.WithPathManager(pm))
+	deviceHandler := dpudevicehandler.NewDpuDeviceHandler(vsp, dpudevicehandler.WithDpuMode(dpuMode), dpudevicehandler.WithPathManager(pm))
 	dp := &dpServer{
+		resourceName:  DpuResourceName,
 		devices:       make(map[string]pluginapi.Device),
-		grpcServer:    grpc.NewServer(),
 		log:           ctrl.Log.WithName("DevicePlugin"),
 		pathManager:   pm,
-		deviceHandler: dh,
+		deviceHandler: deviceHandler,
 		vsp:           vsp,
+
+		pollInterval:        defaultPollInterval,
+		minPollInterval:     defaultMinPollInterval,
+		maxReconcileBackoff: defaultMaxReconcileBackoff,
+		updateCh:            make(chan struct{}, 1),
+		stopCh:              make(chan struct{}),
+		strategy:            StrategyPacked,
+		gracefulStopTimeout: defaultGracefulStopTimeout,
+		coalesceWindow:      defaultCoalesceWindow,
+		drained:             make(map[string]bool),
+		allocations:         newAllocationStore(),
+		responses:           newResponseCache(),
+		podResources:        &podResourcesReconcile{interval: defaultPodResourcesInterval},
+		resyncInterval:      defaultResyncInterval,
+		healthCacheTTL:      -1,
+
+		introspectionEnabled: true,
+		introspectionPath:    pm.DevicePluginIntrospectionPath(),
+
+		clock:                      clock.RealClock{},
+		allocateLatencyThreshold:   defaultAllocateLatencyThreshold,
+		trackStaleAllocations:      true,
+		dropped:                    make(map[string]int),
+		selfTestEnabled:            true,
+		logSampler:                 newLogSampler(1),
+		sortOrder:                  SortByID,
+		devicesEnvNames:            defaultDevicesEnvNames,
+		cpuTopology:                newCPUTopology(),
+		kubeletContact:             make(chan struct{}, 1),
+		serveErrors:                make(chan error, 1),
+		registerVerifyTimeout:      defaultRegisterVerifyTimeout,
+		registerSettleDelay:        defaultRegisterSettleDelay,
+		registerAttempts:           defaultRegisterAttempts,
+		registerRetryTimeout:       defaultRegisterRetryTimeout,
+		registerThrottle:           newRegistrationThrottle(defaultRegisterMinInterval, defaultRegisterWindow, defaultRegisterMaxAttempts),
+		deviceInfoFor:              resolveDeviceInfo,
+		driverName:                 dh.GetDriverName,
+		listen:                     net.Listen,
+		listenRetryDelay:           defaultListenRetryDelay,
+		allocateValidator:          allowAllValidator{},
+		vendorVersionRetryInterval: defaultPollInterval,
+		healthWorkers:              1,
+		recovering:                 make(map[string]time.Time),
+		diagnosticsPath:            pm.DevicePluginDiagnosticsPath(),
+		healthServer:               health.NewServer(),
 	}
+	dp.grpcServer = dp.newGrpcServer()
+	dp.introspection = newIntrospectionServer(dp)
+	dp.healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
 
 	for _, opt := range opts {
 		opt(dp)
 	}
+	dp.clampPollInterval()
+	dp.defaultHealthCacheTTL()
+
+	if dp.pluginEndpoint == "" {
+		dp.pluginEndpoint = dp.pathManager.PluginEndpoint()
+	}
 
 	return dp
 }
@@ -0,0 +1,449 @@
+package deviceplugin
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"k8s.io/utils/clock"
+)
+
+// newTestDevicePlugin disables the health cache, tests change the devices
+// and trigger an update right away.
+func newTestDevicePlugin(ids ...string) *dpServer {
+	dp := NewDevicePlugin(nil, true, *utils.NewPathManager(GinkgoT().TempDir()), WithHealthCacheTTL(0))
+	devices := make(dh.DeviceList)
+	for _, id := range ids {
+		devices[id] = pluginapi.Device{ID: id, Health: pluginapi.Healthy}
+	}
+	dp.setDeviceCache(&devices)
+	return dp
+}
+
+// steppingClock advances by step every time it is read, simulating time
+// passing between two reads. Timers are real.
+type steppingClock struct {
+	clock.RealClock
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func (c *steppingClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	Expect(c.Write(m)).To(Succeed())
+	return m.GetCounter().GetValue()
+}
+
+// blockingValidator holds allocations until release is closed.
+type blockingValidator struct {
+	release chan struct{}
+}
+
+func (v blockingValidator) ValidateAllocate(ctx context.Context, review AllocationReview) error {
+	<-v.release
+	return nil
+}
+
+func allocateRequest(containers ...[]string) *pluginapi.AllocateRequest {
+	rqt := &pluginapi.AllocateRequest{}
+	for _, ids := range containers {
+		rqt.ContainerRequests = append(rqt.ContainerRequests, &pluginapi.ContainerAllocateRequest{DevicesIDs: ids})
+	}
+	return rqt
+}
+
+var _ = Describe("Allocate", func() {
+	It("should allocate distinct devices to each container", func() {
+		dp := newTestDevicePlugin("dev0", "dev1")
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses).To(HaveLen(2))
+	})
+
+	It("should reject a device requested by two containers", func() {
+		dp := newTestDevicePlugin("dev0", "dev1")
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}, []string{"dev1"}))
+		Expect(err).To(MatchError(ContainSubstring("device dev1 requested by containers 0 and 1")))
+	})
+
+	DescribeTable("should fail with a status identifying the device",
+		func(request *pluginapi.AllocateRequest, code codes.Code, message string) {
+			dp := newTestDevicePlugin("dev0", "dev1", "dev2")
+			dev := dp.devices["dev1"]
+			dev.Health = pluginapi.Unhealthy
+			dp.devices["dev1"] = dev
+			_, err := dp.DrainDevice("dev2")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = dp.Allocate(context.Background(), request)
+			Expect(status.Code(err)).To(Equal(code))
+			Expect(status.Convert(err).Message()).To(Equal(message))
+		},
+		Entry("for a device that was never advertised", allocateRequest([]string{"dev0", "unknown"}),
+			codes.NotFound, "invalid allocation request with non-existing device: unknown was never advertised"),
+		Entry("for an unhealthy device", allocateRequest([]string{"dev0", "dev1"}),
+			codes.FailedPrecondition, "invalid allocation request with unhealthy device: dev1"),
+		Entry("for a drained device", allocateRequest([]string{"dev2"}),
+			codes.FailedPrecondition, "invalid allocation request with drained device: dev2"),
+		Entry("for a device requested by two containers", allocateRequest([]string{"dev0"}, []string{"dev0"}),
+			codes.InvalidArgument, "invalid allocation request with device dev0 requested by containers 0 and 1"),
+	)
+
+	Context("latency", func() {
+		var (
+			dp   *dpServer
+			logs []string
+		)
+
+		BeforeEach(func() {
+			dp = newTestDevicePlugin("dev0")
+			logs = nil
+			dp.log = funcr.New(func(prefix, args string) {
+				logs = append(logs, args)
+			}, funcr.Options{})
+			WithAllocateLatencyThreshold(time.Second)(dp)
+		})
+
+		It("should warn when Allocate exceeds the latency threshold", func() {
+			before := counterValue(allocateSlowTotal.WithLabelValues(dp.resourceName))
+			WithClock(&steppingClock{step: 2 * time.Second})(dp)
+
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counterValue(allocateSlowTotal.WithLabelValues(dp.resourceName))).To(Equal(before + 1))
+			Expect(strings.Join(logs, "\n")).To(And(ContainSubstring("exceeded latency threshold"), ContainSubstring(`"devices"=["dev0"]`)))
+		})
+
+		It("should not warn when Allocate is fast enough", func() {
+			before := counterValue(allocateSlowTotal.WithLabelValues(dp.resourceName))
+			WithClock(&steppingClock{step: 100 * time.Millisecond})(dp)
+
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counterValue(allocateSlowTotal.WithLabelValues(dp.resourceName))).To(Equal(before))
+			Expect(strings.Join(logs, "\n")).NotTo(ContainSubstring("exceeded latency threshold"))
+		})
+	})
+
+	Context("in-flight allocations", func() {
+		It("should track concurrent Allocate calls and return to zero once they complete", func() {
+			dp := newTestDevicePlugin("dev0", "dev1", "dev2", "dev3")
+			dp.resourceName = "openshift.io/in-flight"
+			release := make(chan struct{})
+			WithAllocateValidator(blockingValidator{release})(dp)
+			inFlight := allocateInFlight.WithLabelValues(dp.resourceName)
+			latencies := allocateDuration.WithLabelValues(dp.resourceName).(prometheus.Histogram)
+
+			var wg sync.WaitGroup
+			for _, id := range []string{"dev0", "dev1", "dev2", "dev3", "unknown"} {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					_, _ = dp.Allocate(context.Background(), allocateRequest([]string{id}))
+				}()
+			}
+			Eventually(func() float64 { return gaugeValue(inFlight) }).Should(Equal(4.0))
+			close(release)
+			wg.Wait()
+
+			Expect(gaugeValue(inFlight)).To(BeZero())
+			m := &dto.Metric{}
+			Expect(latencies.Write(m)).To(Succeed())
+			Expect(m.GetHistogram().GetSampleCount()).To(BeNumerically("==", 5))
+		})
+	})
+
+	Context("allocation counters", func() {
+		It("should count allocations and failures by reason", func() {
+			dp := newTestDevicePlugin("dev0", "dev1")
+			dp.resourceName = "openshift.io/counted"
+			dev := dp.devices["dev1"]
+			dev.Health = pluginapi.Unhealthy
+			dp.devices["dev1"] = dev
+
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+			Expect(err).To(HaveOccurred())
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"unknown"}))
+			Expect(err).To(HaveOccurred())
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev0"}))
+			Expect(err).To(HaveOccurred())
+
+			Expect(counterValue(allocationsTotal.WithLabelValues(dp.resourceName))).To(Equal(1.0))
+			for reason, count := range map[string]float64{
+				allocateFailureUnhealthy:     1,
+				allocateFailureNotAdvertised: 1,
+				allocateFailureShared:        1,
+				allocateFailureValidator:     0,
+			} {
+				Expect(counterValue(allocationFailuresTotal.WithLabelValues(dp.resourceName, reason))).To(Equal(count), reason)
+			}
+		})
+
+		It("should report the advertised health and the vendor plugin connection", func() {
+			dp := newTestDevicePlugin()
+			dp.resourceName = "openshift.io/reported"
+			devices := dh.DeviceList{
+				"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+				"dev1": {ID: "dev1", Health: pluginapi.Unhealthy},
+				"dev2": {ID: "dev2", Health: pluginapi.Healthy},
+			}
+			dp.reportAdvertisedHealth(&devices)
+			Expect(gaugeValue(advertisedDevicesByHealth.WithLabelValues(dp.resourceName, pluginapi.Healthy))).To(Equal(2.0))
+			Expect(gaugeValue(advertisedDevicesByHealth.WithLabelValues(dp.resourceName, pluginapi.Unhealthy))).To(Equal(1.0))
+
+			connected := vendorPluginConnected.WithLabelValues(dp.resourceName)
+			dp.setVendorConnected(true)
+			Expect(gaugeValue(connected)).To(Equal(1.0))
+			dp.setVendorConnected(false)
+			Expect(gaugeValue(connected)).To(BeZero())
+		})
+	})
+
+	Context("NUMA env", func() {
+		var dp *dpServer
+
+		BeforeEach(func() {
+			dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+			for id, node := range map[string]int64{"dev0": 0, "dev1": 1, "dev2": 1} {
+				dev := dp.devices[id]
+				dev.Topology = &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+				dp.devices[id] = dev
+			}
+		})
+
+		It("should not be set by default", func() {
+			resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).NotTo(HaveKey(numaEnvName))
+		})
+
+		It("should match the NUMA nodes of each container's devices", func() {
+			WithNumaEnv(true)(dp)
+			resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev1", "dev0"}, []string{"dev2"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(numaEnvName, "0,1"))
+			Expect(resp.ContainerResponses[1].Envs).To(HaveKeyWithValue(numaEnvName, "1"))
+		})
+	})
+
+	It("should pass the devices in NF_DEV and NF-DEV by default", func() {
+		dp := newTestDevicePlugin("dev0", "dev1")
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue("NF_DEV", "dev0,dev1"))
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue("NF-DEV", "dev0,dev1"))
+	})
+
+	It("should pass the devices in the configured variables only", func() {
+		dp := newTestDevicePlugin("dev0")
+		WithDevicesEnvNames("DPU_DEVICES", "PCIDEVICE_OPENSHIFT_IO_DPU")(dp)
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue("DPU_DEVICES", "dev0"))
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue("PCIDEVICE_OPENSHIFT_IO_DPU", "dev0"))
+		Expect(resp.ContainerResponses[0].Envs).NotTo(HaveKey("NF_DEV"))
+		Expect(resp.ContainerResponses[0].Envs).NotTo(HaveKey("NF-DEV"))
+	})
+
+	DescribeTable("validating the devices environment variable names",
+		func(names []string, valid bool) {
+			err := validateEnvNames(names)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("should accept the defaults", defaultDevicesEnvNames, true),
+		Entry("should reject no names", []string{}, false),
+		Entry("should reject an empty name", []string{""}, false),
+		Entry("should reject a name starting with a digit", []string{"1DEV"}, false),
+		Entry("should reject a name with an equal sign", []string{"DEV=X"}, false),
+		Entry("should reject a name given twice", []string{"DEV", "DEV"}, false),
+	)
+
+	It("should pass each container only its own devices", func() {
+		dp := newTestDevicePlugin("dev0", "dev1", "dev2", "dev3")
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev2", "dev1"}, []string{"dev3"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses).To(HaveLen(3))
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, "dev0"))
+		Expect(resp.ContainerResponses[1].Envs).To(HaveKeyWithValue(devicesEnvName, "dev2,dev1"))
+		Expect(resp.ContainerResponses[2].Envs).To(HaveKeyWithValue(devicesEnvName, "dev3"))
+	})
+
+	Context("containers without devices", func() {
+		var dp *dpServer
+
+		BeforeEach(func() {
+			dp = newTestDevicePlugin("dev0")
+		})
+
+		It("should not set the devices env by default", func() {
+			resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, "dev0"))
+			Expect(resp.ContainerResponses[1].Envs).NotTo(HaveKey(devicesEnvName))
+		})
+
+		It("should set the devices env to an empty string", func() {
+			WithZeroDevicesEnv("")(dp)
+			resp, err := dp.Allocate(context.Background(), allocateRequest([]string{}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, ""))
+		})
+
+		It("should set the devices env to a sentinel", func() {
+			WithZeroDevicesEnv("none")(dp)
+			resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, "dev0"))
+			Expect(resp.ContainerResponses[1].Envs).To(HaveKeyWithValue(devicesEnvName, "none"))
+		})
+	})
+})
+
+// hangingDeviceHandler is a vendor plugin that never answers GetDevices, it
+// only returns once the context of the call is done.
+type hangingDeviceHandler struct {
+	once   sync.Once
+	called chan struct{}
+}
+
+func (h *hangingDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (h *hangingDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	h.once.Do(func() { close(h.called) })
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var _ = Describe("Shutdown", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0")
+	})
+
+	It("should reject new requests once shutdown begins", func() {
+		Expect(dp.Stop()).To(Succeed())
+
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		Expect(err).To(MatchError(ContainSubstring("shutting down")))
+
+		_, err = dp.PreStartContainer(context.Background(), &pluginapi.PreStartContainerRequest{DevicesIDs: []string{"dev0"}})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	})
+
+	It("should cancel the vendor plugin call ListAndWatch waits for", func() {
+		handler := &hangingDeviceHandler{called: make(chan struct{})}
+		WithDeviceHandler(handler)(dp)
+		done := make(chan error, 1)
+		go func() {
+			done <- dp.ListAndWatch(&pluginapi.Empty{}, &lockedListAndWatchServer{})
+		}()
+		Eventually(handler.called).Should(BeClosed())
+
+		Expect(dp.Stop()).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should wait for requests in flight before stopping", func() {
+		done, err := dp.beginRequest("Allocate")
+		Expect(err).NotTo(HaveOccurred())
+
+		stopped := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(dp.Stop()).To(Succeed())
+			close(stopped)
+		}()
+		Consistently(stopped, 100*time.Millisecond).ShouldNot(BeClosed())
+
+		done()
+		Eventually(stopped).Should(BeClosed())
+	})
+
+	Context("while serving", func() {
+		var conn *grpc.ClientConn
+
+		BeforeEach(func() {
+			root, err := os.MkdirTemp("", "dp")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, root)
+			WithDeviceHandler(&changingDeviceHandler{ids: []string{"dev0"}})(dp)
+			dp.pluginEndpoint = filepath.Join(root, "dp.sock")
+
+			lis, err := net.Listen("unix", dp.pluginEndpoint)
+			Expect(err).NotTo(HaveOccurred())
+			pluginapi.RegisterDevicePluginServer(dp.grpcServer, dp)
+			healthpb.RegisterHealthServer(dp.grpcServer, dp.healthServer)
+			go dp.grpcServer.Serve(lis)
+
+			conn, err = grpc.NewClient("unix:"+dp.pluginEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(conn.Close)
+		})
+
+		It("should end the ListAndWatch streams and stop gracefully", func() {
+			stream, err := pluginapi.NewDevicePluginClient(conn).ListAndWatch(context.Background(), &pluginapi.Empty{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = stream.Recv()
+			Expect(err).NotTo(HaveOccurred())
+
+			start := time.Now()
+			Expect(dp.Stop()).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", dp.gracefulStopTimeout))
+			_, err = stream.Recv()
+			Expect(err).To(Equal(io.EOF))
+			Expect(dp.pluginEndpoint).NotTo(BeAnExistingFile())
+		})
+
+		It("should stop forcibly when requests don't finish in time", func() {
+			dp.gracefulStopTimeout = 200 * time.Millisecond
+			stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = stream.Recv()
+			Expect(err).NotTo(HaveOccurred())
+
+			start := time.Now()
+			Expect(dp.Stop()).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", dp.gracefulStopTimeout))
+			Expect(dp.pluginEndpoint).NotTo(BeAnExistingFile())
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultDevicesEnvNames pass the allocated devices in NF_DEV, which shells
+// can read, and in NF-DEV for the consumers of the original name.
+var defaultDevicesEnvNames = []string{"NF_DEV", devicesEnvName}
+
+// validateEnvNames checks that Kubernetes accepts the names as environment
+// variables of a container.
+func validateEnvNames(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("at least one environment variable is needed to pass the allocated devices")
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if errs := validation.IsEnvVarName(name); len(errs) > 0 {
+			return fmt.Errorf("invalid devices environment variable name %q: %s", name, strings.Join(errs, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("devices environment variable %q is given twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// setDevicesEnv sets every devices environment variable to value.
+func (dp *dpServer) setDevicesEnv(envs map[string]string, value string) {
+	for _, name := range dp.devicesEnvNames {
+		envs[name] = value
+	}
+}
+
+// WithDevicesEnvNames passes the allocated devices in the given environment
+// variables instead of NF_DEV and NF-DEV, e.g. DPU_DEVICES for a vendor
+// runtime, with several names for compatibility. The names are validated by
+// Listen.
+func WithDevicesEnvNames(names ...string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.devicesEnvNames = names
+	}
+}
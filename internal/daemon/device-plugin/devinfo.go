@@ -0,0 +1,113 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+)
+
+// Well-known keys of the attributes vendor plugins report, so that CNI
+// selectors can match on them. Vendor plugins may report other keys as well.
+const (
+	// AttributeLinkSpeed is the link speed of the device in Mb/s, e.g. "25000".
+	AttributeLinkSpeed = "linkSpeed"
+	// AttributePFIndex is the index of the PF the device belongs to, e.g. "0".
+	AttributePFIndex = "pfIndex"
+	// AttributeVFIndex is the index of the VF on its PF, e.g. "3".
+	AttributeVFIndex = "vfIndex"
+	// AttributePCIAddress is the PCI address of the device, e.g.
+	// "0000:3b:00.2", for devices not identified by it.
+	AttributePCIAddress = "pciAddress"
+	// AttributePool is the pool the device belongs to, see NewManager.
+	AttributePool = "pool"
+)
+
+// DeviceInfo describes an allocated device for the CNI plugin, which can look
+// it up by device ID instead of parsing the environment of the container.
+type DeviceInfo struct {
+	ID         string `json:"id"`
+	PCIAddress string `json:"pciAddress,omitempty"`
+	PFName     string `json:"pfName,omitempty"`
+	VFIndex    *int   `json:"vfIndex,omitempty"`
+	Interface  string `json:"interface,omitempty"`
+	// Attributes are the attributes the vendor plugin reported for the
+	// device, see AttributeLinkSpeed for the well-known keys.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// PhysicalID is the shared device a logical device stands for, the
+	// other fields describe the shared device.
+	PhysicalID string `json:"physicalID,omitempty"`
+}
+
+// resolveDeviceInfo collects what is known about a device. On the host,
+// device IDs are VF PCI addresses, while on the DPU they are interface names.
+// Fields that can't be resolved are left empty.
+func resolveDeviceInfo(id string) DeviceInfo {
+	if !sriovutils.IsValidPCIAddress(id) {
+		return DeviceInfo{ID: id, Interface: id}
+	}
+
+	info := DeviceInfo{ID: id, PCIAddress: id}
+	if pfName, err := sriovutils.GetPfName(id); err == nil {
+		info.PFName = pfName
+		if vf, err := sriovutils.GetVfid(id, pfName); err == nil {
+			info.VFIndex = &vf
+		}
+	}
+	if iface, err := sriovutils.GetVFLinkName(id); err == nil {
+		info.Interface = iface
+	}
+	return info
+}
+
+func deviceInfoPath(pm utils.PathManager, id string) string {
+	return filepath.Join(pm.DevicePluginDeviceInfoDir(), id+".json")
+}
+
+// ReadDeviceInfo returns the information the Device Plugin recorded when the
+// device was allocated.
+func ReadDeviceInfo(pm utils.PathManager, id string) (*DeviceInfo, error) {
+	data, err := os.ReadFile(deviceInfoPath(pm, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device info of %s: %v", id, err)
+	}
+	var info DeviceInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse device info of %s: %v", id, err)
+	}
+	return &info, nil
+}
+
+// allocatedDeviceInfo returns what is known about an allocated device,
+// including the attributes the vendor plugin reported for it.
+func (dp *dpServer) allocatedDeviceInfo(id string) DeviceInfo {
+	physical := dp.physicalDevice(id)
+	info := dp.deviceInfoFor(physical)
+	if physical != id {
+		info.ID = id
+		info.PhysicalID = physical
+	}
+	if attributes, ok := dp.deviceHandler.(dh.AttributeHandler); ok {
+		info.Attributes = attributes.GetDeviceAttributes(id)
+	}
+	return info
+}
+
+// writeDeviceInfo atomically writes the info file of an allocated device.
+func (dp *dpServer) writeDeviceInfo(id string) error {
+	return writeFileAtomic(deviceInfoPath(dp.pathManager, id), dp.allocatedDeviceInfo(id))
+}
+
+// releaseAllocation forgets about an allocated device and removes its info file.
+func (dp *dpServer) releaseAllocation(id string) {
+	dp.allocations.release(id)
+	dp.writeCheckpoint()
+	dp.markWarm(id)
+	if err := os.Remove(deviceInfoPath(dp.pathManager, id)); err != nil && !os.IsNotExist(err) {
+		dp.log.Error(err, "Failed to remove device info", "id", id)
+	}
+}
@@ -0,0 +1,64 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device info", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("0000:3b:00.2", "eth0")
+		dp.deviceInfoFor = func(id string) DeviceInfo {
+			if id == "eth0" {
+				return resolveDeviceInfo(id)
+			}
+			vf := 1
+			return DeviceInfo{ID: id, PCIAddress: id, PFName: "ens1f0", VFIndex: &vf, Interface: "ens1f0v1"}
+		}
+	})
+
+	It("should write the info file of every device on allocate", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.2"}, []string{"eth0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := ReadDeviceInfo(dp.pathManager, "0000:3b:00.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.PFName).To(Equal("ens1f0"))
+		Expect(*info.VFIndex).To(Equal(1))
+		Expect(info.Interface).To(Equal("ens1f0v1"))
+
+		info, err = ReadDeviceInfo(dp.pathManager, "eth0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*info).To(Equal(DeviceInfo{ID: "eth0", Interface: "eth0"}))
+	})
+
+	It("should record the attributes the vendor plugin reported", func() {
+		WithDeviceHandler(attributeDeviceHandler{
+			"0000:3b:00.2": {AttributeLinkSpeed: "25000", AttributePFIndex: "0"},
+			"eth0":         {},
+		})(dp)
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.2", "eth0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := ReadDeviceInfo(dp.pathManager, "0000:3b:00.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Attributes).To(Equal(map[string]string{"linkSpeed": "25000", "pfIndex": "0"}))
+
+		info, err = ReadDeviceInfo(dp.pathManager, "eth0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Attributes).To(BeEmpty())
+	})
+
+	It("should remove the info file on release", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"eth0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		dp.releaseAllocation("eth0")
+		_, err = ReadDeviceInfo(dp.pathManager, "eth0")
+		Expect(err).To(HaveOccurred())
+	})
+})
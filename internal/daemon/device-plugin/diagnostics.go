@@ -0,0 +1,123 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const maxRecordedErrors = 16
+
+// ErrorRecord is an error the Device Plugin ran into.
+type ErrorRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// Diagnostics is the state of the Device Plugin dumped when it exits, for
+// postmortem analysis.
+type Diagnostics struct {
+	Time        time.Time          `json:"time"`
+	Reason      string             `json:"reason"`
+	Info        Info               `json:"info"`
+	Devices     []pluginapi.Device `json:"devices"`
+	Allocations []Allocation       `json:"allocations"`
+	Drained     []string           `json:"drained"`
+	Maintenance MaintenanceStatus  `json:"maintenance"`
+	LastErrors  []ErrorRecord      `json:"lastErrors"`
+}
+
+// errorLog keeps the most recent errors.
+type errorLog struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+}
+
+func (l *errorLog) record(now time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, ErrorRecord{Time: now, Error: err.Error()})
+	if len(l.records) > maxRecordedErrors {
+		l.records = l.records[len(l.records)-maxRecordedErrors:]
+	}
+}
+
+func (l *errorLog) list() []ErrorRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ErrorRecord(nil), l.records...)
+}
+
+func (dp *dpServer) recordError(err error) {
+	dp.errors.record(dp.clock.Now(), err)
+}
+
+func (dp *dpServer) diagnostics(reason string) Diagnostics {
+	cached := dp.cachedDevices()
+	devices := make([]pluginapi.Device, 0, len(cached))
+	for _, dev := range cached {
+		devices = append(devices, dev)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+
+	dp.drainMutex.RLock()
+	drained := make([]string, 0, len(dp.drained))
+	for id := range dp.drained {
+		drained = append(drained, id)
+	}
+	dp.drainMutex.RUnlock()
+	sort.Strings(drained)
+
+	return Diagnostics{
+		Time:        dp.clock.Now(),
+		Reason:      reason,
+		Info:        dp.GetInfo(),
+		Devices:     devices,
+		Allocations: dp.allocations.list(),
+		Drained:     drained,
+		Maintenance: dp.MaintenanceStatus(),
+		LastErrors:  dp.errors.list(),
+	}
+}
+
+// dumpDiagnostics writes the diagnostics file. It runs while exiting, so
+// failures are only logged.
+func (dp *dpServer) dumpDiagnostics(reason string) {
+	if dp.diagnosticsPath == "" {
+		return
+	}
+	if err := writeFileAtomic(dp.diagnosticsPath, dp.diagnostics(reason)); err != nil {
+		dp.log.Error(err, "Failed to write diagnostics", "path", dp.diagnosticsPath)
+		return
+	}
+	dp.log.Info("Wrote diagnostics", "path", dp.diagnosticsPath, "reason", reason)
+}
+
+// dumpDiagnosticsOnPanic must be deferred. It dumps the diagnostics when
+// panicking and then carries on panicking.
+func (dp *dpServer) dumpDiagnosticsOnPanic() {
+	if r := recover(); r != nil {
+		dp.dumpDiagnostics(fmt.Sprintf("panic: %v", r))
+		panic(r)
+	}
+}
+
+func writeFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,62 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diagnostics", func() {
+	var dp *dpServer
+
+	readDiagnostics := func() Diagnostics {
+		data, err := os.ReadFile(dp.diagnosticsPath)
+		Expect(err).NotTo(HaveOccurred())
+		var diagnostics Diagnostics
+		Expect(json.Unmarshal(data, &diagnostics)).To(Succeed())
+		return diagnostics
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+	})
+
+	It("should dump the state on Stop", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"unknown"}))
+		Expect(err).To(HaveOccurred())
+		_, err = dp.DrainDevice("dev1")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(dp.Stop()).To(Succeed())
+
+		diagnostics := readDiagnostics()
+		Expect(diagnostics.Reason).To(Equal("stop"))
+		Expect(diagnostics.Devices).To(HaveLen(2))
+		Expect(diagnostics.Allocations).To(HaveLen(1))
+		Expect(diagnostics.Allocations[0].DeviceID).To(Equal("dev0"))
+		Expect(diagnostics.Drained).To(Equal([]string{"dev1"}))
+		Expect(diagnostics.LastErrors).To(HaveLen(1))
+		Expect(diagnostics.LastErrors[0].Error).To(ContainSubstring("non-existing device: unknown"))
+	})
+
+	It("should dump the state when panicking", func() {
+		Expect(func() {
+			defer dp.dumpDiagnosticsOnPanic()
+			panic("boom")
+		}).To(PanicWith("boom"))
+		Expect(readDiagnostics().Reason).To(Equal("panic: boom"))
+	})
+
+	It("should not fail Stop when the dump can't be written", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "file"), nil, 0o644)).To(Succeed())
+		WithDiagnosticsPath(filepath.Join(dir, "file", "diagnostics.json"))(dp)
+		Expect(dp.Stop()).To(Succeed())
+	})
+})
@@ -0,0 +1,149 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"sort"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// DrainStatus reports the drain state of a single device. A drained device is
+// advertised as unhealthy so that Kubelet stops scheduling new pods onto it,
+// while containers already using it are left untouched. Once Allocated turns
+// false, the device is free for maintenance.
+type DrainStatus struct {
+	ID        string `json:"id"`
+	Drained   bool   `json:"drained"`
+	Allocated bool   `json:"allocated"`
+}
+
+// DrainDevice cordons a single device.
+func (dp *dpServer) DrainDevice(id string) (DrainStatus, error) {
+	if _, ok := dp.device(id); !ok {
+		return DrainStatus{}, fmt.Errorf("cannot drain non-existing device: %s", id)
+	}
+
+	dp.drainMutex.Lock()
+	dp.drained[id] = true
+	dp.drainMutex.Unlock()
+
+	dp.log.Info("Device drained", "id", id)
+	dp.triggerUpdate()
+	return dp.DrainStatus(id), nil
+}
+
+// UndrainDevice makes a previously drained device schedulable again.
+func (dp *dpServer) UndrainDevice(id string) (DrainStatus, error) {
+	dp.drainMutex.Lock()
+	_, ok := dp.drained[id]
+	delete(dp.drained, id)
+	dp.drainMutex.Unlock()
+
+	if !ok {
+		return DrainStatus{}, fmt.Errorf("device %s is not drained", id)
+	}
+
+	dp.log.Info("Device undrained", "id", id)
+	dp.triggerUpdate()
+	return dp.DrainStatus(id), nil
+}
+
+func (dp *dpServer) DrainStatus(id string) DrainStatus {
+	return DrainStatus{
+		ID:        id,
+		Drained:   dp.isDrained(id),
+		Allocated: dp.allocations.isAllocated(id),
+	}
+}
+
+func (dp *dpServer) isDrained(id string) bool {
+	dp.drainMutex.RLock()
+	defer dp.drainMutex.RUnlock()
+	return dp.drainAll || dp.drained[id]
+}
+
+// NodeDrainStatus reports whether all devices are drained, e.g. before the
+// DPU firmware is upgraded. Once Allocated is empty, no container uses the
+// devices anymore.
+type NodeDrainStatus struct {
+	Drained   bool     `json:"drained"`
+	Allocated []string `json:"allocated"`
+}
+
+// Drain cordons all devices: they are advertised as unhealthy so that
+// Kubelet doesn't schedule new pods using them, and Allocate rejects them,
+// while containers already using them keep running. The drain is applied
+// to what is advertised on every reconcile, after the health reported by the
+// vendor plugin and the health sources, so polling the health doesn't undo
+// it and devices added while drained are drained too. Draining again is a
+// no-op.
+func (dp *dpServer) Drain() NodeDrainStatus {
+	dp.drainMutex.Lock()
+	wasDrained := dp.drainAll
+	dp.drainAll = true
+	dp.drainMutex.Unlock()
+
+	if !wasDrained {
+		dp.log.Info("All devices drained")
+		dp.triggerUpdate()
+	}
+	return dp.NodeDrainStatus()
+}
+
+// Uncordon ends a Drain. Devices drained one by one with DrainDevice stay
+// drained.
+func (dp *dpServer) Uncordon() (NodeDrainStatus, error) {
+	dp.drainMutex.Lock()
+	wasDrained := dp.drainAll
+	dp.drainAll = false
+	dp.drainMutex.Unlock()
+
+	if !wasDrained {
+		return NodeDrainStatus{}, fmt.Errorf("devices are not drained")
+	}
+
+	dp.log.Info("All devices uncordoned")
+	dp.triggerUpdate()
+	return dp.NodeDrainStatus(), nil
+}
+
+func (dp *dpServer) NodeDrainStatus() NodeDrainStatus {
+	dp.drainMutex.RLock()
+	drained := dp.drainAll
+	dp.drainMutex.RUnlock()
+
+	allocated := []string{}
+	for id := range dp.cachedDevices() {
+		if dp.allocations.isAllocated(id) {
+			allocated = append(allocated, id)
+		}
+	}
+	sort.Strings(allocated)
+	return NodeDrainStatus{Drained: drained, Allocated: allocated}
+}
+
+// advertisedDevices returns the devices as they should be reported to Kubelet,
+// i.e. with the health frozen during a maintenance window and drained devices
+// marked as unhealthy.
+//
+// Kubelet allocates devices by the ID we advertise while Allocate looks them
+// up by their key in the device list, so the advertised ID is always the key.
+func (dp *dpServer) advertisedDevices(devices *dh.DeviceList) *dh.DeviceList {
+	advertised := make(dh.DeviceList, len(*devices))
+	for id, dev := range *devices {
+		if dev.ID != id {
+			dp.log.Error(fmt.Errorf("device ID %q doesn't match its key %q", dev.ID, id), "Advertising device under its key")
+			dev.ID = id
+		}
+		if health, ok := dp.frozenHealth(id); ok {
+			dev.Health = health
+		}
+		if dp.isDrained(id) {
+			dev.Health = pluginapi.Unhealthy
+		}
+		advertised[id] = dev
+	}
+	dp.recordSuppressed(*devices, advertised)
+	return &advertised
+}
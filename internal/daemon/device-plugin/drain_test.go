@@ -0,0 +1,120 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func advertisedCache(dp *dpServer) dh.DeviceList {
+	cached := dh.DeviceList(dp.devices)
+	return *dp.advertisedDevices(&cached)
+}
+
+var _ = Describe("Device drain", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+	})
+
+	It("should only make the drained device unschedulable", func() {
+		status, err := dp.DrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Drained).To(BeTrue())
+
+		advertised := advertisedCache(dp)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(advertised["dev1"].Health).To(Equal(pluginapi.Healthy))
+
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).To(MatchError(ContainSubstring("drained device: dev0")))
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dp.UndrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		advertised = advertisedCache(dp)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Healthy))
+	})
+
+	It("should report whether a drained device is still allocated", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := dp.DrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Allocated).To(BeTrue())
+
+		dp.releaseAllocation("dev0")
+		Expect(dp.DrainStatus("dev0").Allocated).To(BeFalse())
+	})
+
+	It("should be driven through the introspection socket", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/devices/dev1/drain", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var status DrainStatus
+		Expect(json.NewDecoder(rec.Body).Decode(&status)).To(Succeed())
+		Expect(status).To(Equal(DrainStatus{ID: "dev1", Drained: true}))
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/devices/unknown/drain", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should drain all devices but leave the allocated ones in use", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(dp.Drain()).To(Equal(NodeDrainStatus{Drained: true, Allocated: []string{"dev0"}}))
+		advertised := advertisedCache(dp)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(advertised["dev1"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(dp.allocations.isAllocated("dev0")).To(BeTrue())
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+		Expect(err).To(MatchError(ContainSubstring("drained device: dev1")))
+
+		dp.releaseAllocation("dev0")
+		Expect(dp.NodeDrainStatus().Allocated).To(BeEmpty())
+	})
+
+	It("should keep the devices drained one by one when uncordoning", func() {
+		_, err := dp.DrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		dp.Drain()
+
+		status, err := dp.Uncordon()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Drained).To(BeFalse())
+		advertised := advertisedCache(dp)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(advertised["dev1"].Health).To(Equal(pluginapi.Healthy))
+
+		_, err = dp.Uncordon()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should drain all devices through the introspection socket", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/drain", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(dp.isDrained("dev1")).To(BeTrue())
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/drain", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(dp.isDrained("dev1")).To(BeFalse())
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/drain", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
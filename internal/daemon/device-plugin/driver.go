@@ -0,0 +1,31 @@
+package deviceplugin
+
+import (
+	"fmt"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+)
+
+// checkDriverBinding verifies that the devices are bound to the expected
+// driver, e.g. vfio-pci for DPDK workloads. A device bound to another driver
+// would only fail once the container uses it, so it is rejected up front and
+// Kubelet retries once the binding is fixed. Devices that aren't PCI
+// functions, such as the interfaces advertised on the DPU, aren't checked.
+func (dp *dpServer) checkDriverBinding(ids []string) error {
+	if dp.expectedDriver == "" {
+		return nil
+	}
+	for _, id := range dp.physicalDevices(ids) {
+		if !sriovutils.IsValidPCIAddress(id) {
+			continue
+		}
+		driver, err := dp.driverName(id)
+		if err != nil {
+			return fmt.Errorf("failed to check the driver of device %s: %v", id, err)
+		}
+		if driver != dp.expectedDriver {
+			return fmt.Errorf("device %s is bound to driver %q, expected %q", id, driver, dp.expectedDriver)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Driver binding", func() {
+	var (
+		dp      *dpServer
+		drivers map[string]string
+	)
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("0000:3b:00.2", "0000:3b:00.3", "eth0")
+		drivers = map[string]string{
+			"0000:3b:00.2": "vfio-pci",
+			"0000:3b:00.3": "iavf",
+		}
+		dp.driverName = func(pciAddr string) (string, error) {
+			driver, ok := drivers[pciAddr]
+			if !ok {
+				return "", fmt.Errorf("no driver")
+			}
+			return driver, nil
+		}
+		WithExpectedDriver("vfio-pci")(dp)
+	})
+
+	It("should allocate devices bound to the expected driver", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.2", "eth0"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject devices bound to another driver", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.2"}, []string{"0000:3b:00.3"}))
+		Expect(err).To(MatchError(ContainSubstring(`device 0000:3b:00.3 is bound to driver "iavf", expected "vfio-pci"`)))
+		Expect(dp.GetAllocations()).To(BeEmpty())
+	})
+
+	It("should check the binding again before the container starts", func() {
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.PreStartRequired).To(BeTrue())
+
+		rqt := &pluginapi.PreStartContainerRequest{DevicesIDs: []string{"0000:3b:00.2"}}
+		_, err = dp.PreStartContainer(context.Background(), rqt)
+		Expect(err).NotTo(HaveOccurred())
+
+		drivers["0000:3b:00.2"] = "iavf"
+		_, err = dp.PreStartContainer(context.Background(), rqt)
+		Expect(err).To(MatchError(ContainSubstring("is bound to driver")))
+	})
+
+	It("should not check the binding by default", func() {
+		WithExpectedDriver("")(dp)
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"0000:3b:00.3"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,76 @@
+package deviceplugin
+
+import (
+	"time"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// EventType is what happened to the devices of an Event.
+type EventType string
+
+const (
+	// EventAllocated is sent for each container Allocate handed devices to.
+	EventAllocated EventType = "allocated"
+	// EventHealthChanged is sent for each advertised device whose health
+	// changed.
+	EventHealthChanged EventType = "health"
+)
+
+// Event describes an allocation or health change of the Device Plugin.
+type Event struct {
+	Type     EventType `json:"type"`
+	Resource string    `json:"resource"`
+	Time     time.Time `json:"time"`
+	Devices  []string  `json:"devices"`
+	// Health is the new health of the devices of an EventHealthChanged.
+	Health string `json:"health,omitempty"`
+	// Reason is why a health source reported the devices of an
+	// EventHealthChanged unhealthy, empty when the vendor plugin did.
+	Reason string `json:"reason,omitempty"`
+}
+
+// EventSink receives the events of the Device Plugin. Send is called from
+// Allocate and ListAndWatch, so it must not block; events that can't be
+// delivered right away are best dropped.
+type EventSink interface {
+	Send(event Event)
+}
+
+func (dp *dpServer) sendEvent(eventType EventType, devices []string, health, reason string) {
+	if dp.eventSink == nil {
+		return
+	}
+	dp.eventSink.Send(Event{
+		Type:     eventType,
+		Resource: dp.resourceName,
+		Time:     dp.clock.Now(),
+		Devices:  devices,
+		Health:   health,
+		Reason:   reason,
+	})
+}
+
+// sendHealthChanges sends an event for each device advertised both before
+// and now with a different health. Devices coming and going aren't health
+// changes.
+func (dp *dpServer) sendHealthChanges(old, new *dh.DeviceList) {
+	if dp.eventSink == nil {
+		return
+	}
+	failures := dp.HealthFailures()
+	for _, id := range sortedDeviceIDs(new) {
+		oldDev, ok := (*old)[id]
+		if health := (*new)[id].Health; ok && oldDev.Health != health {
+			dp.sendEvent(EventHealthChanged, []string{id}, health, failures[id])
+		}
+	}
+}
+
+// WithEventSink sends allocation and health events to sink, e.g. a
+// DatagramEventSink or a KubeEventSink.
+func WithEventSink(sink EventSink) func(*dpServer) {
+	return func(d *dpServer) {
+		d.eventSink = sink
+	}
+}
@@ -0,0 +1,51 @@
+package deviceplugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	// listenAttempts bounds how often listening is retried while out of file
+	// descriptors, retrying endlessly would only add to the pressure.
+	listenAttempts          = 5
+	defaultListenRetryDelay = 500 * time.Millisecond
+	maxListenRetryDelay     = 8 * time.Second
+)
+
+// isFDExhausted returns whether err is caused by the process (EMFILE) or the
+// system (ENFILE) running out of file descriptors.
+func isFDExhausted(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// describeFDExhaustion makes file descriptor exhaustion errors actionable,
+// other errors are returned unchanged.
+func describeFDExhaustion(err error) error {
+	if !isFDExhausted(err) {
+		return err
+	}
+	return fmt.Errorf("%w: out of file descriptors, raise the open files limit (ulimit -n) of the daemon or look for a file descriptor leak", err)
+}
+
+// listenWithRetry listens on a unix socket. Running out of file descriptors
+// may be transient, e.g. while Kubelet restarts, so it's retried a bounded
+// number of times with an exponential backoff. Other errors fail right away.
+func (dp *dpServer) listenWithRetry(address string) (net.Listener, error) {
+	backoff := newReconcileBackoff(dp.listenRetryDelay, maxListenRetryDelay)
+	for {
+		lis, err := dp.listen("unix", address)
+		if err == nil || !isFDExhausted(err) {
+			return lis, err
+		}
+		if backoff.failures+1 >= listenAttempts {
+			return nil, describeFDExhaustion(fmt.Errorf("giving up after %d attempts: %w", listenAttempts, err))
+		}
+		delay := backoff.failure()
+		dp.log.Error(describeFDExhaustion(err), "Failed to listen, retrying", "address", address, "attempt", backoff.failures, "retryIn", delay)
+		time.Sleep(delay)
+	}
+}
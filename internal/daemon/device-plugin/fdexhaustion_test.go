@@ -0,0 +1,67 @@
+package deviceplugin
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File descriptor exhaustion", func() {
+	var (
+		dp       *dpServer
+		attempts int
+		failures int
+		failWith error
+	)
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin()
+		WithSelfTest(false)(dp)
+		WithIntrospection(false)(dp)
+		Expect(os.MkdirAll(dp.socketDir(), 0o755)).To(Succeed())
+		dp.listenRetryDelay = time.Millisecond
+		attempts = 0
+		failures = listenAttempts
+		failWith = os.NewSyscallError("socket", syscall.EMFILE)
+		dp.listen = func(network, address string) (net.Listener, error) {
+			attempts++
+			if attempts <= failures {
+				return nil, &net.OpError{Op: "listen", Net: network, Err: failWith}
+			}
+			return net.Listen(network, address)
+		}
+	})
+
+	It("should explain how to fix running out of file descriptors", func() {
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("too many open files")))
+		Expect(err).To(MatchError(ContainSubstring("raise the open files limit (ulimit -n)")))
+		Expect(attempts).To(Equal(listenAttempts))
+	})
+
+	It("should recover when file descriptors become available again", func() {
+		failures = 2
+		dir, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+
+		lis, err := dp.listenWithRetry(filepath.Join(dir, "dp.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		lis.Close()
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("should not retry other errors", func() {
+		failWith = os.NewSyscallError("bind", syscall.EADDRINUSE)
+		_, err := dp.listenWithRetry(dp.pluginEndpoint)
+		Expect(errors.Is(err, syscall.EADDRINUSE)).To(BeTrue())
+		Expect(err).NotTo(MatchError(ContainSubstring("ulimit")))
+		Expect(attempts).To(Equal(1))
+	})
+})
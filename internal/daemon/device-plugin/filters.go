@@ -0,0 +1,135 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// Reasons for not advertising a discovered device, used as metric label.
+const (
+	dropReasonCap               = "cap"
+	dropReasonMissingCapability = "missing_capability"
+	dropReasonOverCapacity      = "over_capacity"
+	dropReasonReserved          = "reserved"
+)
+
+// sortedDeviceIDs returns the IDs of devices in a stable order.
+func sortedDeviceIDs(devices *dh.DeviceList) []string {
+	ids := make([]string, 0, len(*devices))
+	for id := range *devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// capDevices drops the devices exceeding maxDevices. The devices are sorted
+// by ID before truncating so that the same devices are kept on every poll.
+func (dp *dpServer) capDevices(devices *dh.DeviceList) {
+	dropped := 0
+	if dp.maxDevices > 0 && len(*devices) > dp.maxDevices {
+		for _, id := range sortedDeviceIDs(devices)[dp.maxDevices:] {
+			delete(*devices, id)
+			dropped++
+		}
+	}
+	dp.reportDroppedDevices(dropReasonCap, dropped)
+}
+
+// reserveDevices drops the devices kept for the host: first the fixed
+// reservation, then reservedPercent of the remaining devices, rounded down.
+// The percentage is taken from the highest IDs so that the reserved set only
+// changes when the discovered devices do.
+func (dp *dpServer) reserveDevices(devices *dh.DeviceList) {
+	var reserved []string
+	for _, id := range dp.reservedIDs {
+		if _, ok := (*devices)[id]; ok {
+			delete(*devices, id)
+			reserved = append(reserved, id)
+		}
+	}
+	ids := sortedDeviceIDs(devices)
+	count := len(ids) * dp.reservedPercent / 100
+	for _, id := range ids[len(ids)-count:] {
+		delete(*devices, id)
+		reserved = append(reserved, id)
+	}
+	sort.Strings(reserved)
+
+	if !slices.Equal(reserved, dp.reserved) {
+		dp.log.Info("Reserved devices for the host changed", "reserved", strings.Join(reserved, ","))
+		dp.reserved = reserved
+	}
+	dp.reportDroppedDevices(dropReasonReserved, len(reserved))
+}
+
+// missingCapabilities returns the required capabilities a device lacks.
+func (dp *dpServer) missingCapabilities(id string) []string {
+	var capabilities []string
+	if handler, ok := dp.deviceHandler.(dh.CapabilityHandler); ok {
+		capabilities = handler.GetDeviceCapabilities(id)
+	}
+	have := make(map[string]bool, len(capabilities))
+	for _, c := range capabilities {
+		have[c] = true
+	}
+	var missing []string
+	for _, c := range dp.requiredCapabilities {
+		if !have[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// filterCapabilities drops the devices lacking one of the required
+// capabilities. Devices are logged when they start being skipped, not on
+// every poll.
+func (dp *dpServer) filterCapabilities(devices *dh.DeviceList) {
+	if len(dp.requiredCapabilities) == 0 {
+		return
+	}
+	skipped := make(map[string]bool)
+	for _, id := range sortedDeviceIDs(devices) {
+		missing := dp.missingCapabilities(id)
+		if len(missing) == 0 {
+			continue
+		}
+		if !dp.capabilitySkipped[id] {
+			dp.log.Info("Not advertising device lacking required capabilities", "id", id, "missing", strings.Join(missing, ","))
+		}
+		skipped[id] = true
+		delete(*devices, id)
+	}
+	dp.capabilitySkipped = skipped
+	dp.reportDroppedDevices(dropReasonMissingCapability, len(skipped))
+}
+
+// clampToCapacity makes sure that no more devices are advertised than the
+// vendor plugin reported as physically present. Exceeding it is always a bug,
+// e.g. in the logic sharing devices, and would let pods be scheduled onto
+// capacity that doesn't exist.
+func (dp *dpServer) clampToCapacity(physical int, devices *dh.DeviceList) {
+	dropped := 0
+	if len(*devices) > physical {
+		err := fmt.Errorf("advertised %d devices but the vendor plugin reports only %d", len(*devices), physical)
+		dp.log.Error(err, "Refusing to advertise more devices than physically present, this is a bug")
+		for _, id := range sortedDeviceIDs(devices)[physical:] {
+			delete(*devices, id)
+			dropped++
+		}
+	}
+	dp.reportDroppedDevices(dropReasonOverCapacity, dropped)
+}
+
+func (dp *dpServer) reportDroppedDevices(reason string, dropped int) {
+	if dp.dropped[reason] != dropped {
+		dp.log.Info("Number of discovered devices not advertised changed", "reason", reason, "dropped", dropped)
+		dp.dropped[reason] = dropped
+	}
+	droppedDevices.WithLabelValues(dp.resourceName, reason).Set(float64(dropped))
+}
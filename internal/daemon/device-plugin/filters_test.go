@@ -0,0 +1,175 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr/funcr"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func gaugeValue(g prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	Expect(g.Write(m)).To(Succeed())
+	return m.GetGauge().GetValue()
+}
+
+func testDeviceList(n int) *dh.DeviceList {
+	devices := make(dh.DeviceList)
+	for i := n - 1; i >= 0; i-- {
+		id := fmt.Sprintf("dev%02d", i)
+		devices[id] = pluginapi.Device{ID: id, Health: pluginapi.Healthy}
+	}
+	return &devices
+}
+
+// capabilityDeviceHandler reports the capabilities of the devices.
+type capabilityDeviceHandler struct {
+	capabilities map[string][]string
+}
+
+func (h *capabilityDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (h *capabilityDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	return testDeviceList(len(h.capabilities)), nil
+}
+
+func (h *capabilityDeviceHandler) GetDeviceCapabilities(id string) []string {
+	return h.capabilities[id]
+}
+
+var _ = Describe("Device filters", func() {
+	Context("capDevices", func() {
+		It("should deterministically keep the lowest IDs and report the drop count", func() {
+			dp := newTestDevicePlugin()
+			WithMaxDevices(3)(dp)
+
+			for i := 0; i < 5; i++ {
+				devices := testDeviceList(10)
+				dp.capDevices(devices)
+				Expect(sortedDeviceIDs(devices)).To(Equal([]string{"dev00", "dev01", "dev02"}))
+			}
+			Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonCap))).To(Equal(7.0))
+		})
+
+		It("should not drop anything below the cap", func() {
+			dp := newTestDevicePlugin()
+			WithMaxDevices(30)(dp)
+
+			devices := testDeviceList(10)
+			dp.capDevices(devices)
+			Expect(*devices).To(HaveLen(10))
+			Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonCap))).To(Equal(0.0))
+		})
+	})
+
+	Context("reserveDevices", func() {
+		DescribeTable("should reserve the percentage of the devices rounded down",
+			func(total, percent, reserved int) {
+				dp := newTestDevicePlugin()
+				WithReservedPercentage(percent)(dp)
+
+				devices := testDeviceList(total)
+				dp.reserveDevices(devices)
+				Expect(*devices).To(HaveLen(total - reserved))
+				Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonReserved))).To(Equal(float64(reserved)))
+			},
+			Entry("25% of 8", 8, 25, 2),
+			Entry("25% of 10", 10, 25, 2),
+			Entry("25% of 3", 3, 25, 0),
+			Entry("100% of 4", 4, 100, 4),
+			Entry("none", 10, 0, 0),
+		)
+
+		It("should apply the percentage to what is left after the fixed reservation", func() {
+			dp := newTestDevicePlugin()
+			WithReservedDevices("dev00", "dev01", "missing")(dp)
+			WithReservedPercentage(50)(dp)
+
+			for i := 0; i < 3; i++ {
+				devices := testDeviceList(10)
+				dp.reserveDevices(devices)
+				Expect(sortedDeviceIDs(devices)).To(Equal([]string{"dev02", "dev03", "dev04", "dev05"}))
+			}
+			Expect(dp.reserved).To(Equal([]string{"dev00", "dev01", "dev06", "dev07", "dev08", "dev09"}))
+		})
+	})
+
+	Context("filterCapabilities", func() {
+		var (
+			dp   *dpServer
+			logs []string
+		)
+
+		BeforeEach(func() {
+			dp = newTestDevicePlugin()
+			logs = nil
+			dp.log = funcr.New(func(prefix, args string) {
+				logs = append(logs, args)
+			}, funcr.Options{})
+			WithDeviceHandler(&capabilityDeviceHandler{capabilities: map[string][]string{
+				"dev00": {"ipsec-offload", "vf-lag"},
+				"dev01": {"vf-lag"},
+				"dev02": {"vf-lag", "ipsec-offload"},
+			}})(dp)
+			WithRequiredCapabilities("ipsec-offload", "vf-lag")(dp)
+		})
+
+		It("should exclude devices missing a required capability", func() {
+			for i := 0; i < 3; i++ {
+				devices := testDeviceList(3)
+				dp.filterCapabilities(devices)
+				Expect(sortedDeviceIDs(devices)).To(Equal([]string{"dev00", "dev02"}))
+			}
+			Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonMissingCapability))).To(Equal(1.0))
+
+			skipped := 0
+			for _, l := range logs {
+				if strings.Contains(l, "lacking required capabilities") {
+					Expect(l).To(And(ContainSubstring(`"id"="dev01"`), ContainSubstring(`"missing"="ipsec-offload"`)))
+					skipped++
+				}
+			}
+			Expect(skipped).To(Equal(1))
+		})
+
+		It("should advertise every device without required capabilities", func() {
+			WithRequiredCapabilities()(dp)
+			devices := testDeviceList(3)
+			dp.filterCapabilities(devices)
+			Expect(*devices).To(HaveLen(3))
+		})
+	})
+
+	Context("clampToCapacity", func() {
+		It("should never advertise more devices than physically present", func() {
+			dp := newTestDevicePlugin()
+			var logs []string
+			dp.log = funcr.New(func(prefix, args string) {
+				logs = append(logs, args)
+			}, funcr.Options{})
+
+			// As if each of the 4 physical devices had been expanded
+			// into more than one advertised device.
+			devices := testDeviceList(6)
+			dp.clampToCapacity(4, devices)
+			Expect(sortedDeviceIDs(devices)).To(Equal([]string{"dev00", "dev01", "dev02", "dev03"}))
+			Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonOverCapacity))).To(Equal(2.0))
+			Expect(strings.Join(logs, "\n")).To(ContainSubstring("advertised 6 devices but the vendor plugin reports only 4"))
+
+			devices = testDeviceList(4)
+			dp.clampToCapacity(4, devices)
+			Expect(*devices).To(HaveLen(4))
+			Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonOverCapacity))).To(Equal(0.0))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package deviceplugin
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// freezeCheckInterval is how long the presence of the freeze file is cached,
+// so that a burst of allocations doesn't stat it on every call.
+const freezeCheckInterval = time.Second
+
+// allocationFreeze is a node-wide switch: while its file exists, allocations
+// are rejected, but the Device Plugin stays registered.
+type allocationFreeze struct {
+	path string
+
+	mu        sync.Mutex
+	frozen    bool
+	checkedAt time.Time
+}
+
+// isFrozen returns whether the freeze file exists, checking again once the
+// cached answer is older than freezeCheckInterval.
+func (dp *dpServer) isFrozen() bool {
+	f := dp.freeze
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := dp.clock.Now()
+	if !f.checkedAt.IsZero() && now.Sub(f.checkedAt) < freezeCheckInterval {
+		return f.frozen
+	}
+	f.checkedAt = now
+	_, err := os.Stat(f.path)
+	frozen := err == nil
+	if frozen != f.frozen {
+		if frozen {
+			dp.log.Info("Freeze file found, rejecting allocations", "path", f.path)
+		} else {
+			dp.log.Info("Freeze file removed, allowing allocations again", "path", f.path)
+		}
+		f.frozen = frozen
+	}
+	return f.frozen
+}
+
+// checkNotFrozen fails allocations with a retryable status while frozen.
+func (dp *dpServer) checkNotFrozen() error {
+	if dp.isFrozen() {
+		return status.Errorf(codes.Unavailable, "allocations are frozen on this node by %s", dp.freeze.path)
+	}
+	return nil
+}
+
+// WithFreezeFile rejects allocations as retryable while the file at path
+// exists, so that operators can stop new allocations on the node without
+// taking down the Device Plugin.
+func WithFreezeFile(path string) func(*dpServer) {
+	return func(d *dpServer) {
+		d.freeze = &allocationFreeze{path: path}
+	}
+}
@@ -0,0 +1,60 @@
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Allocation freeze", func() {
+	var (
+		dp         *dpServer
+		clock      *clocktesting.FakeClock
+		freezeFile string
+	)
+
+	allocate := func() error {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		dp.releaseAllocation("dev0")
+		return err
+	}
+
+	BeforeEach(func() {
+		freezeFile = filepath.Join(GinkgoT().TempDir(), "freeze")
+		clock = clocktesting.NewFakeClock(time.Now())
+		dp = newTestDevicePlugin("dev0")
+		WithClock(clock)(dp)
+		WithFreezeFile(freezeFile)(dp)
+	})
+
+	It("should reject allocations as retryable while the freeze file exists", func() {
+		Expect(allocate()).To(Succeed())
+
+		Expect(os.WriteFile(freezeFile, nil, 0o600)).To(Succeed())
+		clock.SetTime(clock.Now().Add(freezeCheckInterval))
+		err := allocate()
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		Expect(err).To(MatchError(ContainSubstring("allocations are frozen")))
+
+		Expect(os.Remove(freezeFile)).To(Succeed())
+		clock.SetTime(clock.Now().Add(freezeCheckInterval))
+		Expect(allocate()).To(Succeed())
+	})
+
+	It("should only check the freeze file again after the check interval", func() {
+		Expect(allocate()).To(Succeed())
+		Expect(os.WriteFile(freezeFile, nil, 0o600)).To(Succeed())
+
+		clock.SetTime(clock.Now().Add(freezeCheckInterval / 2))
+		Expect(allocate()).To(Succeed())
+		clock.SetTime(clock.Now().Add(freezeCheckInterval / 2))
+		Expect(status.Code(allocate())).To(Equal(codes.Unavailable))
+	})
+})
@@ -0,0 +1,93 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ProbeHealth checks the standard gRPC health service on the Device Plugin
+// socket at endpoint, and fails unless the Device Plugin is serving, i.e.
+// registered with Kubelet and able to reach the vendor plugin. Kubernetes
+// gRPC probes only reach TCP ports, so this is meant for an exec probe.
+func ProbeHealth(ctx context.Context, endpoint string) error {
+	conn, err := grpc.NewClient("unix:"+endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the Device Plugin at %s: %v", endpoint, err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to check the health of the Device Plugin at %s: %v", endpoint, err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("Device Plugin at %s is %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// setRegistered records whether the Device Plugin is registered with Kubelet.
+func (dp *dpServer) setRegistered(registered bool) {
+	dp.servingMutex.Lock()
+	defer dp.servingMutex.Unlock()
+	dp.registered = registered
+	dp.updateServingStatus()
+}
+
+// setVendorConnected records whether the last attempt to get the devices
+// from the vendor plugin succeeded.
+func (dp *dpServer) setVendorConnected(connected bool) {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	vendorPluginConnected.WithLabelValues(dp.resourceName).Set(value)
+
+	dp.servingMutex.Lock()
+	defer dp.servingMutex.Unlock()
+	if dp.vendorConnected == connected {
+		return
+	}
+	dp.vendorConnected = connected
+	dp.updateServingStatus()
+	if connected {
+		// The vendor plugin may have restarted with other mounts or
+		// device nodes for the same devices.
+		dp.responses.clear()
+	}
+}
+
+// serveFailed records that the gRPC server stopped serving a socket Kubelet
+// reaches the Device Plugin on, other than through Stop. Kubelet can't reach
+// the Device Plugin anymore, so it reports not serving for good and Serve
+// returns the error, which makes the daemon exit and the pod restart.
+func (dp *dpServer) serveFailed(err error) {
+	dp.log.Error(err, "Device Plugin server stopped serving")
+	dp.recordError(err)
+
+	dp.servingMutex.Lock()
+	dp.serveBroken = true
+	dp.updateServingStatus()
+	dp.servingMutex.Unlock()
+
+	select {
+	case dp.serveErrors <- err:
+	default:
+	}
+}
+
+// updateServingStatus reports the Device Plugin as serving on the standard
+// gRPC health service only while it is registered with Kubelet, can reach
+// the vendor plugin and its server didn't fail. Must be called with
+// servingMutex held.
+func (dp *dpServer) updateServingStatus() {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if dp.registered && dp.vendorConnected && !dp.serveBroken {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	dp.log.Info("Setting gRPC health status", "status", status, "registered", dp.registered, "vendorConnected", dp.vendorConnected, "serveBroken", dp.serveBroken)
+	dp.healthServer.SetServingStatus("", status)
+}
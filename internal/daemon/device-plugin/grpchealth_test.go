@@ -0,0 +1,115 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// flakyDeviceHandler fails to get the devices while failing is set, as if
+// the vendor plugin couldn't be reached.
+type flakyDeviceHandler struct {
+	changingDeviceHandler
+	failing atomic.Bool
+}
+
+func (h *flakyDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	if h.failing.Load() {
+		return nil, fmt.Errorf("vendor plugin unreachable")
+	}
+	return h.changingDeviceHandler.GetDevices(ctx)
+}
+
+var _ = Describe("gRPC health service", func() {
+	var (
+		dp      *dpServer
+		handler *flakyDeviceHandler
+	)
+
+	servingStatus := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := dp.healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.Status
+	}
+
+	BeforeEach(func() {
+		handler = &flakyDeviceHandler{changingDeviceHandler: changingDeviceHandler{ids: []string{"dev0"}}}
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		WithMaxReconcileBackoff(10 * time.Millisecond)(dp)
+		dp.pollInterval = 10 * time.Millisecond
+	})
+
+	It("should not serve before registering", func() {
+		Expect(servingStatus()).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+		dp.setVendorConnected(true)
+		Expect(servingStatus()).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+		dp.setRegistered(true)
+		Expect(servingStatus()).To(Equal(healthpb.HealthCheckResponse_SERVING))
+	})
+
+	It("should stop serving while the vendor plugin can't be reached", func() {
+		stream := &lockedListAndWatchServer{}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			handler.failing.Store(false)
+			handler.set()
+			Eventually(done).Should(BeClosed())
+		})
+
+		dp.setRegistered(true)
+		Eventually(servingStatus).Should(Equal(healthpb.HealthCheckResponse_SERVING))
+
+		handler.failing.Store(true)
+		Eventually(servingStatus).Should(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+
+		handler.failing.Store(false)
+		Eventually(servingStatus).Should(Equal(healthpb.HealthCheckResponse_SERVING))
+	})
+
+	It("should stop serving once stopped", func() {
+		dp.setVendorConnected(true)
+		dp.setRegistered(true)
+		Expect(dp.Stop()).To(Succeed())
+		Expect(servingStatus()).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+		dp.setVendorConnected(false)
+		dp.setVendorConnected(true)
+		Expect(servingStatus()).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+	})
+
+	It("should pass the probe only while serving", func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		endpoint := filepath.Join(root, "dp.sock")
+		lis, err := net.Listen("unix", endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		healthpb.RegisterHealthServer(dp.grpcServer, dp.healthServer)
+		go dp.grpcServer.Serve(lis)
+		DeferCleanup(dp.grpcServer.Stop)
+
+		Expect(ProbeHealth(context.Background(), endpoint)).To(MatchError(ContainSubstring("is NOT_SERVING")))
+		dp.setVendorConnected(true)
+		dp.setRegistered(true)
+		Expect(ProbeHealth(context.Background(), endpoint)).To(Succeed())
+		Expect(ProbeHealth(context.Background(), filepath.Join(root, "missing.sock"))).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,203 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"k8s.io/utils/clock"
+)
+
+const defaultProbeTimeout = 2 * time.Second
+
+// HealthSource is an additional source of device health on top of what the
+// vendor plugin reports. A device is only advertised as healthy when all
+// configured sources consider it healthy.
+type HealthSource interface {
+	// DeviceHealth returns nil if the device is healthy, or an error
+	// describing why it is not.
+	DeviceHealth(ctx context.Context, id string) error
+}
+
+// Prober checks whether target can be reached through iface.
+type Prober interface {
+	Probe(ctx context.Context, iface string, target string) error
+}
+
+// pingProber sends a single ICMP echo request bound to the interface.
+type pingProber struct{}
+
+func (pingProber) Probe(ctx context.Context, iface string, target string) error {
+	out, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", "1", "-I", iface, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ping %s via %s failed: %v: %s", target, iface, err, out)
+	}
+	return nil
+}
+
+type probeResult struct {
+	err       error
+	checkedAt time.Time
+}
+
+// reachabilityHealthSource gates device health on the device being able to
+// reach a target (typically a gateway) through its interface. Results are
+// reused until the probe interval elapsed, so that probing doesn't follow
+// the ListAndWatch poll interval.
+type reachabilityHealthSource struct {
+	target       string
+	interval     time.Duration
+	prober       Prober
+	clock        clock.PassiveClock
+	interfaceFor func(id string) (string, error)
+
+	mu      sync.Mutex
+	results map[string]probeResult
+}
+
+// deviceInterface resolves the network interface of a device. On the host,
+// device IDs are PCI addresses, while on the DPU they are interface names.
+func deviceInterface(id string) (string, error) {
+	if sriovutils.IsValidPCIAddress(id) {
+		return sriovutils.GetVFLinkName(id)
+	}
+	return id, nil
+}
+
+func NewReachabilityHealthSource(target string, interval time.Duration, opts ...func(*reachabilityHealthSource)) *reachabilityHealthSource {
+	s := &reachabilityHealthSource{
+		target:       target,
+		interval:     interval,
+		prober:       pingProber{},
+		clock:        clock.RealClock{},
+		interfaceFor: deviceInterface,
+		results:      make(map[string]probeResult),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithProber(prober Prober) func(*reachabilityHealthSource) {
+	return func(s *reachabilityHealthSource) {
+		s.prober = prober
+	}
+}
+
+func (s *reachabilityHealthSource) DeviceHealth(ctx context.Context, id string) error {
+	s.mu.Lock()
+	result, ok := s.results[id]
+	s.mu.Unlock()
+	if ok && s.clock.Since(result.checkedAt) < s.interval {
+		return result.err
+	}
+
+	// Don't hold the lock while probing so that devices can be probed in
+	// parallel.
+	err := s.probe(ctx, id)
+
+	s.mu.Lock()
+	s.results[id] = probeResult{err: err, checkedAt: s.clock.Now()}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *reachabilityHealthSource) probe(ctx context.Context, id string) error {
+	iface, err := s.interfaceFor(id)
+	if err != nil {
+		return fmt.Errorf("failed to find interface of device %s: %v", id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultProbeTimeout)
+	defer cancel()
+	return s.prober.Probe(ctx, iface, s.target)
+}
+
+// reportAdvertisedHealth updates the number of advertised healthy and
+// unhealthy devices.
+func (dp *dpServer) reportAdvertisedHealth(advertised *dh.DeviceList) {
+	healthy := 0
+	for _, dev := range *advertised {
+		if dev.Health == pluginapi.Healthy {
+			healthy++
+		}
+	}
+	advertisedDevicesByHealth.WithLabelValues(dp.resourceName, pluginapi.Healthy).Set(float64(healthy))
+	advertisedDevicesByHealth.WithLabelValues(dp.resourceName, pluginapi.Unhealthy).Set(float64(len(*advertised) - healthy))
+}
+
+// applyHealthSources marks the devices that any health source reports as
+// failing as unhealthy. Up to healthWorkers devices are checked concurrently,
+// the results are only applied once all checks are done.
+func (dp *dpServer) applyHealthSources(devices *dh.DeviceList) {
+	if len(dp.healthSources) == 0 {
+		return
+	}
+
+	var ids []string
+	for _, id := range sortedDeviceIDs(devices) {
+		if (*devices)[id].Health == pluginapi.Healthy {
+			ids = append(ids, id)
+		}
+	}
+
+	failures := make([]error, len(ids))
+	workers := make(chan struct{}, max(dp.healthWorkers, 1))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failures[i] = dp.checkDeviceHealth(id)
+			<-workers
+		}()
+	}
+	wg.Wait()
+
+	reasons := make(map[string]string)
+	for i, id := range ids {
+		if failures[i] == nil {
+			continue
+		}
+		dp.sampledInfo(dp.log.V(1), "Health source reported device as unhealthy", "id", id, "reason", failures[i])
+		reasons[id] = failures[i].Error()
+		dev := (*devices)[id]
+		dev.Health = pluginapi.Unhealthy
+		(*devices)[id] = dev
+	}
+
+	dp.healthFailuresMutex.Lock()
+	dp.healthFailures = reasons
+	dp.healthFailuresMutex.Unlock()
+}
+
+// HealthFailures returns why the health sources reported devices as
+// unhealthy, by device ID.
+func (dp *dpServer) HealthFailures() map[string]string {
+	dp.healthFailuresMutex.RLock()
+	defer dp.healthFailuresMutex.RUnlock()
+	failures := make(map[string]string, len(dp.healthFailures))
+	for id, reason := range dp.healthFailures {
+		failures[id] = reason
+	}
+	return failures
+}
+
+// checkDeviceHealth returns the first failure reported by a health source.
+func (dp *dpServer) checkDeviceHealth(id string) error {
+	for _, source := range dp.healthSources {
+		if err := source.DeviceHealth(context.Background(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+type fakeProber struct {
+	reachable map[string]bool
+	probes    int
+}
+
+func (p *fakeProber) Probe(ctx context.Context, iface string, target string) error {
+	p.probes++
+	if !p.reachable[iface] {
+		return fmt.Errorf("%s unreachable via %s", target, iface)
+	}
+	return nil
+}
+
+// slowHealthSource takes latency to check a device and reports the devices
+// in unhealthy as failing.
+type slowHealthSource struct {
+	latency   time.Duration
+	unhealthy map[string]bool
+	checks    atomic.Int32
+}
+
+func (s *slowHealthSource) DeviceHealth(ctx context.Context, id string) error {
+	s.checks.Add(1)
+	time.Sleep(s.latency)
+	if s.unhealthy[id] {
+		return fmt.Errorf("%s is broken", id)
+	}
+	return nil
+}
+
+var _ = Describe("Reachability health source", func() {
+	var (
+		prober *fakeProber
+		clock  *clocktesting.FakePassiveClock
+		source *reachabilityHealthSource
+	)
+
+	BeforeEach(func() {
+		prober = &fakeProber{reachable: map[string]bool{"ens5f0": true}}
+		clock = clocktesting.NewFakePassiveClock(time.Now())
+		source = NewReachabilityHealthSource("192.0.2.1", 10*time.Second, WithProber(prober))
+		source.clock = clock
+	})
+
+	It("should report reachable devices as healthy and unreachable ones as unhealthy", func() {
+		Expect(source.DeviceHealth(context.Background(), "ens5f0")).To(Succeed())
+		Expect(source.DeviceHealth(context.Background(), "ens5f1")).To(MatchError(ContainSubstring("unreachable via ens5f1")))
+	})
+
+	It("should only probe again once the probe interval elapsed", func() {
+		Expect(source.DeviceHealth(context.Background(), "ens5f0")).To(Succeed())
+		prober.reachable["ens5f0"] = false
+		Expect(source.DeviceHealth(context.Background(), "ens5f0")).To(Succeed())
+		Expect(prober.probes).To(Equal(1))
+
+		clock.SetTime(clock.Now().Add(10 * time.Second))
+		Expect(source.DeviceHealth(context.Background(), "ens5f0")).NotTo(Succeed())
+		Expect(prober.probes).To(Equal(2))
+	})
+
+	It("should gate the advertised health of the device plugin", func() {
+		dp := newTestDevicePlugin()
+		WithHealthSource(source)(dp)
+
+		devices := dh.DeviceList{
+			"ens5f0": {ID: "ens5f0", Health: pluginapi.Healthy},
+			"ens5f1": {ID: "ens5f1", Health: pluginapi.Healthy},
+		}
+		dp.applyHealthSources(&devices)
+		Expect(devices["ens5f0"].Health).To(Equal(pluginapi.Healthy))
+		Expect(devices["ens5f1"].Health).To(Equal(pluginapi.Unhealthy))
+	})
+})
+
+var _ = Describe("Health check parallelism", func() {
+	It("should check a large device set concurrently within the poll interval", func() {
+		source := &slowHealthSource{latency: 20 * time.Millisecond, unhealthy: map[string]bool{"dev07": true}}
+		dp := newTestDevicePlugin()
+		WithHealthSource(source)(dp)
+		WithHealthWorkers(25)(dp)
+
+		// Checked one after the other, this would take 2s.
+		devices := testDeviceList(100)
+		start := time.Now()
+		dp.applyHealthSources(devices)
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+		Expect(source.checks.Load()).To(BeNumerically("==", 100))
+		Expect((*devices)["dev07"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect((*devices)["dev08"].Health).To(Equal(pluginapi.Healthy))
+	})
+})
+
+var _ = Describe("Composite health source", func() {
+	var (
+		dp      *dpServer
+		devices *dh.DeviceList
+	)
+
+	apply := func(reducer HealthReducer) {
+		dp = newTestDevicePlugin()
+		WithHealthSource(NewCompositeHealthSource(reducer,
+			NamedHealthSource{Name: "link", HealthSource: &slowHealthSource{unhealthy: map[string]bool{"dev00": true, "dev01": true}}},
+			NamedHealthSource{Name: "vendor", HealthSource: &slowHealthSource{unhealthy: map[string]bool{"dev01": true, "dev02": true}}},
+		))(dp)
+		devices = testDeviceList(4)
+		dp.applyHealthSources(devices)
+	}
+
+	healthOf := func(id string) string {
+		return (*devices)[id].Health
+	}
+
+	It("should require every source to be healthy with all-healthy", func() {
+		apply(AllHealthy)
+		Expect(healthOf("dev00")).To(Equal(pluginapi.Unhealthy))
+		Expect(healthOf("dev01")).To(Equal(pluginapi.Unhealthy))
+		Expect(healthOf("dev02")).To(Equal(pluginapi.Unhealthy))
+		Expect(healthOf("dev03")).To(Equal(pluginapi.Healthy))
+
+		Expect(dp.HealthFailures()).To(Equal(map[string]string{
+			"dev00": "link: dev00 is broken",
+			"dev01": "link: dev01 is broken; vendor: dev01 is broken",
+			"dev02": "vendor: dev02 is broken",
+		}))
+	})
+
+	It("should require one source to be healthy with any-healthy", func() {
+		apply(AnyHealthy)
+		Expect(healthOf("dev00")).To(Equal(pluginapi.Healthy))
+		Expect(healthOf("dev01")).To(Equal(pluginapi.Unhealthy))
+		Expect(healthOf("dev02")).To(Equal(pluginapi.Healthy))
+		Expect(healthOf("dev03")).To(Equal(pluginapi.Healthy))
+
+		Expect(dp.HealthFailures()).To(Equal(map[string]string{
+			"dev01": "link: dev01 is broken; vendor: dev01 is broken",
+		}))
+	})
+
+	It("should show the failing sources through introspection", func() {
+		apply(AllHealthy)
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"dev02":"vendor: dev02 is broken"`))
+	})
+})
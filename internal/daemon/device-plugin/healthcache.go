@@ -0,0 +1,82 @@
+package deviceplugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// healthCache keeps the devices, with their health, last reported by the
+// vendor plugin, so that updates triggered in a burst, e.g. by draining
+// devices one by one, don't query a slow vendor plugin each time. The vendor
+// plugin reports all devices at once, so they share a single timestamp.
+// Failures aren't cached, ListAndWatch backs off on its own.
+type healthCache struct {
+	mu        sync.Mutex
+	devices   dh.DeviceList
+	fetchedAt time.Time
+}
+
+// get returns a copy of the cached devices if they are younger than ttl.
+func (c *healthCache) get(now time.Time, ttl time.Duration) (*dh.DeviceList, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.devices == nil || now.Sub(c.fetchedAt) >= ttl {
+		return nil, false
+	}
+	return copyDeviceList(c.devices), true
+}
+
+func (c *healthCache) put(devices *dh.DeviceList, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.devices = *copyDeviceList(*devices)
+	c.fetchedAt = now
+}
+
+// copyDeviceList copies the list, ListAndWatch filters and marks the devices
+// it gets in place.
+func copyDeviceList(devices dh.DeviceList) *dh.DeviceList {
+	copied := make(dh.DeviceList, len(devices))
+	for id, dev := range devices {
+		copied[id] = dev
+	}
+	return &copied
+}
+
+// getDevices gets the devices from the vendor plugin, or from the health
+// cache while it is younger than healthCacheTTL.
+func (dp *dpServer) getDevices(ctx context.Context) (*dh.DeviceList, error) {
+	if dp.healthCacheTTL <= 0 {
+		return dp.deviceHandler.GetDevices(ctx)
+	}
+	if devices, ok := dp.healthCache.get(dp.clock.Now(), dp.healthCacheTTL); ok {
+		return devices, nil
+	}
+	devices, err := dp.deviceHandler.GetDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dp.healthCache.put(devices, dp.clock.Now())
+	return devices, nil
+}
+
+// defaultHealthCacheTTL caches the devices for half the poll interval unless
+// configured otherwise, so that every regular poll still queries the vendor
+// plugin, only the updates triggered in between are served from the cache.
+func (dp *dpServer) defaultHealthCacheTTL() {
+	if dp.healthCacheTTL < 0 {
+		dp.healthCacheTTL = dp.pollInterval / 2
+	}
+}
+
+// WithHealthCacheTTL sets how long the devices and their health reported by
+// the vendor plugin are reused before querying it again. Zero disables the
+// cache, a negative TTL uses the default of half the poll interval.
+func WithHealthCacheTTL(ttl time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.healthCacheTTL = ttl
+	}
+}
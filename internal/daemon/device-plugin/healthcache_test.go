@@ -0,0 +1,64 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Health cache", func() {
+	var (
+		dp      *dpServer
+		handler *countingDeviceHandler
+		clock   *clocktesting.FakeClock
+	)
+
+	BeforeEach(func() {
+		handler = &countingDeviceHandler{changingDeviceHandler: changingDeviceHandler{ids: []string{"dev0"}}}
+		clock = clocktesting.NewFakeClock(time.Now())
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		WithClock(clock)(dp)
+		WithHealthCacheTTL(time.Minute)(dp)
+	})
+
+	It("should not query the vendor plugin more than once within the TTL", func() {
+		for range 3 {
+			devices, err := dp.getDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*devices).To(HaveKey("dev0"))
+			clock.SetTime(clock.Now().Add(10 * time.Second))
+		}
+		Expect(handler.polls.Load()).To(Equal(int32(1)))
+
+		clock.SetTime(clock.Now().Add(time.Minute))
+		_, err := dp.getDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handler.polls.Load()).To(Equal(int32(2)))
+	})
+
+	It("should not let changes to the served devices leak into the cache", func() {
+		devices, err := dp.getDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		delete(*devices, "dev0")
+
+		devices, err = dp.getDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*devices).To(HaveKey("dev0"))
+	})
+
+	It("should query the vendor plugin every time when disabled", func() {
+		WithHealthCacheTTL(0)(dp)
+		_, _ = dp.getDevices(context.Background())
+		_, _ = dp.getDevices(context.Background())
+		Expect(handler.polls.Load()).To(Equal(int32(2)))
+	})
+
+	It("should default to half the poll interval", func() {
+		dp := NewDevicePlugin(nil, true, dp.pathManager, WithPollInterval(10*time.Second))
+		Expect(dp.healthCacheTTL).To(Equal(5 * time.Second))
+	})
+})
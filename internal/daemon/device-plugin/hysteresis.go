@@ -0,0 +1,53 @@
+package deviceplugin
+
+import (
+	"time"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// applyRecoveryHysteresis keeps advertising a device that recovered as
+// unhealthy until it stayed healthy for the recovery hysteresis, while a
+// failing device is advertised as unhealthy right away. This keeps flapping
+// devices out of the pool.
+func (dp *dpServer) applyRecoveryHysteresis(devices *dh.DeviceList) {
+	if dp.recoveryHysteresis <= 0 {
+		return
+	}
+
+	for id := range dp.recovering {
+		if _, ok := (*devices)[id]; !ok {
+			delete(dp.recovering, id)
+		}
+	}
+
+	now := dp.clock.Now()
+	for id, dev := range *devices {
+		if dev.Health != pluginapi.Healthy {
+			// A zero time marks a device that is still failing.
+			dp.recovering[id] = time.Time{}
+			continue
+		}
+
+		since, ok := dp.recovering[id]
+		if !ok {
+			continue
+		}
+		if since.IsZero() {
+			dp.log.Info("Device recovered, waiting before advertising it as healthy", "id", id, "hysteresis", dp.recoveryHysteresis)
+			dp.recovering[id] = now
+			since = now
+			// Re-evaluate the device when it's due instead of waiting for
+			// the next poll.
+			time.AfterFunc(dp.recoveryHysteresis, dp.triggerUpdate)
+		}
+		if now.Sub(since) < dp.recoveryHysteresis {
+			dev.Health = pluginapi.Unhealthy
+			(*devices)[id] = dev
+			continue
+		}
+		dp.log.Info("Device stayed healthy, advertising it as healthy", "id", id)
+		delete(dp.recovering, id)
+	}
+}
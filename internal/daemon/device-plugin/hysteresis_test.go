@@ -0,0 +1,65 @@
+package deviceplugin
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Recovery hysteresis", func() {
+	var (
+		dp    *dpServer
+		clock *clocktesting.FakeClock
+	)
+
+	// advertisedHealth runs the hysteresis on dev0 reported with health.
+	advertisedHealth := func(health string) string {
+		devices := dh.DeviceList{"dev0": {ID: "dev0", Health: health}}
+		dp.applyRecoveryHysteresis(&devices)
+		return devices["dev0"].Health
+	}
+
+	BeforeEach(func() {
+		clock = clocktesting.NewFakeClock(time.Now())
+		dp = newTestDevicePlugin()
+		WithClock(clock)(dp)
+		WithRecoveryHysteresis(time.Minute)(dp)
+	})
+
+	It("should advertise a healthy device right away", func() {
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Healthy))
+	})
+
+	It("should wait for the hysteresis before advertising a recovered device", func() {
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Healthy))
+		Expect(advertisedHealth(pluginapi.Unhealthy)).To(Equal(pluginapi.Unhealthy))
+
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Unhealthy))
+		clock.SetTime(clock.Now().Add(59 * time.Second))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Unhealthy))
+		clock.SetTime(clock.Now().Add(time.Second))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Healthy))
+	})
+
+	It("should restart the hysteresis when the device fails again", func() {
+		Expect(advertisedHealth(pluginapi.Unhealthy)).To(Equal(pluginapi.Unhealthy))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Unhealthy))
+		clock.SetTime(clock.Now().Add(30 * time.Second))
+		Expect(advertisedHealth(pluginapi.Unhealthy)).To(Equal(pluginapi.Unhealthy))
+
+		clock.SetTime(clock.Now().Add(30 * time.Second))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Unhealthy))
+		clock.SetTime(clock.Now().Add(time.Minute))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Healthy))
+	})
+
+	It("should advertise recovered devices right away without hysteresis", func() {
+		WithRecoveryHysteresis(0)(dp)
+		Expect(advertisedHealth(pluginapi.Unhealthy)).To(Equal(pluginapi.Unhealthy))
+		Expect(advertisedHealth(pluginapi.Healthy)).To(Equal(pluginapi.Healthy))
+	})
+})
@@ -0,0 +1,58 @@
+package deviceplugin
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newGrpcServer creates the Device Plugin server, every call goes through
+// the interceptors so that a panicking handler fails only its own call
+// instead of taking down the daemon.
+func (dp *dpServer) newGrpcServer() *grpc.Server {
+	return grpc.NewServer(
+		grpc.ChainUnaryInterceptor(dp.unaryInterceptor),
+		grpc.ChainStreamInterceptor(dp.streamInterceptor),
+	)
+}
+
+func (dp *dpServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	start := dp.clock.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = dp.recovered(info.FullMethod, r)
+		}
+		dp.logCall(info.FullMethod, dp.clock.Since(start), err)
+	}()
+	return handler(ctx, req)
+}
+
+func (dp *dpServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := dp.clock.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = dp.recovered(info.FullMethod, r)
+		}
+		dp.logCall(info.FullMethod, dp.clock.Since(start), err)
+	}()
+	return handler(srv, ss)
+}
+
+// recovered logs the panic of a handler and turns it into the status of the
+// call.
+func (dp *dpServer) recovered(method string, r interface{}) error {
+	dp.log.Error(nil, "Recovered from a panic while serving", "method", method, "panic", r, "stack", string(debug.Stack()))
+	grpcPanicsTotal.WithLabelValues(dp.resourceName, method).Inc()
+	return status.Errorf(codes.Internal, "panic while serving %s: %v", method, r)
+}
+
+// logCall is verbose only, the handlers already log their failures.
+func (dp *dpServer) logCall(method string, latency time.Duration, err error) {
+	code := status.Code(err)
+	grpcRequestsTotal.WithLabelValues(dp.resourceName, method, code.String()).Inc()
+	dp.log.V(1).Info("Device Plugin call served", "method", method, "latency", latency, "code", code)
+}
@@ -0,0 +1,51 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("gRPC interceptors", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0")
+	})
+
+	It("should turn a panicking unary handler into an Internal error", func() {
+		method := "/v1beta1.DevicePlugin/Allocate"
+		before := counterValue(grpcPanicsTotal.WithLabelValues(dp.resourceName, method))
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		resp, err := dp.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+		Expect(resp).To(BeNil())
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+		Expect(err.Error()).To(ContainSubstring("boom"))
+		Expect(counterValue(grpcPanicsTotal.WithLabelValues(dp.resourceName, method))).To(Equal(before + 1))
+	})
+
+	It("should turn a panicking stream handler into an Internal error", func() {
+		info := &grpc.StreamServerInfo{FullMethod: "/v1beta1.DevicePlugin/ListAndWatch"}
+		err := dp.streamInterceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+			panic("boom")
+		})
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+	})
+
+	It("should pass through the result of the handler", func() {
+		method := "/v1beta1.DevicePlugin/GetDevicePluginOptions"
+		before := counterValue(grpcRequestsTotal.WithLabelValues(dp.resourceName, method, codes.Unavailable.String()))
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := dp.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Unavailable, "not yet")
+		})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		Expect(counterValue(grpcRequestsTotal.WithLabelValues(dp.resourceName, method, codes.Unavailable.String()))).To(Equal(before + 1))
+	})
+})
@@ -0,0 +1,380 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openshift/dpu-operator/pkgs/version"
+)
+
+// introspectionAPIVersion is bumped whenever an incompatible change is made
+// to the introspection API. Additions are advertised as features instead.
+const (
+	introspectionAPIVersion       = 1
+	introspectionAPIVersionHeader = "X-Introspection-API-Version"
+)
+
+// introspectionFeatures lists the optional parts of the introspection API
+// this Device Plugin serves, so that clients can adapt to older plugins.
+var introspectionFeatures = []string{"info", "allocations", "drain", "maintenance", "cores", "simulate", "health", "strategy", "schedulability", "node-drain"}
+
+// Unsupported is the response to requests for an API version or a feature
+// the Device Plugin doesn't support. It tells the client what is supported
+// so that it can degrade gracefully.
+type Unsupported struct {
+	Reason     string   `json:"reason"`
+	APIVersion int      `json:"apiVersion"`
+	Features   []string `json:"features"`
+}
+
+// BuildInfo identifies the build of the running Device Plugin.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// ConfigSummary is the effective runtime configuration of the Device Plugin.
+// It must never contain secrets, only settings an operator can act upon.
+type ConfigSummary struct {
+	PollInterval             string   `json:"pollInterval"`
+	MinPollInterval          string   `json:"minPollInterval"`
+	MaxReconcileBackoff      string   `json:"maxReconcileBackoff"`
+	AllocateLatencyThreshold string   `json:"allocateLatencyThreshold"`
+	CoalesceWindow           string   `json:"coalesceWindow"`
+	MaxDevices               int      `json:"maxDevices"`
+	SortOrder                string   `json:"sortOrder"`
+	AllocationStrategy       string   `json:"allocationStrategy"`
+	HealthSources            int      `json:"healthSources"`
+	HealthWorkers            int      `json:"healthWorkers"`
+	RecoveryHysteresis       string   `json:"recoveryHysteresis"`
+	TrackStaleAllocations    bool     `json:"trackStaleAllocations"`
+	AllocationTTL            string   `json:"allocationTTL"`
+	ReclaimExpired           bool     `json:"reclaimExpired"`
+	NumaEnv                  bool     `json:"numaEnv"`
+	PayloadEnv               bool     `json:"payloadEnv"`
+	SelfTest                 bool     `json:"selfTest"`
+	LogSampling              int      `json:"logSampling"`
+	ExpectedDriver           string   `json:"expectedDriver,omitempty"`
+	MinVendorVersion         string   `json:"minVendorVersion,omitempty"`
+	RequiredCapabilities     []string `json:"requiredCapabilities,omitempty"`
+	ReservedDevices          []string `json:"reservedDevices,omitempty"`
+	ReservedPercent          int      `json:"reservedPercent,omitempty"`
+	FreezeFile               string   `json:"freezeFile,omitempty"`
+	ReadinessFile            string   `json:"readinessFile,omitempty"`
+	AdvertiseUnchanged       bool     `json:"advertiseUnchanged,omitempty"`
+	WarmFirstWindow          string   `json:"warmFirstWindow,omitempty"`
+	HealthCacheTTL           string   `json:"healthCacheTTL"`
+}
+
+// RegistrationInfo describes the sockets used to register with Kubelet.
+type RegistrationInfo struct {
+	KubeletSocket string `json:"kubeletSocket"`
+	PluginSocket  string `json:"pluginSocket"`
+	// Endpoint is the plugin socket as sent to Kubelet, relative to the
+	// Kubelet device plugin directory.
+	Endpoint string `json:"endpoint"`
+}
+
+// Info is the response of the introspection "/info" endpoint.
+type Info struct {
+	APIVersion   int           `json:"apiVersion"`
+	Features     []string      `json:"features"`
+	ResourceName string        `json:"resourceName"`
+	Build        BuildInfo     `json:"build"`
+	Config       ConfigSummary `json:"config"`
+	// Registration is unset until the Device Plugin registered with Kubelet.
+	Registration *RegistrationInfo `json:"registration,omitempty"`
+}
+
+// introspectionServer exposes the internal state of the Device Plugin as JSON
+// over a local unix socket, so that operators can see what the plugin is doing
+// without going through Kubelet.
+type introspectionServer struct {
+	http.Server
+	dp *dpServer
+}
+
+func newIntrospectionServer(dp *dpServer) *introspectionServer {
+	router := mux.NewRouter()
+	s := &introspectionServer{
+		Server: http.Server{
+			Handler: router,
+		},
+		dp: dp,
+	}
+
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeUnsupported(w, fmt.Sprintf("%s %s is not supported", r.Method, r.URL.Path))
+	})
+	router.MethodNotAllowedHandler = router.NotFoundHandler
+	router.Use(checkAPIVersion)
+	router.HandleFunc("/info", s.handleGetInfo).Methods(http.MethodGet)
+	router.HandleFunc("/allocations", s.handleGetAllocations).Methods(http.MethodGet)
+	router.HandleFunc("/devices/{id}/drain", s.handleGetDrainStatus).Methods(http.MethodGet)
+	router.HandleFunc("/devices/{id}/drain", s.handleDrainDevice).Methods(http.MethodPost)
+	router.HandleFunc("/devices/{id}/undrain", s.handleUndrainDevice).Methods(http.MethodPost)
+	router.HandleFunc("/drain", s.handleGetNodeDrainStatus).Methods(http.MethodGet)
+	router.HandleFunc("/drain", s.handleDrain).Methods(http.MethodPost)
+	router.HandleFunc("/drain", s.handleUncordon).Methods(http.MethodDelete)
+	router.HandleFunc("/cores", s.handleGetCores).Methods(http.MethodGet)
+	router.HandleFunc("/health", s.handleGetHealth).Methods(http.MethodGet)
+	router.HandleFunc("/simulate", s.handleSimulateAllocate).Methods(http.MethodGet).Queries("count", "{count}")
+	router.HandleFunc("/maintenance", s.handleGetMaintenance).Methods(http.MethodGet)
+	router.HandleFunc("/maintenance", s.handleStartMaintenance).Methods(http.MethodPost).Queries("duration", "{duration}")
+	router.HandleFunc("/maintenance", s.handleEndMaintenance).Methods(http.MethodDelete)
+	router.HandleFunc("/strategy", s.handleGetStrategy).Methods(http.MethodGet)
+	router.HandleFunc("/strategy", s.handleSetStrategy).Methods(http.MethodPut).Queries("name", "{name}")
+	router.HandleFunc("/schedulability", s.handleGetSchedulability).Methods(http.MethodGet)
+
+	return s
+}
+
+func (s *introspectionServer) Listen() (net.Listener, error) {
+	socketPath := s.dp.introspectionPath
+	err := s.dp.pathManager.EnsureSocketDirExists(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run directory for introspection socket: %v", err)
+	}
+	listener, err := s.dp.listenWithRetry(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on introspection socket: %v", err)
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set file permissions on introspection socket: %v", err)
+	}
+	return listener, nil
+}
+
+func (s *introspectionServer) ShutdownAndWait() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Shutdown(ctx)
+}
+
+func (s *introspectionServer) handleGetInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.GetInfo())
+}
+
+func (s *introspectionServer) handleGetAllocations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.GetAllocations())
+}
+
+func (s *introspectionServer) handleGetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.DrainStatus(mux.Vars(r)["id"]))
+}
+
+func (s *introspectionServer) handleDrainDevice(w http.ResponseWriter, r *http.Request) {
+	status, err := s.dp.DrainDevice(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleUndrainDevice(w http.ResponseWriter, r *http.Request) {
+	status, err := s.dp.UndrainDevice(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleGetNodeDrainStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.NodeDrainStatus())
+}
+
+func (s *introspectionServer) handleDrain(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.Drain())
+}
+
+func (s *introspectionServer) handleUncordon(w http.ResponseWriter, r *http.Request) {
+	status, err := s.dp.Uncordon()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleGetCores(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.PreferredCoreSets())
+}
+
+func (s *introspectionServer) handleGetHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.HealthFailures())
+}
+
+func (s *introspectionServer) handleSimulateAllocate(w http.ResponseWriter, r *http.Request) {
+	count, err := strconv.Atoi(mux.Vars(r)["count"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid count: %v", err), http.StatusBadRequest)
+		return
+	}
+	var mustInclude []string
+	if value := r.URL.Query().Get("mustInclude"); value != "" {
+		mustInclude = strings.Split(value, ",")
+	}
+	result, err := s.dp.SimulateAllocate(r.Context(), count, mustInclude, r.URL.Query().Get("selector"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (s *introspectionServer) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.MaintenanceStatus())
+}
+
+func (s *introspectionServer) handleStartMaintenance(w http.ResponseWriter, r *http.Request) {
+	duration, err := time.ParseDuration(mux.Vars(r)["duration"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid duration: %v", err), http.StatusBadRequest)
+		return
+	}
+	status, err := s.dp.StartMaintenance(duration)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleEndMaintenance(w http.ResponseWriter, r *http.Request) {
+	status, err := s.dp.EndMaintenance()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleGetStrategy(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.StrategyStatus())
+}
+
+func (s *introspectionServer) handleSetStrategy(w http.ResponseWriter, r *http.Request) {
+	status, err := s.dp.SetStrategy(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *introspectionServer) handleGetSchedulability(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dp.GetSchedulability())
+}
+
+// checkAPIVersion answers requests from clients expecting a newer API
+// version than this Device Plugin serves with an Unsupported response.
+func checkAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requested := r.Header.Get(introspectionAPIVersionHeader); requested != "" {
+			v, err := strconv.Atoi(requested)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s header: %v", introspectionAPIVersionHeader, err), http.StatusBadRequest)
+				return
+			}
+			if v > introspectionAPIVersion {
+				writeUnsupported(w, fmt.Sprintf("API version %d is not supported", v))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnsupported(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotImplemented)
+	json.NewEncoder(w).Encode(Unsupported{
+		Reason:     reason,
+		APIVersion: introspectionAPIVersion,
+		Features:   introspectionFeatures,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+	}
+}
+
+// GetAllocations returns the devices handed out by Allocate.
+func (dp *dpServer) GetAllocations() []Allocation {
+	return dp.allocations.list()
+}
+
+// GetInfo returns the introspection summary of the Device Plugin.
+func (dp *dpServer) GetInfo() Info {
+	dp.registrationMutex.Lock()
+	registration := dp.registration
+	dp.registrationMutex.Unlock()
+
+	freezeFile := ""
+	if dp.freeze != nil {
+		freezeFile = dp.freeze.path
+	}
+	readinessFile := ""
+	if dp.readiness != nil {
+		readinessFile = dp.readiness.path
+	}
+	warmFirstWindow := ""
+	if dp.warm != nil {
+		warmFirstWindow = dp.warm.window.String()
+	}
+
+	return Info{
+		APIVersion:   introspectionAPIVersion,
+		Features:     introspectionFeatures,
+		ResourceName: dp.resourceName,
+		Build: BuildInfo{
+			Version: version.Version,
+			Commit:  version.Commit,
+		},
+		Config: ConfigSummary{
+			PollInterval:             dp.pollInterval.String(),
+			MinPollInterval:          dp.minPollInterval.String(),
+			MaxReconcileBackoff:      dp.maxReconcileBackoff.String(),
+			AllocateLatencyThreshold: dp.allocateLatencyThreshold.String(),
+			CoalesceWindow:           dp.coalesceWindow.String(),
+			MaxDevices:               dp.maxDevices,
+			SortOrder:                string(dp.sortOrder),
+			AllocationStrategy:       string(dp.allocationStrategy()),
+			HealthSources:            len(dp.healthSources),
+			HealthWorkers:            dp.healthWorkers,
+			RecoveryHysteresis:       dp.recoveryHysteresis.String(),
+			TrackStaleAllocations:    dp.trackStaleAllocations,
+			AllocationTTL:            dp.allocationTTL.String(),
+			ReclaimExpired:           dp.reclaimExpired,
+			NumaEnv:                  dp.numaEnv,
+			PayloadEnv:               dp.payloadEnv,
+			SelfTest:                 dp.selfTestEnabled,
+			LogSampling:              int(dp.logSampler.every),
+			ExpectedDriver:           dp.expectedDriver,
+			MinVendorVersion:         dp.minVendorVersion,
+			RequiredCapabilities:     dp.requiredCapabilities,
+			ReservedDevices:          dp.reservedIDs,
+			ReservedPercent:          dp.reservedPercent,
+			FreezeFile:               freezeFile,
+			ReadinessFile:            readinessFile,
+			AdvertiseUnchanged:       dp.advertiseUnchanged,
+			WarmFirstWindow:          warmFirstWindow,
+			HealthCacheTTL:           dp.healthCacheTTL.String(),
+		},
+		Registration: registration,
+	}
+}
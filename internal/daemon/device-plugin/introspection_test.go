@@ -0,0 +1,151 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"github.com/openshift/dpu-operator/pkgs/version"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func getInfo(dp *dpServer) Info {
+	rec := httptest.NewRecorder()
+	dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/info", nil))
+	Expect(rec.Code).To(Equal(http.StatusOK))
+
+	var info Info
+	Expect(json.NewDecoder(rec.Body).Decode(&info)).To(Succeed())
+	return info
+}
+
+var _ = Describe("Introspection", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(GinkgoT().TempDir()))
+	})
+
+	It("should report the build info", func() {
+		info := getInfo(dp)
+		Expect(info.ResourceName).To(Equal(DpuResourceName))
+		Expect(info.Build.Version).To(Equal(version.Version))
+		Expect(info.Build.Commit).To(Equal(version.Commit))
+	})
+
+	It("should advertise the supported API version and features", func() {
+		info := getInfo(dp)
+		Expect(info.APIVersion).To(Equal(introspectionAPIVersion))
+		Expect(info.Features).To(ContainElements("info", "drain"))
+	})
+
+	Context("version skew", func() {
+		serve := func(req *http.Request) (int, Unsupported) {
+			rec := httptest.NewRecorder()
+			dp.introspection.Handler.ServeHTTP(rec, req)
+
+			var unsupported Unsupported
+			if rec.Code == http.StatusNotImplemented {
+				Expect(json.NewDecoder(rec.Body).Decode(&unsupported)).To(Succeed())
+			}
+			return rec.Code, unsupported
+		}
+
+		It("should serve clients of the current API version", func() {
+			req := httptest.NewRequest(http.MethodGet, "/info", nil)
+			req.Header.Set(introspectionAPIVersionHeader, strconv.Itoa(introspectionAPIVersion))
+			code, _ := serve(req)
+			Expect(code).To(Equal(http.StatusOK))
+		})
+
+		It("should answer a newer API version with an unsupported response", func() {
+			req := httptest.NewRequest(http.MethodGet, "/info", nil)
+			req.Header.Set(introspectionAPIVersionHeader, strconv.Itoa(introspectionAPIVersion+1))
+			code, unsupported := serve(req)
+			Expect(code).To(Equal(http.StatusNotImplemented))
+			Expect(unsupported.Reason).To(ContainSubstring("API version 2 is not supported"))
+			Expect(unsupported.APIVersion).To(Equal(introspectionAPIVersion))
+		})
+
+		It("should answer an unknown feature with an unsupported response", func() {
+			code, unsupported := serve(httptest.NewRequest(http.MethodGet, "/devices/dev0/quarantine", nil))
+			Expect(code).To(Equal(http.StatusNotImplemented))
+			Expect(unsupported.Reason).To(ContainSubstring("/devices/dev0/quarantine is not supported"))
+			Expect(unsupported.Features).To(Equal(introspectionFeatures))
+		})
+	})
+
+	It("should report the default configuration", func() {
+		config := getInfo(dp).Config
+		Expect(config.PollInterval).To(Equal(defaultPollInterval.String()))
+		Expect(config.MaxDevices).To(BeZero())
+		Expect(config.TrackStaleAllocations).To(BeTrue())
+		Expect(config.SelfTest).To(BeTrue())
+		Expect(config.LogSampling).To(Equal(1))
+	})
+
+	It("should report a non-default configuration", func() {
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(GinkgoT().TempDir()),
+			WithMaxReconcileBackoff(time.Minute),
+			WithAllocateLatencyThreshold(0),
+			WithMaxDevices(8),
+			WithDeviceSortOrder(SortByNumaThenID),
+			WithAllocationStrategy(StrategySpread),
+			WithHealthSource(NewReachabilityHealthSource("10.0.0.1", time.Minute)),
+			WithHealthWorkers(4),
+			WithStaleAllocationTracking(false),
+			WithNumaEnv(true),
+			WithSelfTest(false),
+			WithLogSampling(10),
+			WithCoalesceWindow(time.Second),
+		)
+
+		Expect(getInfo(dp).Config).To(Equal(ConfigSummary{
+			PollInterval:             defaultPollInterval.String(),
+			MinPollInterval:          defaultMinPollInterval.String(),
+			MaxReconcileBackoff:      "1m0s",
+			AllocateLatencyThreshold: "0s",
+			CoalesceWindow:           "1s",
+			MaxDevices:               8,
+			SortOrder:                "numa",
+			AllocationStrategy:       "spread",
+			HealthSources:            1,
+			HealthWorkers:            4,
+			RecoveryHysteresis:       "0s",
+			TrackStaleAllocations:    false,
+			AllocationTTL:            "0s",
+			NumaEnv:                  true,
+			SelfTest:                 false,
+			LogSampling:              10,
+			HealthCacheTTL:           (defaultPollInterval / 2).String(),
+		}))
+	})
+
+	It("should expose the build info metric with the same labels", func() {
+		families, err := metrics.Registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+
+		found := false
+		for _, family := range families {
+			if family.GetName() != "dpu_device_plugin_build_info" {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["version"] == version.Version && labels["commit"] == version.Commit {
+					Expect(m.GetGauge().GetValue()).To(Equal(1.0))
+					found = true
+				}
+			}
+		}
+		Expect(found).To(BeTrue())
+	})
+})
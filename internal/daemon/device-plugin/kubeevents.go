@@ -0,0 +1,141 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"k8s.io/utils/clock"
+)
+
+// defaultKubeEventInterval is how often a device's health changes are
+// recorded at most.
+const defaultKubeEventInterval = 5 * time.Minute
+
+// Reasons of the events recorded by a KubeEventSink.
+const (
+	ReasonDeviceUnhealthy = "DeviceUnhealthy"
+	ReasonDeviceRecovered = "DeviceRecovered"
+)
+
+// KubeEventSink records the health changes of devices as Kubernetes events on
+// an object, e.g. the Node, so that they show up in "kubectl describe" and
+// alerting. Allocations aren't recorded. A device's health changes are
+// recorded at most once per interval: the latest change held back meanwhile
+// is recorded once the interval passed, with the number of changes it
+// stands for, so that a flapping device doesn't flood the events but its
+// final health is still recorded.
+type KubeEventSink struct {
+	recorder record.EventRecorder
+	object   runtime.Object
+	interval time.Duration
+	clock    clock.WithDelayedExecution
+
+	mu      sync.Mutex
+	devices map[string]*deviceEvents
+}
+
+// deviceEvents is the rate limiting state of a device.
+type deviceEvents struct {
+	recordedAt time.Time
+	pending    *Event
+	held       int
+	timer      clock.Timer
+}
+
+func NewKubeEventSink(recorder record.EventRecorder, object runtime.Object, opts ...func(*KubeEventSink)) *KubeEventSink {
+	s := &KubeEventSink{
+		recorder: recorder,
+		object:   object,
+		interval: defaultKubeEventInterval,
+		clock:    clock.RealClock{},
+		devices:  make(map[string]*deviceEvents),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithKubeEventInterval sets how often a device's health changes are recorded
+// at most.
+func WithKubeEventInterval(interval time.Duration) func(*KubeEventSink) {
+	return func(s *KubeEventSink) {
+		s.interval = interval
+	}
+}
+
+// NodeReference refers to the Node as Kubelet does, so that the events show
+// up in "kubectl describe node".
+func NodeReference(nodeName string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{Kind: "Node", Name: nodeName, UID: types.UID(nodeName)}
+}
+
+func (s *KubeEventSink) Send(event Event) {
+	if event.Type != EventHealthChanged {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	for _, id := range event.Devices {
+		change := event
+		change.Devices = []string{id}
+		d, ok := s.devices[id]
+		if !ok {
+			d = &deviceEvents{}
+			s.devices[id] = d
+		}
+		if elapsed := now.Sub(d.recordedAt); d.recordedAt.IsZero() || elapsed >= s.interval {
+			s.record(d, change, now, 0)
+			continue
+		}
+		d.pending = &change
+		d.held++
+		if d.timer == nil {
+			d.timer = s.clock.AfterFunc(s.interval-now.Sub(d.recordedAt), func() { s.flush(id) })
+		}
+	}
+}
+
+// flush records the latest change held back for the device, when the interval
+// passed.
+func (s *KubeEventSink) flush(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.devices[id]
+	d.timer = nil
+	if d.pending != nil {
+		s.record(d, *d.pending, d.recordedAt.Add(s.interval), d.held-1)
+	}
+}
+
+// record records the change, mentioning the earlier changes it was held back
+// with.
+func (s *KubeEventSink) record(d *deviceEvents, change Event, now time.Time, skipped int) {
+	d.recordedAt = now
+	d.pending = nil
+	d.held = 0
+
+	id := change.Devices[0]
+	eventType, reason := corev1.EventTypeNormal, ReasonDeviceRecovered
+	message := fmt.Sprintf("Device %s of resource %s recovered", id, change.Resource)
+	if change.Health != pluginapi.Healthy {
+		eventType, reason = corev1.EventTypeWarning, ReasonDeviceUnhealthy
+		message = fmt.Sprintf("Device %s of resource %s is unhealthy", id, change.Resource)
+		if change.Reason != "" {
+			message += ": " + change.Reason
+		}
+	}
+	if skipped > 0 {
+		message += fmt.Sprintf(" (%d earlier health changes not recorded)", skipped)
+	}
+	s.recorder.Event(s.object, eventType, reason, message)
+}
@@ -0,0 +1,81 @@
+package deviceplugin
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"k8s.io/client-go/tools/record"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Kubernetes event sink", func() {
+	var (
+		recorder *record.FakeRecorder
+		clock    *clocktesting.FakeClock
+		sink     *KubeEventSink
+	)
+
+	healthChange := func(id, health, reason string) Event {
+		return Event{Type: EventHealthChanged, Resource: DpuResourceName, Devices: []string{id}, Health: health, Reason: reason}
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		clock = clocktesting.NewFakeClock(time.Now())
+		sink = NewKubeEventSink(recorder, NodeReference("worker-0"), WithKubeEventInterval(time.Minute))
+		sink.clock = clock
+	})
+
+	It("should record unhealthy and recovered devices", func() {
+		sink.Send(healthChange("dev0", pluginapi.Unhealthy, "ping 10.0.0.1 via ens1f0v0 failed"))
+		Expect(recorder.Events).To(Receive(Equal("Warning DeviceUnhealthy Device dev0 of resource openshift.io/dpu is unhealthy: ping 10.0.0.1 via ens1f0v0 failed")))
+
+		clock.Step(time.Minute)
+		sink.Send(healthChange("dev0", pluginapi.Healthy, ""))
+		Expect(recorder.Events).To(Receive(Equal("Normal DeviceRecovered Device dev0 of resource openshift.io/dpu recovered")))
+	})
+
+	It("should not record allocations", func() {
+		sink.Send(Event{Type: EventAllocated, Resource: DpuResourceName, Devices: []string{"dev0"}})
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should hold back the changes of a flapping device until the interval passed", func() {
+		sink.Send(healthChange("dev0", pluginapi.Unhealthy, ""))
+		Expect(recorder.Events).To(Receive(ContainSubstring("DeviceUnhealthy")))
+
+		for i := 0; i < 3; i++ {
+			clock.Step(time.Second)
+			sink.Send(healthChange("dev0", pluginapi.Healthy, ""))
+			clock.Step(time.Second)
+			sink.Send(healthChange("dev0", pluginapi.Unhealthy, "link down"))
+		}
+		sink.Send(healthChange("dev1", pluginapi.Unhealthy, ""))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Device dev1")))
+		Expect(recorder.Events).NotTo(Receive())
+
+		clock.Step(time.Minute)
+		Eventually(recorder.Events).Should(Receive(Equal("Warning DeviceUnhealthy Device dev0 of resource openshift.io/dpu is unhealthy: link down (5 earlier health changes not recorded)")))
+	})
+
+	It("should receive the health changes of the Device Plugin with their reason", func() {
+		dp := newTestDevicePlugin("dev0", "dev1")
+		WithEventSink(sink)(dp)
+		dp.healthFailures = map[string]string{"dev1": "link down"}
+
+		old := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Healthy},
+		}
+		new := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Unhealthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Unhealthy},
+		}
+		dp.sendHealthChanges(&old, &new)
+		Expect(recorder.Events).To(Receive(HaveSuffix("Device dev0 of resource openshift.io/dpu is unhealthy")))
+		Expect(recorder.Events).To(Receive(HaveSuffix("Device dev1 of resource openshift.io/dpu is unhealthy: link down")))
+	})
+})
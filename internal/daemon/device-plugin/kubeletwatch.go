@@ -0,0 +1,78 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"google.golang.org/grpc"
+)
+
+// watchKubelet re-registers the Device Plugin whenever Kubelet recreates its
+// socket, which it does when it restarts. A restarted Kubelet has forgotten
+// about all plugins and removed their sockets, so without this the resource
+// silently disappears from the node capacity. The watch ends when the Device
+// Plugin is stopped.
+func (dp *dpServer) watchKubelet() {
+	kubeletSocket := dp.pathManager.KubeletEndPoint()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		dp.log.Error(err, "Failed to watch the Kubelet socket, Kubelet restarts won't be noticed")
+		return
+	}
+	if err := watcher.Add(filepath.Dir(kubeletSocket)); err != nil {
+		watcher.Close()
+		dp.log.Error(err, "Failed to watch the Kubelet socket, Kubelet restarts won't be noticed", "path", kubeletSocket)
+		return
+	}
+
+	dp.kubeletWatchWg.Add(1)
+	go func() {
+		defer dp.kubeletWatchWg.Done()
+		defer watcher.Close()
+		for {
+			select {
+			case <-dp.stopCh:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Name == kubeletSocket && event.Has(fsnotify.Create) {
+					dp.reregister()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				dp.log.Error(err, "Error while watching the Kubelet socket")
+			}
+		}
+	}()
+}
+
+// reregister registers with a restarted Kubelet. The gRPC server and the
+// advertised devices are kept, only the plugin socket is listened on again if
+// Kubelet removed it, Kubelet then gets the devices from a new ListAndWatch.
+func (dp *dpServer) reregister() {
+	dp.log.Info("Kubelet socket was recreated, registering again", "kubeletSocket", dp.pathManager.KubeletEndPoint())
+	dp.setRegistered(false)
+
+	if _, err := os.Stat(dp.pluginEndpoint); os.IsNotExist(err) {
+		lis, err := dp.listenWithRetry(dp.pluginEndpoint)
+		if err != nil {
+			dp.log.Error(err, "Failed to listen on the Device Plugin socket again", "pluginEndpoint", dp.pluginEndpoint)
+			return
+		}
+		go func() {
+			if err := dp.grpcServer.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+				dp.serveFailed(fmt.Errorf("failed to serve the recreated socket: %v", err))
+			}
+		}()
+	}
+
+	if err := dp.registerWithKubelet(); err != nil {
+		dp.log.Error(err, "Failed to register again with the restarted Kubelet")
+	}
+}
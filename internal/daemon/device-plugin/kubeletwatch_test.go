@@ -0,0 +1,83 @@
+package deviceplugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Kubelet restarts", func() {
+	var (
+		dp      *dpServer
+		kubelet *fakeKubelet
+		server  *grpc.Server
+		socket  net.Listener
+	)
+
+	// startKubelet creates the Kubelet socket, the way a starting Kubelet does.
+	startKubelet := func() {
+		var err error
+		socket, err = net.Listen("unix", dp.pathManager.KubeletEndPoint())
+		Expect(err).NotTo(HaveOccurred())
+		server = grpc.NewServer()
+		pluginapi.RegisterRegistrationServer(server, kubelet)
+		go server.Serve(socket)
+	}
+
+	// stopKubelet removes the Kubelet socket right away, the server may not
+	// have started serving it yet.
+	stopKubelet := func() {
+		socket.Close()
+		server.Stop()
+	}
+
+	// restartKubelet removes all sockets and creates the Kubelet socket again.
+	restartKubelet := func() {
+		stopKubelet()
+		Expect(os.Remove(dp.pluginEndpoint)).To(Succeed())
+		startKubelet()
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(root), WithRegisterVerification(time.Second, 1),
+			WithRegisterThrottle(0, time.Minute, 10))
+		kubelet = &fakeKubelet{dp: dp, contactFrom: 1}
+		Expect(os.MkdirAll(filepath.Dir(dp.pathManager.KubeletEndPoint()), 0o755)).To(Succeed())
+		startKubelet()
+		DeferCleanup(func() { stopKubelet() })
+
+		lis, err := net.Listen("unix", dp.pluginEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		go dp.grpcServer.Serve(lis)
+		dp.watchKubelet()
+	})
+
+	It("should register again and serve the recreated plugin socket", func() {
+		restartKubelet()
+		Eventually(kubelet.count).Should(Equal(1))
+		Eventually(dp.pluginEndpoint).Should(BeAnExistingFile())
+		Eventually(func() bool { return dp.GetInfo().Registration != nil }).Should(BeTrue())
+
+		restartKubelet()
+		Eventually(kubelet.count).Should(Equal(2))
+		Expect(dp.Stop()).To(Succeed())
+	})
+
+	It("should stop watching once stopped", func() {
+		Expect(dp.Stop()).To(Succeed())
+		stopKubelet()
+		startKubelet()
+		Consistently(kubelet.count, 300*time.Millisecond).Should(BeZero())
+	})
+})
@@ -0,0 +1,177 @@
+package deviceplugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeDeviceService is a vendor plugin DeviceService serving the given
+// devices through the unary GetDevices only, like the simplest vendor plugins.
+type fakeDeviceService struct {
+	pb.DeviceServiceClient
+
+	mu      sync.Mutex
+	devices []*pb.Device
+}
+
+func (f *fakeDeviceService) GetDevices(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.DeviceListResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &pb.DeviceListResponse{Devices: map[string]*pb.Device{}}
+	for _, d := range f.devices {
+		resp.Devices[d.ID] = d
+	}
+	return resp, nil
+}
+
+func (f *fakeDeviceService) GetDevicesPage(ctx context.Context, in *pb.DeviceListRequest, opts ...grpc.CallOption) (*pb.DeviceListResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDevicesPage not implemented")
+}
+
+func (f *fakeDeviceService) GetDevicesStream(ctx context.Context, in *pb.DeviceListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.DeviceListResponse], error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDevicesStream not implemented")
+}
+
+func (f *fakeDeviceService) SetNumVfs(ctx context.Context, in *pb.VfCount, opts ...grpc.CallOption) (*pb.VfCount, error) {
+	return in, nil
+}
+
+func (f *fakeDeviceService) GetAnnotationTemplate(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.AnnotationTemplate, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAnnotationTemplate not implemented")
+}
+
+func (f *fakeDeviceService) GetAllocateInfo(ctx context.Context, in *pb.AllocateInfoRequest, opts ...grpc.CallOption) (*pb.AllocateInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAllocateInfo not implemented")
+}
+
+func (f *fakeDeviceService) set(devices ...*pb.Device) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices = devices
+}
+
+// Exercises the Device Plugin on top of the real vendor plugin client and
+// device handler, with only the DeviceService faked.
+var _ = Describe("Device Plugin lifecycle", func() {
+	var (
+		dp      *dpServer
+		service *fakeDeviceService
+	)
+
+	// refresh gets the devices from the vendor plugin into the cache, as
+	// ListAndWatch does.
+	refresh := func() *dh.DeviceList {
+		devices, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		dp.setDeviceCache(devices)
+		return devices
+	}
+
+	BeforeEach(func() {
+		service = &fakeDeviceService{}
+		pathManager := *utils.NewPathManager(GinkgoT().TempDir())
+		vsp, err := plugin.NewGrpcPlugin(true, "", nil, plugin.WithPathManager(pathManager), plugin.WithDeviceServiceClient(service))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(vsp.Close)
+		dp = NewDevicePlugin(vsp, true, pathManager, WithHealthCacheTTL(0))
+		Expect(dp.SetupDevices()).To(Succeed())
+	})
+
+	DescribeTable("GetDevices",
+		func(device *pb.Device, expected pluginapi.Device) {
+			service.set(device)
+			Expect(*refresh()).To(Equal(dh.DeviceList{expected.ID: expected}))
+		},
+		Entry("should advertise a device without health as healthy",
+			&pb.Device{ID: "dev0"},
+			pluginapi.Device{ID: "dev0", Health: pluginapi.Healthy}),
+		Entry("should advertise a device the vendor plugin reports unhealthy as unhealthy",
+			&pb.Device{ID: "dev0", Health: "LinkDown"},
+			pluginapi.Device{ID: "dev0", Health: pluginapi.Unhealthy}),
+		Entry("should advertise the NUMA node of a device",
+			&pb.Device{ID: "dev0", Health: "Healthy", NumaNode: proto.Int32(1)},
+			pluginapi.Device{ID: "dev0", Health: pluginapi.Healthy, Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}}}),
+	)
+
+	DescribeTable("Allocate",
+		func(ids []string, env string, code codes.Code) {
+			service.set(&pb.Device{ID: "dev0"}, &pb.Device{ID: "dev1"}, &pb.Device{ID: "dev2", Health: "LinkDown"})
+			refresh()
+
+			resp, err := dp.Allocate(context.Background(), allocateRequest(ids))
+			if code != codes.OK {
+				Expect(status.Code(err)).To(Equal(code))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, env))
+		},
+		Entry("should pass a healthy device", []string{"dev0"}, "dev0", codes.OK),
+		Entry("should pass several devices in order", []string{"dev1", "dev0"}, "dev1,dev0", codes.OK),
+		Entry("should reject an unhealthy device", []string{"dev2"}, "", codes.FailedPrecondition),
+		Entry("should reject an unknown device", []string{"dev3"}, "", codes.NotFound),
+	)
+
+	It("should send the devices sorted by ID", func() {
+		service.set(&pb.Device{ID: "dev1"}, &pb.Device{ID: "dev0", Health: "LinkDown"})
+		stream := &fakeListAndWatchServer{}
+		Expect(dp.sendDevices(stream, refresh())).To(Succeed())
+
+		Expect(stream.sent).To(HaveLen(1))
+		Expect(stream.sent[0].Devices).To(Equal([]*pluginapi.Device{
+			{ID: "dev0", Health: pluginapi.Unhealthy},
+			{ID: "dev1", Health: pluginapi.Healthy},
+		}))
+	})
+
+	DescribeTable("detecting changes",
+		func(after []*pb.Device, changed bool) {
+			service.set(&pb.Device{ID: "dev0"}, &pb.Device{ID: "dev1"})
+			_, hash := dp.advertiseNeeded(refresh(), "")
+
+			service.set(after...)
+			needed, _ := dp.advertiseNeeded(refresh(), hash)
+			Expect(needed).To(Equal(changed))
+		},
+		Entry("should not resend identical devices", []*pb.Device{{ID: "dev1"}, {ID: "dev0"}}, false),
+		Entry("should resend on a health change", []*pb.Device{{ID: "dev0", Health: "LinkDown"}, {ID: "dev1"}}, true),
+		Entry("should resend on an added device", []*pb.Device{{ID: "dev0"}, {ID: "dev1"}, {ID: "dev2"}}, true),
+		Entry("should resend on a removed device", []*pb.Device{{ID: "dev0"}}, true),
+	)
+
+	It("should advertise the devices the vendor plugin adds while watching", func() {
+		service.set(&pb.Device{ID: "dev0"})
+		WithCoalesceWindow(0)(dp)
+		dp.pollInterval = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &lockedListAndWatchServer{ctx: ctx}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(Equal([][]string{{"dev0"}}))
+
+		service.set(&pb.Device{ID: "dev0"}, &pb.Device{ID: "dev1"})
+		dp.triggerUpdate()
+		Eventually(stream.sends).Should(Equal([][]string{{"dev0"}, {"dev0", "dev1"}}))
+	})
+})
@@ -0,0 +1,125 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// MaintenanceStatus reports the state of the maintenance window. While the
+// window is active the advertised health is frozen at its state from when
+// the window started, Suppressed lists the devices whose real health differs.
+type MaintenanceStatus struct {
+	Active     bool       `json:"active"`
+	Start      *time.Time `json:"start,omitempty"`
+	End        *time.Time `json:"end,omitempty"`
+	Suppressed []string   `json:"suppressed,omitempty"`
+}
+
+type maintenanceWindow struct {
+	start, end time.Time
+	// health is the health of every device when the window started.
+	health     map[string]string
+	suppressed []string
+}
+
+// StartMaintenance freezes the advertised health of the devices for the given
+// duration, so that health flaps during maintenance don't churn Kubelet.
+// Starting a new window while one is active only moves its end.
+func (dp *dpServer) StartMaintenance(duration time.Duration) (MaintenanceStatus, error) {
+	if duration <= 0 {
+		return MaintenanceStatus{}, fmt.Errorf("invalid maintenance window duration: %v", duration)
+	}
+
+	now := dp.clock.Now()
+	dp.maintenanceMutex.Lock()
+	if dp.maintenance == nil {
+		devices := dp.cachedDevices()
+		health := make(map[string]string, len(devices))
+		for id, dev := range devices {
+			health[id] = dev.Health
+		}
+		dp.maintenance = &maintenanceWindow{start: now, health: health}
+	}
+	dp.maintenance.end = now.Add(duration)
+	dp.maintenanceMutex.Unlock()
+
+	dp.log.Info("Maintenance window started", "end", now.Add(duration))
+	return dp.MaintenanceStatus(), nil
+}
+
+// EndMaintenance ends the maintenance window early. The real health is
+// advertised right away.
+func (dp *dpServer) EndMaintenance() (MaintenanceStatus, error) {
+	dp.maintenanceMutex.Lock()
+	active := dp.maintenance != nil
+	dp.maintenance = nil
+	dp.maintenanceMutex.Unlock()
+
+	if !active {
+		return MaintenanceStatus{}, fmt.Errorf("no maintenance window is active")
+	}
+
+	dp.log.Info("Maintenance window ended")
+	maintenanceSuppressedDevices.WithLabelValues(dp.resourceName).Set(0)
+	dp.triggerUpdate()
+	return dp.MaintenanceStatus(), nil
+}
+
+func (dp *dpServer) MaintenanceStatus() MaintenanceStatus {
+	dp.maintenanceMutex.Lock()
+	defer dp.maintenanceMutex.Unlock()
+
+	if dp.maintenance == nil {
+		return MaintenanceStatus{}
+	}
+	start, end := dp.maintenance.start, dp.maintenance.end
+	return MaintenanceStatus{
+		Active:     true,
+		Start:      &start,
+		End:        &end,
+		Suppressed: append([]string(nil), dp.maintenance.suppressed...),
+	}
+}
+
+// frozenHealth returns the health to advertise for the given device while
+// a maintenance window is active, and false otherwise. The window is closed
+// once its end has passed.
+func (dp *dpServer) frozenHealth(id string) (string, bool) {
+	dp.maintenanceMutex.Lock()
+	defer dp.maintenanceMutex.Unlock()
+
+	if dp.maintenance == nil {
+		return "", false
+	}
+	if !dp.clock.Now().Before(dp.maintenance.end) {
+		dp.maintenance = nil
+		dp.log.Info("Maintenance window expired")
+		maintenanceSuppressedDevices.WithLabelValues(dp.resourceName).Set(0)
+		return "", false
+	}
+	health, ok := dp.maintenance.health[id]
+	return health, ok
+}
+
+// recordSuppressed records the devices whose real health isn't advertised
+// because of the maintenance window.
+func (dp *dpServer) recordSuppressed(real map[string]pluginapi.Device, advertised map[string]pluginapi.Device) {
+	var suppressed []string
+	for id, dev := range real {
+		if advertised[id].Health != dev.Health && !dp.isDrained(id) {
+			suppressed = append(suppressed, id)
+		}
+	}
+	sort.Strings(suppressed)
+
+	dp.maintenanceMutex.Lock()
+	defer dp.maintenanceMutex.Unlock()
+	if dp.maintenance == nil {
+		return
+	}
+	dp.maintenance.suppressed = suppressed
+	maintenanceSuppressedDevices.WithLabelValues(dp.resourceName).Set(float64(len(suppressed)))
+}
@@ -0,0 +1,96 @@
+package deviceplugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Maintenance window", func() {
+	var (
+		dp    *dpServer
+		clock *clocktesting.FakeClock
+	)
+
+	withHealth := func(health map[string]string) *dh.DeviceList {
+		devices := make(dh.DeviceList)
+		for id, h := range health {
+			devices[id] = pluginapi.Device{ID: id, Health: h}
+		}
+		return &devices
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		clock = clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+	})
+
+	It("should freeze the advertised health during the window and reconcile after", func() {
+		_, err := dp.StartMaintenance(time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		real := withHealth(map[string]string{"dev0": pluginapi.Unhealthy, "dev1": pluginapi.Healthy})
+		advertised := *dp.advertisedDevices(real)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Healthy))
+		Expect(dp.MaintenanceStatus().Suppressed).To(Equal([]string{"dev0"}))
+		Expect(gaugeValue(maintenanceSuppressedDevices.WithLabelValues(dp.resourceName))).To(Equal(1.0))
+
+		clock.SetTime(clock.Now().Add(time.Hour))
+		advertised = *dp.advertisedDevices(real)
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(dp.MaintenanceStatus().Active).To(BeFalse())
+		Expect(gaugeValue(maintenanceSuppressedDevices.WithLabelValues(dp.resourceName))).To(Equal(0.0))
+	})
+
+	It("should advertise the real health once ended early", func() {
+		_, err := dp.StartMaintenance(time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dp.EndMaintenance()
+		Expect(err).NotTo(HaveOccurred())
+
+		advertised := *dp.advertisedDevices(withHealth(map[string]string{"dev0": pluginapi.Unhealthy}))
+		Expect(advertised["dev0"].Health).To(Equal(pluginapi.Unhealthy))
+
+		_, err = dp.EndMaintenance()
+		Expect(err).To(MatchError("no maintenance window is active"))
+	})
+
+	It("should still apply drains during the window", func() {
+		_, err := dp.StartMaintenance(time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dp.DrainDevice("dev1")
+		Expect(err).NotTo(HaveOccurred())
+
+		advertised := advertisedCache(dp)
+		Expect(advertised["dev1"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect(dp.MaintenanceStatus().Suppressed).To(BeEmpty())
+	})
+
+	It("should be driven through the introspection socket", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/maintenance?duration=30m", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var status MaintenanceStatus
+		Expect(json.NewDecoder(rec.Body).Decode(&status)).To(Succeed())
+		Expect(status.Active).To(BeTrue())
+		Expect(status.End.Sub(*status.Start)).To(Equal(30 * time.Minute))
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/maintenance?duration=soon", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/maintenance", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(dp.MaintenanceStatus().Active).To(BeFalse())
+	})
+})
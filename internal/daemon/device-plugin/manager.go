@@ -0,0 +1,404 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	dpudevicehandler "github.com/openshift/dpu-operator/internal/daemon/device-handler/dpu-device-handler"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const resourcePrefix = "openshift.io/"
+
+// PoolCollisionPolicy decides what happens to a device matched by more than
+// one pool, which would otherwise be advertised, and allocated, twice.
+type PoolCollisionPolicy string
+
+const (
+	// PoolCollisionReject advertises the device in none of its pools.
+	PoolCollisionReject PoolCollisionPolicy = "reject"
+	// PoolCollisionKeepFirst only advertises the device in the pool whose
+	// resource name sorts first.
+	PoolCollisionKeepFirst PoolCollisionPolicy = "keep-first"
+)
+
+// Manager runs one Device Plugin per resource. Devices are assigned to a
+// resource by the pool the vendor plugin reports for them, or else by the
+// value of a vendor attribute, e.g. "pool", so a new resource is registered
+// with Kubelet as soon as a new pool shows up. Devices without a pool, or
+// with one that isn't a valid resource name, are advertised as the default
+// resource. The attribute may list several pools separated by commas, which
+// is a collision handled by collisionPolicy.
+type Manager struct {
+	log             logr.Logger
+	vsp             plugin.VendorPlugin
+	pathManager     utils.PathManager
+	handler         dh.AttributeHandler
+	attributeKey    string
+	collisionPolicy PoolCollisionPolicy
+	pollInterval    time.Duration
+	newPlugin       func(resourceName string, handler dh.DeviceHandler) DevicePlugin
+	// pluginOpts are applied to the Device Plugin of every resource.
+	pluginOpts []Option
+
+	mu      sync.Mutex
+	groups  map[string]dh.DeviceList
+	plugins map[string]DevicePlugin
+	stopCh  chan struct{}
+	// stopped is set by Stop, no Device Plugin is started afterwards.
+	stopped  bool
+	stopOnce sync.Once
+	// failed receives the first error a Device Plugin stopped serving with.
+	failed chan error
+	// collisions are the devices last seen in more than one pool.
+	collisions map[string]bool
+	// vendorErr is the error of the last failed reconcile, until one
+	// succeeds. It's returned to every Device Plugin, which keeps its
+	// advertised devices and reports the vendor plugin as unreachable.
+	vendorErr error
+}
+
+// resourceDeviceHandler serves the devices of a single resource of a Manager
+// to the Device Plugin of that resource.
+type resourceDeviceHandler struct {
+	m            *Manager
+	resourceName string
+}
+
+func (h *resourceDeviceHandler) SetupDevices() error {
+	// Setting up the devices is done once by the Manager.
+	return nil
+}
+
+func (h *resourceDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	if h.m.vendorErr != nil {
+		return nil, h.m.vendorErr
+	}
+	devices := make(dh.DeviceList, len(h.m.groups[h.resourceName]))
+	for id, dev := range h.m.groups[h.resourceName] {
+		devices[id] = dev
+	}
+	return &devices, nil
+}
+
+func (h *resourceDeviceHandler) GetDeviceCapabilities(id string) []string {
+	if handler, ok := h.m.handler.(dh.CapabilityHandler); ok {
+		return handler.GetDeviceCapabilities(id)
+	}
+	return nil
+}
+
+func (h *resourceDeviceHandler) GetDeviceAttributes(id string) map[string]string {
+	return h.m.handler.GetDeviceAttributes(id)
+}
+
+func (h *resourceDeviceHandler) GetDeviceLocalityGroup(id string) string {
+	if handler, ok := h.m.handler.(dh.LocalityHandler); ok {
+		return handler.GetDeviceLocalityGroup(id)
+	}
+	return ""
+}
+
+func (h *resourceDeviceHandler) GetPhysicalDevice(id string) string {
+	if handler, ok := h.m.handler.(dh.ReplicaHandler); ok {
+		return handler.GetPhysicalDevice(id)
+	}
+	return ""
+}
+
+func (h *resourceDeviceHandler) GetDeviceReplicas(physical string) int {
+	if handler, ok := h.m.handler.(dh.ReplicaHandler); ok {
+		return handler.GetDeviceReplicas(physical)
+	}
+	return 0
+}
+
+// Option configures a Device Plugin, see the With functions of this package.
+type Option = func(*dpServer)
+
+// WithDevicePluginOptions applies opts to the Device Plugin of every
+// resource. The device handler, resource name and socket the Manager sets
+// take precedence.
+func WithDevicePluginOptions(opts ...Option) func(*Manager) {
+	return func(m *Manager) {
+		m.pluginOpts = append(m.pluginOpts, opts...)
+	}
+}
+
+// WithPoolCollisionPolicy sets what happens to devices matched by more than
+// one pool, PoolCollisionReject by default.
+func WithPoolCollisionPolicy(policy PoolCollisionPolicy) func(*Manager) {
+	return func(m *Manager) {
+		m.collisionPolicy = policy
+	}
+}
+
+// NewManager creates a Manager assigning devices to resources by the pool the
+// vendor plugin reports, falling back to the attributeKey attribute unless
+// it's empty.
+func NewManager(vsp plugin.VendorPlugin, dpuMode bool, pm utils.PathManager, attributeKey string, opts ...func(*Manager)) *Manager {
+	m := &Manager{
+		log:             ctrl.Log.WithName("DevicePluginManager"),
+		vsp:             vsp,
+		pathManager:     pm,
+		handler:         dpudevicehandler.NewDpuDeviceHandler(vsp, dpudevicehandler.WithDpuMode(dpuMode), dpudevicehandler.WithPathManager(pm)),
+		attributeKey:    attributeKey,
+		collisionPolicy: PoolCollisionReject,
+		pollInterval:    defaultPollInterval,
+		groups:          make(map[string]dh.DeviceList),
+		plugins:         make(map[string]DevicePlugin),
+		stopCh:          make(chan struct{}),
+		failed:          make(chan error, 1),
+		collisions:      make(map[string]bool),
+	}
+	m.newPlugin = m.newDevicePlugin
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// newDevicePlugin creates the Device Plugin of a resource. Only the default
+// resource keeps the well-known sockets, the others get theirs suffixed with
+// the pool name.
+func (m *Manager) newDevicePlugin(resourceName string, handler dh.DeviceHandler) DevicePlugin {
+	opts := append(slices.Clone(m.pluginOpts), WithDeviceHandler(handler))
+	if resourceName == DpuResourceName {
+		opts = append(opts, WithResourceName(DpuResourceName, m.pathManager.PluginEndpoint()))
+	} else {
+		suffix := strings.TrimPrefix(resourceName, resourcePrefix)
+		endpoint := m.pathManager.PluginEndpoint()
+		introspection := m.pathManager.DevicePluginIntrospectionPath()
+		diagnostics := m.pathManager.DevicePluginDiagnosticsPath()
+		opts = append(opts,
+			WithResourceName(resourceName, strings.TrimSuffix(endpoint, ".sock")+"-"+suffix+".sock"),
+			WithIntrospectionPath(strings.TrimSuffix(introspection, ".sock")+"-"+suffix+".sock"),
+			WithDiagnosticsPath(filepath.Join(filepath.Dir(diagnostics), "diagnostics-"+suffix+".json")),
+		)
+	}
+	return NewDevicePlugin(m.vsp, false, m.pathManager, opts...)
+}
+
+// poolsOf returns the pools of a device, as reported by the vendor plugin or
+// else as listed in its attribute.
+func (m *Manager) poolsOf(id string) string {
+	if handler, ok := m.handler.(dh.PoolHandler); ok {
+		if pool := handler.GetDevicePool(id); pool != "" {
+			return pool
+		}
+	}
+	if m.attributeKey == "" {
+		return ""
+	}
+	return m.handler.GetDeviceAttributes(id)[m.attributeKey]
+}
+
+// resourceNamesFor returns the sorted resources of a device in the given
+// pools, more than one when the device is matched by several pools.
+func (m *Manager) resourceNamesFor(value string) []string {
+	seen := make(map[string]bool)
+	var resourceNames []string
+	for _, pool := range strings.Split(value, ",") {
+		pool = strings.TrimSpace(pool)
+		if pool == "" {
+			continue
+		}
+		resourceName := resourcePrefix + pool
+		if err := validateResourceName(resourceName); err != nil || strings.Contains(pool, "/") {
+			m.log.Info("Ignoring invalid pool", "pool", pool)
+			continue
+		}
+		if !seen[resourceName] {
+			seen[resourceName] = true
+			resourceNames = append(resourceNames, resourceName)
+		}
+	}
+	if len(resourceNames) == 0 {
+		return []string{DpuResourceName}
+	}
+	sort.Strings(resourceNames)
+	return resourceNames
+}
+
+func (m *Manager) SetupDevices() error {
+	return m.handler.SetupDevices()
+}
+
+// Listen listens on the socket of the default resource, so that it exists
+// once Listen returns as with a single Device Plugin. The Device Plugins of
+// the other resources listen when their resource is discovered.
+func (m *Manager) Listen() (net.Listener, error) {
+	m.mu.Lock()
+	p, ok := m.plugins[DpuResourceName]
+	if !ok {
+		p = m.newPlugin(DpuResourceName, &resourceDeviceHandler{m: m, resourceName: DpuResourceName})
+		m.plugins[DpuResourceName] = p
+	}
+	m.mu.Unlock()
+	return p.Listen()
+}
+
+// Serve serves the default resource on the listener returned by Listen and
+// discovers the other resources until Stop is called.
+func (m *Manager) Serve(lis net.Listener) error {
+	m.mu.Lock()
+	p := m.plugins[DpuResourceName]
+	m.mu.Unlock()
+	if p == nil {
+		return fmt.Errorf("Manager.Serve called before Listen")
+	}
+	go func() {
+		if err := p.Serve(lis); err != nil {
+			m.pluginFailed(DpuResourceName, err)
+		}
+	}()
+	return m.ListenAndServe()
+}
+
+// ListenAndServe discovers the resources until Stop is called, or returns the
+// error of the first Device Plugin that stopped serving, as Kubelet can't
+// reach that resource anymore.
+func (m *Manager) ListenAndServe() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		if err := m.reconcile(ctx); err != nil {
+			m.log.Error(err, "Failed to reconcile resources")
+		}
+		select {
+		case <-m.stopCh:
+			return nil
+		case err := <-m.failed:
+			return err
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+// pluginFailed reports a Device Plugin that stopped serving to ListenAndServe.
+func (m *Manager) pluginFailed(resourceName string, err error) {
+	m.log.Error(err, "Device Plugin failed", "resourceName", resourceName)
+	select {
+	case m.failed <- fmt.Errorf("Device Plugin of %s failed: %v", resourceName, err):
+	default:
+	}
+}
+
+// reconcile groups the devices by resource and starts a Device Plugin for
+// every resource seen for the first time. Resources whose devices are all
+// gone keep running with no devices, as Kubelet keeps them registered.
+// Devices rejected for being in several pools are reported as an error once
+// the other devices have been updated. While the vendor plugin can't be
+// reached, the groups are kept and every resource gets the error.
+func (m *Manager) reconcile(ctx context.Context) error {
+	devices, err := m.handler.GetDevices(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to get devices: %v", err)
+		m.mu.Lock()
+		m.vendorErr = err
+		m.mu.Unlock()
+		return err
+	}
+
+	groups := make(map[string]dh.DeviceList)
+	collisions := make(map[string]bool)
+	var rejected []string
+	for _, id := range sortedDeviceIDs(devices) {
+		resourceNames := m.resourceNamesFor(m.poolsOf(id))
+		if len(resourceNames) > 1 {
+			collisions[id] = true
+			if !m.collisions[id] {
+				m.log.Error(fmt.Errorf("device %s is matched by pools %s", id, strings.Join(resourceNames, ", ")), "Device matched by more than one pool", "policy", m.collisionPolicy)
+			}
+			if m.collisionPolicy != PoolCollisionKeepFirst {
+				rejected = append(rejected, id)
+				continue
+			}
+		}
+		resourceName := resourceNames[0]
+		if groups[resourceName] == nil {
+			groups[resourceName] = make(dh.DeviceList)
+		}
+		groups[resourceName][id] = (*devices)[id]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups = groups
+	m.collisions = collisions
+	m.vendorErr = nil
+	for resourceName := range groups {
+		if _, ok := m.plugins[resourceName]; ok || m.stopped {
+			continue
+		}
+		m.log.Info("Starting Device Plugin for new resource", "resourceName", resourceName, "devices", len(groups[resourceName]))
+		p := m.newPlugin(resourceName, &resourceDeviceHandler{m: m, resourceName: resourceName})
+		m.plugins[resourceName] = p
+		go func() {
+			if err := p.ListenAndServe(); err != nil {
+				m.pluginFailed(resourceName, err)
+			}
+		}()
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("not advertising devices matched by more than one pool: %s", strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// Resources returns the resources a Device Plugin was started for.
+func (m *Manager) Resources() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resources := make([]string, 0, len(m.plugins))
+	for resourceName := range m.plugins {
+		resources = append(resources, resourceName)
+	}
+	return resources
+}
+
+// Stop stops the Device Plugins of every resource, it may be called more than
+// once. They are stopped without holding the lock, which their ListAndWatch
+// streams take to get their devices.
+func (m *Manager) Stop() error {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+
+	m.mu.Lock()
+	m.stopped = true
+	plugins := maps.Clone(m.plugins)
+	m.mu.Unlock()
+
+	var errs []string
+	for _, resourceName := range slices.Sorted(maps.Keys(plugins)) {
+		if err := plugins[resourceName].Stop(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", resourceName, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to stop Device Plugins: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeAttributeHandler serves devices with the given attributes.
+type fakeAttributeHandler struct {
+	attributes map[string]map[string]string
+	// err fails GetDevices, as when the vendor plugin is unreachable.
+	err error
+}
+
+func (h *fakeAttributeHandler) SetupDevices() error {
+	return nil
+}
+
+func (h *fakeAttributeHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	devices := make(dh.DeviceList)
+	for id := range h.attributes {
+		devices[id] = pluginapi.Device{ID: id, Health: pluginapi.Healthy}
+	}
+	return &devices, nil
+}
+
+func (h *fakeAttributeHandler) GetDeviceAttributes(id string) map[string]string {
+	return h.attributes[id]
+}
+
+// fakePoolHandler also serves the pools reported by the vendor plugin.
+type fakePoolHandler struct {
+	fakeAttributeHandler
+	pools map[string]string
+}
+
+func (h *fakePoolHandler) GetDevicePool(id string) string {
+	return h.pools[id]
+}
+
+// fakeDevicePlugin records the handler it was created with.
+type fakeDevicePlugin struct {
+	DevicePlugin
+	handler dh.DeviceHandler
+	stopped bool
+	// serveErr is returned by ListenAndServe.
+	serveErr error
+	// onStop is called by Stop, e.g. to get the devices like a ListAndWatch
+	// stream ending.
+	onStop func()
+}
+
+func (p *fakeDevicePlugin) ListenAndServe() error {
+	return p.serveErr
+}
+
+func (p *fakeDevicePlugin) Listen() (net.Listener, error) {
+	return nil, nil
+}
+
+func (p *fakeDevicePlugin) Stop() error {
+	if p.onStop != nil {
+		p.onStop()
+	}
+	p.stopped = true
+	return nil
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		m       *Manager
+		handler *fakeAttributeHandler
+		plugins map[string]*fakeDevicePlugin
+		mu      sync.Mutex
+	)
+
+	devicesOf := func(resourceName string) []string {
+		mu.Lock()
+		p := plugins[resourceName]
+		mu.Unlock()
+		Expect(p).NotTo(BeNil(), "no Device Plugin for %s", resourceName)
+		devices, err := p.handler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		return sortedDeviceIDs(devices)
+	}
+
+	BeforeEach(func() {
+		handler = &fakeAttributeHandler{attributes: map[string]map[string]string{
+			"dev0": {"pool": "dpu-a"},
+			"dev1": {"pool": "dpu-b"},
+			"dev2": {"pool": "dpu-a", "speed": "100G"},
+			"dev3": {},
+			"dev4": {"pool": "not/valid"},
+		}}
+		plugins = make(map[string]*fakeDevicePlugin)
+		m = NewManager(nil, true, *utils.NewPathManager(GinkgoT().TempDir()), "pool")
+		m.handler = handler
+		m.newPlugin = func(resourceName string, handler dh.DeviceHandler) DevicePlugin {
+			mu.Lock()
+			defer mu.Unlock()
+			p := &fakeDevicePlugin{handler: handler}
+			plugins[resourceName] = p
+			return p
+		}
+	})
+
+	It("should create a resource per distinct attribute value", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+
+		Expect(m.Resources()).To(ConsistOf("openshift.io/dpu-a", "openshift.io/dpu-b", DpuResourceName))
+		Expect(devicesOf("openshift.io/dpu-a")).To(Equal([]string{"dev0", "dev2"}))
+		Expect(devicesOf("openshift.io/dpu-b")).To(Equal([]string{"dev1"}))
+		Expect(devicesOf(DpuResourceName)).To(Equal([]string{"dev3", "dev4"}))
+	})
+
+	It("should register new resources as new attribute values appear", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		handler.attributes["dev5"] = map[string]string{"pool": "dpu-c"}
+		delete(handler.attributes, "dev1")
+		Expect(m.reconcile(context.Background())).To(Succeed())
+
+		Expect(m.Resources()).To(ContainElement("openshift.io/dpu-c"))
+		Expect(devicesOf("openshift.io/dpu-c")).To(Equal([]string{"dev5"}))
+		Expect(devicesOf("openshift.io/dpu-b")).To(BeEmpty())
+	})
+
+	Context("with a device matched by two pools", func() {
+		BeforeEach(func() {
+			handler.attributes["dev5"] = map[string]string{"pool": "dpu-b, dpu-a"}
+		})
+
+		It("should advertise it in none of the pools by default", func() {
+			Expect(m.reconcile(context.Background())).To(MatchError(ContainSubstring("matched by more than one pool: dev5")))
+
+			Expect(devicesOf("openshift.io/dpu-a")).To(Equal([]string{"dev0", "dev2"}))
+			Expect(devicesOf("openshift.io/dpu-b")).To(Equal([]string{"dev1"}))
+			Expect(m.collisions).To(HaveKey("dev5"))
+		})
+
+		It("should only advertise it in the first pool when configured to", func() {
+			WithPoolCollisionPolicy(PoolCollisionKeepFirst)(m)
+			Expect(m.reconcile(context.Background())).To(Succeed())
+
+			Expect(devicesOf("openshift.io/dpu-a")).To(Equal([]string{"dev0", "dev2", "dev5"}))
+			Expect(devicesOf("openshift.io/dpu-b")).To(Equal([]string{"dev1"}))
+			Expect(m.collisions).To(HaveKey("dev5"))
+		})
+	})
+
+	It("should report to every resource that the vendor plugin is unreachable", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		handler.err = fmt.Errorf("connection refused")
+		Expect(m.reconcile(context.Background())).NotTo(Succeed())
+		for _, resourceName := range []string{"openshift.io/dpu-a", "openshift.io/dpu-b", DpuResourceName} {
+			_, err := plugins[resourceName].handler.GetDevices(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("connection refused")), resourceName)
+		}
+
+		handler.err = nil
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(devicesOf("openshift.io/dpu-a")).To(Equal([]string{"dev0", "dev2"}))
+
+		handler.err = nil
+		handler.attributes = map[string]map[string]string{}
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(devicesOf("openshift.io/dpu-a")).To(BeEmpty())
+	})
+
+	It("should prefer the pool reported by the vendor plugin to the attribute", func() {
+		m.handler = &fakePoolHandler{fakeAttributeHandler: *handler, pools: map[string]string{
+			"dev0": "mgmt",
+			"dev1": "data",
+			"dev3": "data",
+		}}
+		Expect(m.reconcile(context.Background())).To(Succeed())
+
+		Expect(m.Resources()).To(ConsistOf("openshift.io/mgmt", "openshift.io/data", "openshift.io/dpu-a", DpuResourceName))
+		Expect(devicesOf("openshift.io/mgmt")).To(Equal([]string{"dev0"}))
+		Expect(devicesOf("openshift.io/data")).To(Equal([]string{"dev1", "dev3"}))
+		Expect(devicesOf("openshift.io/dpu-a")).To(Equal([]string{"dev2"}))
+	})
+
+	It("should only use the reported pools without an attribute", func() {
+		m.attributeKey = ""
+		m.handler = &fakePoolHandler{fakeAttributeHandler: *handler, pools: map[string]string{"dev0": "mgmt"}}
+		Expect(m.reconcile(context.Background())).To(Succeed())
+
+		Expect(m.Resources()).To(ConsistOf("openshift.io/mgmt", DpuResourceName))
+		Expect(devicesOf(DpuResourceName)).To(Equal([]string{"dev1", "dev2", "dev3", "dev4"}))
+	})
+
+	It("should serve the default resource on the listener of Listen", func() {
+		_, err := m.Listen()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Resources()).To(ConsistOf(DpuResourceName))
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(m.Resources()).To(ConsistOf("openshift.io/dpu-a", "openshift.io/dpu-b", DpuResourceName))
+		Expect(devicesOf(DpuResourceName)).To(Equal([]string{"dev3", "dev4"}))
+	})
+
+	It("should stop every Device Plugin", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(m.Stop()).To(Succeed())
+		for _, p := range plugins {
+			Expect(p.stopped).To(BeTrue())
+		}
+	})
+
+	It("should stop more than once", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(m.Stop()).To(Succeed())
+		Expect(m.Stop()).To(Succeed())
+	})
+
+	It("should stop the Device Plugins while they get their devices", func() {
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		for _, p := range plugins {
+			p.onStop = func() {
+				_, _ = p.handler.GetDevices(context.Background())
+			}
+		}
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- m.Stop()
+		}()
+		Eventually(stopped).Should(Receive(BeNil()))
+	})
+
+	It("should not start Device Plugins once stopped", func() {
+		Expect(m.Stop()).To(Succeed())
+		Expect(m.reconcile(context.Background())).To(Succeed())
+		Expect(m.Resources()).To(BeEmpty())
+	})
+
+	It("should return once a Device Plugin stops serving", func() {
+		m.newPlugin = func(resourceName string, handler dh.DeviceHandler) DevicePlugin {
+			p := &fakeDevicePlugin{handler: handler}
+			if resourceName == "openshift.io/dpu-b" {
+				p.serveErr = fmt.Errorf("listener broken")
+			}
+			return p
+		}
+		m.pollInterval = time.Hour
+		Expect(m.ListenAndServe()).To(MatchError(ContainSubstring("Device Plugin of openshift.io/dpu-b failed: listener broken")))
+	})
+
+	It("should give every resource its own socket", func() {
+		a := m.newDevicePlugin("openshift.io/dpu-a", handler).(*dpServer)
+		def := m.newDevicePlugin(DpuResourceName, handler).(*dpServer)
+		Expect(a.resourceName).To(Equal("openshift.io/dpu-a"))
+		Expect(a.pluginEndpoint).To(HaveSuffix("dpuNet-dpu-a.sock"))
+		Expect(a.introspectionEnabled).To(BeTrue())
+		Expect(a.introspectionPath).To(HaveSuffix("introspection-dpu-a.sock"))
+		Expect(def.pluginEndpoint).To(Equal(m.pathManager.PluginEndpoint()))
+		Expect(def.introspectionPath).To(Equal(m.pathManager.DevicePluginIntrospectionPath()))
+	})
+
+	It("should pass the Device Plugin options to every resource", func() {
+		WithDevicePluginOptions(WithFreezeFile("/run/freeze"), WithResourceName("ignored", "ignored.sock"))(m)
+		for _, resourceName := range []string{"openshift.io/dpu-a", DpuResourceName} {
+			dp := m.newDevicePlugin(resourceName, handler).(*dpServer)
+			Expect(dp.freeze).NotTo(BeNil(), resourceName)
+			Expect(dp.freeze.path).To(Equal("/run/freeze"))
+			Expect(dp.deviceHandler).To(BeIdenticalTo(handler))
+			Expect(dp.resourceName).To(Equal(resourceName))
+		}
+	})
+})
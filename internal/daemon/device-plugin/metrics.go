@@ -0,0 +1,155 @@
+package deviceplugin
+
+import (
+	"github.com/openshift/dpu-operator/pkgs/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "dpu_device_plugin"
+
+// Reasons of failed allocations.
+const (
+	allocateFailureShuttingDown  = "shutting_down"
+	allocateFailureFrozen        = "frozen"
+	allocateFailureShared        = "shared"
+	allocateFailureNotAdvertised = "not_advertised"
+	allocateFailureDriver        = "driver"
+	allocateFailureUnhealthy     = "unhealthy"
+	allocateFailureDrained       = "drained"
+	allocateFailureResponse      = "response"
+	allocateFailureValidator     = "validator"
+)
+
+var (
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "build_info",
+		Help:      "Build information of the running Device Plugin. The value is always 1.",
+	}, []string{"version", "commit"})
+
+	allocateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocate_duration_seconds",
+		Help:      "Latency of Allocate calls, including rejected ones.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"resource"})
+
+	allocateInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocate_in_flight",
+		Help:      "Number of Allocate calls currently being served.",
+	}, []string{"resource"})
+
+	allocationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocations_total",
+		Help:      "Number of successful Allocate calls.",
+	}, []string{"resource"})
+
+	allocationFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocation_failures_total",
+		Help:      "Number of failed Allocate calls, by reason.",
+	}, []string{"resource", "reason"})
+
+	advertisedDevicesByHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "advertised_devices",
+		Help:      "Number of advertised devices, by health.",
+	}, []string{"resource", "health"})
+
+	vendorPluginConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "vendor_plugin_connected",
+		Help:      "Whether the last attempt to get the devices from the vendor plugin succeeded (1) or not (0).",
+	}, []string{"resource"})
+
+	allocateSlowTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocate_slow_total",
+		Help:      "Number of Allocate calls that exceeded the configured latency threshold.",
+	}, []string{"resource"})
+
+	staleAllocationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stale_allocations_total",
+		Help:      "Number of allocated devices that disappeared while still allocated.",
+	}, []string{"resource"})
+
+	expiredAllocationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "expired_allocations_total",
+		Help:      "Number of allocations whose lease expired without being refreshed.",
+	}, []string{"resource"})
+
+	droppedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "dropped_devices",
+		Help:      "Number of discovered devices that are not advertised, by reason.",
+	}, []string{"resource", "reason"})
+
+	numaHealthyDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "numa_healthy_devices",
+		Help:      "Number of advertised healthy devices per NUMA node, \"none\" for devices without NUMA affinity.",
+	}, []string{"resource", "numa_node"})
+
+	numaAllocatableDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "numa_allocatable_devices",
+		Help:      "Number of advertised healthy and unallocated devices per NUMA node, \"none\" for devices without NUMA affinity.",
+	}, []string{"resource", "numa_node"})
+
+	allocateResponseCacheHitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocate_response_cache_hits_total",
+		Help:      "Number of container allocate responses served from the cache.",
+	}, []string{"resource"})
+
+	maintenanceSuppressedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "maintenance_suppressed_devices",
+		Help:      "Number of devices whose health change is not advertised because of an active maintenance window.",
+	}, []string{"resource"})
+
+	podResourcesDiscrepancies = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "podresources_discrepancies",
+		Help:      "Number of devices allocated by the Device Plugin or assigned by Kubelet but not both, by kind, as of the last reconcile against the PodResources API.",
+	}, []string{"resource", "kind"})
+
+	allocateValidatorBypassesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "allocate_validator_bypasses_total",
+		Help:      "Number of allocations accepted without review because the allocate validator failed and is configured to fail open.",
+	}, []string{"resource"})
+
+	droppedEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dropped_events_total",
+		Help:      "Number of allocation and health events the event sink couldn't deliver.",
+	}, []string{"resource"})
+
+	grpcRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of calls served by the Device Plugin server, by method and status code.",
+	}, []string{"resource", "method", "code"})
+
+	grpcPanicsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_panics_total",
+		Help:      "Number of calls of the Device Plugin server whose handler panicked, by method.",
+	}, []string{"resource", "method"})
+)
+
+func init() {
+	// The Device Plugin runs inside the daemon, whose controller manager
+	// already serves the controller-runtime registry.
+	metrics.Registry.MustRegister(buildInfo, allocateSlowTotal, staleAllocationsTotal, droppedDevices, numaHealthyDevices, numaAllocatableDevices,
+		maintenanceSuppressedDevices, allocateResponseCacheHitsTotal, expiredAllocationsTotal, allocateDuration, allocateInFlight,
+		allocationsTotal, allocationFailuresTotal, advertisedDevicesByHealth, vendorPluginConnected,
+		droppedEventsTotal, podResourcesDiscrepancies, grpcRequestsTotal, grpcPanicsTotal, allocateValidatorBypassesTotal)
+	buildInfo.WithLabelValues(version.Version, version.Commit).Set(1)
+}
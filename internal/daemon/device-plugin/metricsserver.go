@@ -0,0 +1,49 @@
+package deviceplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultMetricsBindAddress is where the daemon serves the Device Plugin
+// metrics by default. The controller manager of the DPU side already serves
+// on 18001, and the daemon runs in the host network.
+const DefaultMetricsBindAddress = ":18002"
+
+// ServeMetrics serves the Device Plugin metrics for Prometheus at /metrics on
+// address until ctx is done. The metrics of every resource pool are served
+// together, told apart by their resource label.
+func ServeMetrics(ctx context.Context, address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics on %s: %v", address, err)
+	}
+	return serveMetrics(ctx, lis)
+}
+
+func serveMetrics(ctx context.Context, lis net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+	defer stop()
+	if err := server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve metrics: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package deviceplugin
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics server", func() {
+	It("should serve the Device Plugin metrics until stopped", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- serveMetrics(ctx, lis)
+		}()
+
+		resp, err := http.Get("http://" + lis.Addr().String() + "/metrics")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring(metricsNamespace + "_build_info"))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
@@ -0,0 +1,54 @@
+package deviceplugin
+
+import (
+	"sort"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// DeviceSortOrder is the order in which devices are advertised to Kubelet.
+type DeviceSortOrder string
+
+const (
+	// SortByID advertises the devices ordered by ID.
+	SortByID DeviceSortOrder = "id"
+	// SortByNumaThenID groups the devices by their lowest NUMA node, ordered
+	// by ID within a node. Devices without NUMA affinity come last.
+	SortByNumaThenID DeviceSortOrder = "numa"
+)
+
+// orderedDevices returns the devices in the configured sort order, so that
+// the advertised order is stable across polls and restarts.
+func (dp *dpServer) orderedDevices(devices *dh.DeviceList) []*pluginapi.Device {
+	ordered := make([]*pluginapi.Device, 0, len(*devices))
+	for _, id := range sortedDeviceIDs(devices) {
+		dev := (*devices)[id]
+		ordered = append(ordered, &dev)
+	}
+
+	if dp.sortOrder == SortByNumaThenID {
+		numa := make(map[string]int64, len(ordered))
+		for _, dev := range ordered {
+			numa[dev.ID] = lowestNumaNode(*dev)
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return numa[ordered[i].ID] < numa[ordered[j].ID]
+		})
+	}
+	return ordered
+}
+
+// lowestNumaNode returns the lowest NUMA node of a device, or the highest
+// possible node when the affinity is unknown so that such devices sort last.
+func lowestNumaNode(dev pluginapi.Device) int64 {
+	nodes := deviceNumaNodes(dev)
+	if len(nodes) == 0 {
+		return 1<<63 - 1
+	}
+	lowest := nodes[0]
+	for _, node := range nodes[1:] {
+		lowest = min(lowest, node)
+	}
+	return lowest
+}
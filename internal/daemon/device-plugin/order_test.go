@@ -0,0 +1,68 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeListAndWatchServer records the responses sent to Kubelet.
+type fakeListAndWatchServer struct {
+	grpc.ServerStream
+	sent []*pluginapi.ListAndWatchResponse
+}
+
+func (s *fakeListAndWatchServer) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeListAndWatchServer) Send(resp *pluginapi.ListAndWatchResponse) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+func sentIDs(resp *pluginapi.ListAndWatchResponse) []string {
+	ids := make([]string, 0, len(resp.Devices))
+	for _, dev := range resp.Devices {
+		ids = append(ids, dev.ID)
+	}
+	return ids
+}
+
+var _ = Describe("Device sort order", func() {
+	var (
+		dp      *dpServer
+		devices dh.DeviceList
+		stream  *fakeListAndWatchServer
+	)
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin()
+		stream = &fakeListAndWatchServer{}
+		devices = make(dh.DeviceList)
+		for id, node := range map[string]int64{"dev0": 1, "dev1": 0, "dev2": 1, "dev3": -1} {
+			dev := pluginapi.Device{ID: id, Health: pluginapi.Healthy}
+			if node >= 0 {
+				dev.Topology = &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+			}
+			devices[id] = dev
+		}
+	})
+
+	It("should advertise the devices ordered by ID by default", func() {
+		for i := 0; i < 3; i++ {
+			Expect(dp.sendDevices(stream, &devices)).To(Succeed())
+			Expect(sentIDs(stream.sent[i])).To(Equal([]string{"dev0", "dev1", "dev2", "dev3"}))
+		}
+	})
+
+	It("should advertise the devices ordered by NUMA node, then ID", func() {
+		WithDeviceSortOrder(SortByNumaThenID)(dp)
+		Expect(dp.sendDevices(stream, &devices)).To(Succeed())
+		Expect(sentIDs(stream.sent[0])).To(Equal([]string{"dev1", "dev0", "dev2", "dev3"}))
+	})
+})
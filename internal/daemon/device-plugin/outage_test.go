@@ -0,0 +1,60 @@
+package deviceplugin
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Vendor plugin outage", func() {
+	var (
+		dp     *dpServer
+		vsp    *healthReportingVendorPlugin
+		stream *lockedListAndWatchServer
+	)
+
+	BeforeEach(func() {
+		vsp = &healthReportingVendorPlugin{health: map[string]string{"dev0": "", "dev1": ""}}
+		dp = NewDevicePlugin(vsp, true, *utils.NewPathManager(GinkgoT().TempDir()), WithCoalesceWindow(0), WithHealthCacheTTL(0))
+		Expect(dp.SetupDevices()).To(Succeed())
+		dp.pollInterval = time.Hour
+
+		stream = &lockedListAndWatchServer{}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			vsp.set(false, map[string]string{"dev9": ""})
+			dp.triggerUpdate()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(Equal([][]string{{"dev0", "dev1"}}))
+	})
+
+	It("should keep the advertised devices while the vendor plugin is unreachable", func() {
+		vsp.set(true, nil)
+		dp.triggerUpdate()
+		dp.triggerUpdate()
+		Consistently(stream.sends, 300*time.Millisecond).Should(HaveLen(1))
+
+		vsp.set(false, nil)
+		dp.triggerUpdate()
+		Consistently(stream.sends, 300*time.Millisecond).Should(HaveLen(1))
+	})
+
+	It("should withdraw the devices when the vendor plugin reports none", func() {
+		vsp.set(false, map[string]string{})
+		dp.triggerUpdate()
+		Eventually(stream.sends).Should(HaveLen(2))
+		Expect(stream.sends()[1]).To(BeEmpty())
+	})
+})
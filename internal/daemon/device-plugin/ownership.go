@@ -0,0 +1,55 @@
+package deviceplugin
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AllocationOwner is what is known about the container holding a device.
+// Kubelet only tells the position of the container in the Allocate request,
+// the pod and container are a best-effort guess.
+type AllocationOwner struct {
+	// ContainerIndex is the index of the container request in Allocate.
+	ContainerIndex int `json:"containerIndex"`
+	// Devices are all devices allocated to the container.
+	Devices []string `json:"devices"`
+	// Pod and Container are only set when a single pending container on the
+	// node requests as many devices of our resource, see WithDeviceSelectors.
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+}
+
+// allocationOwner describes the owner of the devices of a container request.
+func (dp *dpServer) allocationOwner(ctx context.Context, index int, ids []string) *AllocationOwner {
+	owner := &AllocationOwner{ContainerIndex: index, Devices: append([]string(nil), ids...)}
+	if dp.podLister == nil || len(ids) == 0 {
+		return owner
+	}
+	pods, err := dp.podLister.ListPods(ctx)
+	if err != nil {
+		dp.log.V(1).Info("Failed to list pods, not guessing the owner of the allocation", "err", err)
+		return owner
+	}
+
+	want := resource.NewQuantity(int64(len(ids)), resource.DecimalSI)
+	matches := 0
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodPending {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if q, ok := container.Resources.Limits[corev1.ResourceName(dp.resourceName)]; ok && q.Cmp(*want) == 0 {
+				matches++
+				owner.Pod = client.ObjectKeyFromObject(&pod).String()
+				owner.Container = container.Name
+			}
+		}
+	}
+	if matches != 1 {
+		owner.Pod, owner.Container = "", ""
+	}
+	return owner
+}
@@ -0,0 +1,67 @@
+package deviceplugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// payloadEnvName carries the AllocationPayload of a container.
+const payloadEnvName = "NF_DEVICES_B64"
+
+// AllocationPayload describes the devices allocated to a container. It's
+// passed in NF_DEVICES_B64 as JSON, encoded with standard base64, e.g.:
+//
+//	{"devices":[{"id":"0000:03:00.2","pciAddress":"0000:03:00.2","pfName":"ens1f0",
+//	  "vfIndex":0,"interface":"ens1f0v0","numaNodes":[0],"attributes":{"sku":"a"}}]}
+//
+// The devices are in the order they were allocated in. Fields that aren't
+// known are omitted.
+type AllocationPayload struct {
+	Devices []AllocatedDevice `json:"devices"`
+}
+
+// AllocatedDevice is a device of an AllocationPayload.
+type AllocatedDevice struct {
+	DeviceInfo
+	NumaNodes []int64 `json:"numaNodes,omitempty"`
+}
+
+// DecodeAllocationPayload decodes the value of NF_DEVICES_B64.
+func DecodeAllocationPayload(value string) (*AllocationPayload, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode allocation payload: %v", err)
+	}
+	var payload AllocationPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse allocation payload: %v", err)
+	}
+	return &payload, nil
+}
+
+// allocationPayloadEnvValue encodes the payload of the given devices.
+func (dp *dpServer) allocationPayloadEnvValue(ids []string) (string, error) {
+	payload := AllocationPayload{Devices: make([]AllocatedDevice, 0, len(ids))}
+	for _, id := range ids {
+		cached, _ := dp.device(id)
+		payload.Devices = append(payload.Devices, AllocatedDevice{
+			DeviceInfo: dp.allocatedDeviceInfo(id),
+			NumaNodes:  deviceNumaNodes(cached),
+		})
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode allocation payload: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// WithAllocationPayloadEnv additionally passes the allocated devices with
+// everything known about them in NF_DEVICES_B64, see AllocationPayload, so
+// that consumers don't have to gather it from several variables.
+func WithAllocationPayloadEnv(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.payloadEnv = enabled
+	}
+}
@@ -0,0 +1,60 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Allocation payload env", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		WithDeviceHandler(attributeDeviceHandler{
+			"dev0": {"sku": "a"},
+			"dev1": {"sku": "b"},
+			"dev2": {},
+		})(dp)
+		dev := dp.devices["dev1"]
+		dev.Topology = &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}}
+		dp.devices["dev1"] = dev
+		dp.deviceInfoFor = func(id string) DeviceInfo {
+			return DeviceInfo{ID: id, Interface: id + "v0"}
+		}
+	})
+
+	It("should not be set by default", func() {
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Envs).NotTo(HaveKey(payloadEnvName))
+	})
+
+	It("should decode to the devices allocated to each container", func() {
+		WithAllocationPayloadEnv(true)(dp)
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev1", "dev0"}, []string{"dev2"}, []string{}))
+		Expect(err).NotTo(HaveOccurred())
+
+		payload, err := DecodeAllocationPayload(resp.ContainerResponses[0].Envs[payloadEnvName])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payload.Devices).To(Equal([]AllocatedDevice{
+			{DeviceInfo: DeviceInfo{ID: "dev1", Interface: "dev1v0", Attributes: map[string]string{"sku": "b"}}, NumaNodes: []int64{1}},
+			{DeviceInfo: DeviceInfo{ID: "dev0", Interface: "dev0v0", Attributes: map[string]string{"sku": "a"}}},
+		}))
+
+		payload, err = DecodeAllocationPayload(resp.ContainerResponses[1].Envs[payloadEnvName])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payload.Devices).To(Equal([]AllocatedDevice{{DeviceInfo: DeviceInfo{ID: "dev2", Interface: "dev2v0"}}}))
+
+		Expect(resp.ContainerResponses[2].Envs).NotTo(HaveKey(payloadEnvName))
+	})
+
+	It("should reject values that aren't base64 encoded JSON", func() {
+		_, err := DecodeAllocationPayload("not base64!")
+		Expect(err).To(MatchError(ContainSubstring("failed to decode")))
+		_, err = DecodeAllocationPayload("bm90IGpzb24=")
+		Expect(err).To(MatchError(ContainSubstring("failed to parse")))
+	})
+})
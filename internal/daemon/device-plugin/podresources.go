@@ -0,0 +1,180 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	defaultPodResourcesInterval = time.Minute
+	podResourcesTimeout         = 10 * time.Second
+	// podResourcesReleaseGrace is how old an allocation no container holds
+	// must be to be released. Kubelet only records the devices it assigned
+	// once Allocate returned, so younger ones may just not be listed yet.
+	podResourcesReleaseGrace = 30 * time.Second
+)
+
+// podResourcesReconcile compares the devices Kubelet assigned to containers,
+// as reported by its PodResources API, against our allocations, and releases
+// the allocations of the pods that went away.
+type podResourcesReconcile struct {
+	endpoint string
+	interval time.Duration
+}
+
+// PodResourcesDiscrepancy is the drift between the allocations of the Device
+// Plugin and the devices Kubelet assigned to containers.
+type PodResourcesDiscrepancy struct {
+	// Untracked are devices Kubelet assigned to a container without the
+	// Device Plugin having an allocation of them, e.g. allocated before a
+	// restart of the Device Plugin.
+	Untracked []string `json:"untracked,omitempty"`
+	// Unassigned are allocations of the Device Plugin that no container
+	// holds according to Kubelet, e.g. of a pod that terminated. They are
+	// released once older than podResourcesReleaseGrace.
+	Unassigned []string `json:"unassigned,omitempty"`
+}
+
+func (d PodResourcesDiscrepancy) empty() bool {
+	return len(d.Untracked) == 0 && len(d.Unassigned) == 0
+}
+
+func (dp *dpServer) podResourcesEndpoint() string {
+	if dp.podResources.endpoint == "" {
+		return dp.pathManager.KubeletPodResourcesEndPoint()
+	}
+	return dp.podResources.endpoint
+}
+
+// kubeletAssignedDevices lists the devices of our resource that Kubelet
+// assigned to containers.
+func (dp *dpServer) kubeletAssignedDevices(ctx context.Context) (map[string]bool, error) {
+	conn, err := grpc.NewClient(filepath.Join("unix:", dp.podResourcesEndpoint()), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the Kubelet PodResources API: %v", err)
+	}
+	defer conn.Close()
+
+	resp, err := podresourcesapi.NewPodResourcesListerClient(conn).List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod resources: %v", err)
+	}
+	assigned := make(map[string]bool)
+	for _, pod := range resp.PodResources {
+		for _, container := range pod.Containers {
+			for _, devices := range container.Devices {
+				if devices.ResourceName != dp.resourceName {
+					continue
+				}
+				for _, id := range devices.DeviceIds {
+					assigned[id] = true
+				}
+			}
+		}
+	}
+	return assigned, nil
+}
+
+// reconcilePodResources compares the devices Kubelet assigned against our
+// allocations, renews the leases of the allocations containers still hold,
+// and reports the discrepancies in the logs and metrics. Kubelet
+// doesn't tell Device Plugins about pods going away, so this is where the
+// allocations of deleted pods are released.
+func (dp *dpServer) reconcilePodResources(ctx context.Context) (PodResourcesDiscrepancy, error) {
+	ctx, cancel := context.WithTimeout(ctx, podResourcesTimeout)
+	defer cancel()
+	assigned, err := dp.kubeletAssignedDevices(ctx)
+	if err != nil {
+		return PodResourcesDiscrepancy{}, err
+	}
+
+	// The containers Kubelet still lists are alive, renew their leases.
+	dp.RefreshAllocations(slices.Collect(maps.Keys(assigned))...)
+
+	var discrepancy PodResourcesDiscrepancy
+	var released []string
+	allocated := make(map[string]bool)
+	now := dp.clock.Now()
+	for _, a := range dp.allocations.list() {
+		allocated[a.DeviceID] = true
+		if !assigned[a.DeviceID] {
+			discrepancy.Unassigned = append(discrepancy.Unassigned, a.DeviceID)
+			if now.Sub(a.AllocatedAt) >= podResourcesReleaseGrace {
+				released = append(released, a.DeviceID)
+			}
+		}
+	}
+	for id := range assigned {
+		if !allocated[id] {
+			discrepancy.Untracked = append(discrepancy.Untracked, id)
+		}
+	}
+	sort.Strings(discrepancy.Untracked)
+
+	podResourcesDiscrepancies.WithLabelValues(dp.resourceName, "untracked").Set(float64(len(discrepancy.Untracked)))
+	podResourcesDiscrepancies.WithLabelValues(dp.resourceName, "unassigned").Set(float64(len(discrepancy.Unassigned)))
+	if !discrepancy.empty() {
+		dp.log.Info("Warning: allocations differ from the devices Kubelet assigned", "untracked", discrepancy.Untracked,
+			"unassigned", discrepancy.Unassigned)
+	}
+	for _, id := range released {
+		dp.log.Info("Releasing allocation no container holds anymore", "id", id)
+		dp.releaseAllocation(id)
+	}
+	return discrepancy, nil
+}
+
+// watchPodResources reconciles against the PodResources API every interval
+// until the Device Plugin is stopped. Kubelet not answering is only logged,
+// the allocations are then kept until the next reconcile.
+func (dp *dpServer) watchPodResources() {
+	if dp.podResources == nil {
+		return
+	}
+	dp.podResourcesWg.Add(1)
+	go func() {
+		defer dp.podResourcesWg.Done()
+		timer := dp.clock.NewTimer(dp.podResources.interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-dp.stopCh:
+				return
+			case <-timer.C():
+				if _, err := dp.reconcilePodResources(context.Background()); err != nil {
+					dp.log.Error(err, "Failed to reconcile allocations against the Kubelet PodResources API",
+						"endpoint", dp.podResourcesEndpoint())
+				}
+				timer.Reset(dp.podResources.interval)
+			}
+		}
+	}()
+}
+
+// WithPodResourcesReconcile sets where and how often the allocations are
+// reconciled against the devices Kubelet reports as assigned through its
+// PodResources API, which releases the allocations no container holds
+// anymore. It's done by default every minute on the Kubelet socket. An empty
+// endpoint uses the Kubelet socket, a zero interval the default and a
+// negative interval disables the reconcile.
+func WithPodResourcesReconcile(endpoint string, interval time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		if interval < 0 {
+			d.podResources = nil
+			return
+		}
+		if interval == 0 {
+			interval = defaultPodResourcesInterval
+		}
+		d.podResources = &podResourcesReconcile{endpoint: endpoint, interval: interval}
+	}
+}
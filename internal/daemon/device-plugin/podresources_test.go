@@ -0,0 +1,167 @@
+package deviceplugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// fakePodResourcesServer reports the given devices of each resource as
+// assigned to a single container.
+type fakePodResourcesServer struct {
+	podresourcesapi.UnimplementedPodResourcesListerServer
+	devices map[string][]string
+}
+
+func (s *fakePodResourcesServer) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest) (*podresourcesapi.ListPodResourcesResponse, error) {
+	container := &podresourcesapi.ContainerResources{Name: "app"}
+	for resource, ids := range s.devices {
+		container.Devices = append(container.Devices, &podresourcesapi.ContainerDevices{ResourceName: resource, DeviceIds: ids})
+	}
+	return &podresourcesapi.ListPodResourcesResponse{PodResources: []*podresourcesapi.PodResources{
+		{Name: "pod", Namespace: "default", Containers: []*podresourcesapi.ContainerResources{container}},
+	}}, nil
+}
+
+var _ = Describe("PodResources reconcile", func() {
+	var (
+		dp       *dpServer
+		endpoint string
+	)
+
+	BeforeEach(func() {
+		// Unix socket paths are limited to 108 bytes, too short for
+		// GinkgoT().TempDir().
+		dir, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		endpoint = filepath.Join(dir, "kubelet.sock")
+
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		WithPodResourcesReconcile(endpoint, time.Hour)(dp)
+	})
+
+	It("should report devices only one side knows about", func() {
+		lis, err := net.Listen("unix", endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		podresourcesapi.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{devices: map[string][]string{
+			dp.resourceName:         {"dev1", "dev2"},
+			"example.com/other-nic": {"dev0"},
+		}})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		discrepancy, err := dp.reconcilePodResources(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discrepancy).To(Equal(PodResourcesDiscrepancy{Untracked: []string{"dev2"}, Unassigned: []string{"dev0"}}))
+		Expect(gaugeValue(podResourcesDiscrepancies.WithLabelValues(dp.resourceName, "untracked"))).To(Equal(1.0))
+		Expect(gaugeValue(podResourcesDiscrepancies.WithLabelValues(dp.resourceName, "unassigned"))).To(Equal(1.0))
+		// Kubelet may not list a device it was just allocated yet.
+		Expect(dp.allocations.isAllocated("dev0")).To(BeTrue())
+	})
+
+	It("should release the allocations of containers that went away", func() {
+		lis, err := net.Listen("unix", endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		podresourcesapi.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{devices: map[string][]string{
+			dp.resourceName: {"dev1"},
+		}})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dp.writeDeviceInfo("dev0")).To(Succeed())
+
+		clock.SetTime(clock.Now().Add(podResourcesReleaseGrace))
+		_, err = dp.reconcilePodResources(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dp.allocations.isAllocated("dev0")).To(BeFalse())
+		Expect(dp.allocations.isAllocated("dev1")).To(BeTrue())
+		Expect(deviceInfoPath(dp.pathManager, "dev0")).NotTo(BeAnExistingFile())
+	})
+
+	It("should keep the devices of a live container past the allocation TTL", func() {
+		lis, err := net.Listen("unix", endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		podresourcesapi.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{devices: map[string][]string{
+			dp.resourceName: {"dev0"},
+		}})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		WithAllocationTTL(time.Minute, true)(dp)
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		for range 5 {
+			clock.SetTime(clock.Now().Add(40 * time.Second))
+			_, err = dp.reconcilePodResources(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			dp.checkExpiredAllocations()
+		}
+		Expect(dp.allocations.isAllocated("dev0")).To(BeTrue())
+		Expect(dp.GetAllocations()[0].Expired).To(BeFalse())
+	})
+
+	It("should fail without Kubelet answering", func() {
+		_, err := dp.reconcilePodResources(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("failed to list pod resources")))
+	})
+
+	It("should release the allocations of containers that went away by default", func() {
+		lis, err := net.Listen("unix", endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		podresourcesapi.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{devices: map[string][]string{
+			dp.resourceName: {"dev1"},
+		}})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		dp = newTestDevicePlugin("dev0", "dev1")
+		Expect(dp.podResources).To(Equal(&podResourcesReconcile{interval: defaultPodResourcesInterval}))
+		// The Kubelet socket of the test.
+		dp.podResources.endpoint = endpoint
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		dp.watchPodResources()
+		DeferCleanup(dp.Stop)
+		Eventually(clock.HasWaiters).Should(BeTrue())
+		clock.Step(defaultPodResourcesInterval)
+		Eventually(func() bool { return dp.allocations.isAllocated("dev0") }).Should(BeFalse())
+		Expect(dp.allocations.isAllocated("dev1")).To(BeTrue())
+	})
+
+	It("should be disabled by a negative interval", func() {
+		WithPodResourcesReconcile("", -1)(dp)
+		Expect(dp.podResources).To(BeNil())
+	})
+
+	It("should default to the Kubelet socket", func() {
+		WithPodResourcesReconcile("", 0)(dp)
+		Expect(dp.podResourcesEndpoint()).To(Equal(dp.pathManager.KubeletPodResourcesEndPoint()))
+		Expect(dp.podResources.interval).To(Equal(defaultPodResourcesInterval))
+	})
+})
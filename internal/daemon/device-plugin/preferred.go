@@ -0,0 +1,163 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeviceSelectorAnnotation on a pod holds a label selector, e.g.
+// "pool=fast", matched against the attributes of the devices. Matching
+// devices are preferred when allocating to the pod.
+const DeviceSelectorAnnotation = "dpu.openshift.io/device-selector"
+
+// PodLister lists the pods scheduled to the node of the Device Plugin.
+type PodLister interface {
+	ListPods(ctx context.Context) ([]corev1.Pod, error)
+}
+
+// PodNodeNameField is the field nodePodLister selects the pods of its node
+// by. The API server supports it natively, a controller-runtime cache needs
+// the index registered by IndexPodsByNode.
+const PodNodeNameField = "spec.nodeName"
+
+// IndexPodsByNode registers the PodNodeNameField index with indexer, e.g. the
+// field indexer of the manager whose cache backs a nodePodLister.
+func IndexPodsByNode(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &corev1.Pod{}, PodNodeNameField, func(obj client.Object) []string {
+		return []string{obj.(*corev1.Pod).Spec.NodeName}
+	})
+}
+
+// nodePodLister lists pods through a client.Reader, which is backed by the
+// informers of the controller-runtime cache. Only the pods of the node are
+// listed, through the PodNodeNameField selector, rather than every pod of the
+// cluster on every allocation.
+type nodePodLister struct {
+	reader   client.Reader
+	nodeName string
+}
+
+func NewNodePodLister(reader client.Reader, nodeName string) PodLister {
+	return &nodePodLister{reader: reader, nodeName: nodeName}
+}
+
+func (l *nodePodLister) ListPods(ctx context.Context) ([]corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := l.reader.List(ctx, &pods, client.MatchingFields{PodNodeNameField: l.nodeName}); err != nil {
+		return nil, fmt.Errorf("failed to list pods of node %s: %v", l.nodeName, err)
+	}
+	return pods.Items, nil
+}
+
+// deviceSelectorFor looks up the device selector of the pod being allocated
+// devices. Kubelet doesn't say which pod a preferred allocation is for, so
+// the oldest pending pod with a container requesting size devices of our
+// resource is assumed. Returns nil when there is no such annotated pod.
+func (dp *dpServer) deviceSelectorFor(ctx context.Context, size int) labels.Selector {
+	if dp.podLister == nil {
+		return nil
+	}
+	pods, err := dp.podLister.ListPods(ctx)
+	if err != nil {
+		dp.log.Error(err, "Failed to look up device selectors, using the default preference")
+		return nil
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+
+	for _, pod := range pods {
+		value, ok := pod.Annotations[DeviceSelectorAnnotation]
+		if !ok || pod.Status.Phase != corev1.PodPending || !dp.requestsDevices(&pod, size) {
+			continue
+		}
+		selector, err := labels.Parse(value)
+		if err != nil {
+			dp.log.Error(err, "Ignoring invalid device selector", "pod", client.ObjectKeyFromObject(&pod))
+			return nil
+		}
+		return selector
+	}
+	return nil
+}
+
+// requestsDevices returns whether a container of the pod requests size devices.
+func (dp *dpServer) requestsDevices(pod *corev1.Pod, size int) bool {
+	want := resource.NewQuantity(int64(size), resource.DecimalSI)
+	for _, container := range pod.Spec.Containers {
+		if q, ok := container.Resources.Limits[corev1.ResourceName(dp.resourceName)]; ok && q.Cmp(*want) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSelector returns whether the attributes of the device match.
+func (dp *dpServer) matchesSelector(id string, selector labels.Selector) bool {
+	handler, ok := dp.deviceHandler.(dh.AttributeHandler)
+	if !ok {
+		return false
+	}
+	return selector.Matches(labels.Set(handler.GetDeviceAttributes(id)))
+}
+
+// preferredDevices picks size devices: the ones Kubelet requires, then the
+// available devices matching the selector of the pod, then the others.
+func (dp *dpServer) preferredDevices(ctx context.Context, req *pluginapi.ContainerPreferredAllocationRequest) []string {
+	return dp.pickDevices(req, dp.deviceSelectorFor(ctx, int(req.AllocationSize)))
+}
+
+// pickDevices implements preferredDevices for a known selector, nil when the
+// pod has none.
+func (dp *dpServer) pickDevices(req *pluginapi.ContainerPreferredAllocationRequest, selector labels.Selector) []string {
+	size := int(req.AllocationSize)
+	preferred := append([]string(nil), req.MustIncludeDeviceIDs...)
+	picked := make(map[string]bool)
+	for _, id := range preferred {
+		picked[id] = true
+	}
+
+	var candidates []string
+	for _, id := range req.AvailableDeviceIDs {
+		if !picked[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Strings(candidates)
+	// The strategy and the selector sort stably, so warm devices are only
+	// preferred among devices they consider equal.
+	dp.preferWarm(candidates)
+	dp.orderCandidates(dp.allocationStrategy(), candidates, req.MustIncludeDeviceIDs)
+	if selector != nil {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return dp.matchesSelector(candidates[i], selector) && !dp.matchesSelector(candidates[j], selector)
+		})
+	}
+	dp.preferUnallocated(candidates)
+
+	for _, id := range candidates {
+		if len(preferred) >= size {
+			break
+		}
+		preferred = append(preferred, id)
+	}
+	return preferred
+}
+
+func (dp *dpServer) GetPreferredAllocation(ctx context.Context, rqt *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+	resp := &pluginapi.PreferredAllocationResponse{}
+	for _, req := range rqt.ContainerRequests {
+		resp.ContainerResponses = append(resp.ContainerResponses, &pluginapi.ContainerPreferredAllocationResponse{
+			DeviceIDs: dp.preferredDevices(ctx, req),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,180 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakePodLister []corev1.Pod
+
+func (l fakePodLister) ListPods(ctx context.Context) ([]corev1.Pod, error) {
+	return append([]corev1.Pod(nil), l...), nil
+}
+
+// fieldSelectingReader serves the pods matching the field selector of the
+// list, like the API server, and records the selectors.
+type fieldSelectingReader struct {
+	client.Reader
+	pods      []corev1.Pod
+	selectors []string
+}
+
+func (r *fieldSelectingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	var options client.ListOptions
+	options.ApplyOptions(opts)
+	selector := fields.Everything()
+	if options.FieldSelector != nil {
+		selector = options.FieldSelector
+	}
+	r.selectors = append(r.selectors, selector.String())
+	for _, pod := range r.pods {
+		if selector.Matches(fields.Set{PodNodeNameField: pod.Spec.NodeName}) {
+			list.(*corev1.PodList).Items = append(list.(*corev1.PodList).Items, pod)
+		}
+	}
+	return nil
+}
+
+// fakeFieldIndexer records the index functions by field.
+type fakeFieldIndexer map[string]client.IndexerFunc
+
+func (i fakeFieldIndexer) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	i[field] = extractValue
+	return nil
+}
+
+// attributeDeviceHandler serves devices with attributes.
+type attributeDeviceHandler map[string]map[string]string
+
+func (h attributeDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (h attributeDeviceHandler) GetDevices(ctx context.Context) (*dh.DeviceList, error) {
+	return testDeviceList(len(h)), nil
+}
+
+func (h attributeDeviceHandler) GetDeviceAttributes(id string) map[string]string {
+	return h[id]
+}
+
+func pendingPod(name string, devices int64, selector string, created time.Time) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				DpuResourceName: *resource.NewQuantity(devices, resource.DecimalSI),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	if selector != "" {
+		pod.Annotations = map[string]string{DeviceSelectorAnnotation: selector}
+	}
+	return pod
+}
+
+var _ = Describe("Preferred allocation", func() {
+	var (
+		dp  *dpServer
+		now time.Time
+	)
+
+	preferred := func(size int32, mustInclude ...string) []string {
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs:   []string{"dev03", "dev00", "dev02", "dev01"},
+				MustIncludeDeviceIDs: mustInclude,
+				AllocationSize:       size,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.ContainerResponses[0].DeviceIDs
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(attributeDeviceHandler{
+			"dev00": {"pool": "slow"},
+			"dev01": {"pool": "fast"},
+			"dev02": {"pool": "slow"},
+			"dev03": {"pool": "fast"},
+		})(dp)
+	})
+
+	It("should prefer the lowest device IDs without annotation", func() {
+		WithDeviceSelectors(fakePodLister{pendingPod("plain", 2, "", now)})(dp)
+		Expect(preferred(2)).To(Equal([]string{"dev00", "dev01"}))
+	})
+
+	It("should prefer the devices matching the selector of the pending pod", func() {
+		WithDeviceSelectors(fakePodLister{pendingPod("fast", 2, "pool=fast", now)})(dp)
+		Expect(preferred(2)).To(Equal([]string{"dev01", "dev03"}))
+	})
+
+	It("should keep the devices Kubelet requires", func() {
+		WithDeviceSelectors(fakePodLister{pendingPod("fast", 2, "pool=fast", now)})(dp)
+		Expect(preferred(2, "dev02")).To(Equal([]string{"dev02", "dev01"}))
+	})
+
+	It("should fill up with non-matching devices", func() {
+		WithDeviceSelectors(fakePodLister{pendingPod("fast", 3, "pool=fast", now)})(dp)
+		Expect(preferred(3)).To(Equal([]string{"dev01", "dev03", "dev00"}))
+	})
+
+	It("should only consider pending pods requesting as many devices", func() {
+		running := pendingPod("running", 2, "pool=fast", now)
+		running.Status.Phase = corev1.PodRunning
+		WithDeviceSelectors(fakePodLister{
+			running,
+			pendingPod("other-size", 1, "pool=fast", now),
+			pendingPod("slow", 2, "pool=slow", now.Add(time.Second)),
+		})(dp)
+		Expect(preferred(2)).To(Equal([]string{"dev00", "dev02"}))
+	})
+
+	It("should advertise GetPreferredAllocation only with device selectors", func() {
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.GetPreferredAllocationAvailable).To(BeFalse())
+
+		WithDeviceSelectors(fakePodLister{})(dp)
+		opts, err = dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.GetPreferredAllocationAvailable).To(BeTrue())
+	})
+})
+
+var _ = Describe("Node pod lister", func() {
+	It("should only list the pods of its node", func() {
+		onNode := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "local"}, Spec: corev1.PodSpec{NodeName: "node1"}}
+		reader := &fieldSelectingReader{pods: []corev1.Pod{
+			onNode,
+			{ObjectMeta: metav1.ObjectMeta{Name: "remote"}, Spec: corev1.PodSpec{NodeName: "node2"}},
+		}}
+
+		pods, err := NewNodePodLister(reader, "node1").ListPods(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(Equal([]corev1.Pod{onNode}))
+		Expect(reader.selectors).To(Equal([]string{"spec.nodeName=node1"}))
+	})
+
+	It("should index the pods by node", func() {
+		indexer := fakeFieldIndexer{}
+		Expect(IndexPodsByNode(context.Background(), indexer)).To(Succeed())
+		Expect(indexer).To(HaveKey(PodNodeNameField))
+		Expect(indexer[PodNodeNameField](&corev1.Pod{Spec: corev1.PodSpec{NodeName: "node1"}})).To(Equal([]string{"node1"}))
+	})
+})
@@ -0,0 +1,68 @@
+package deviceplugin
+
+import (
+	"os"
+	"time"
+)
+
+const defaultReadinessPollInterval = time.Second
+
+// readinessGate delays registering with Kubelet until a driver container has
+// written its readiness file, e.g. once the DPUs are programmed.
+type readinessGate struct {
+	path string
+	// timeout is how long to wait for the file, zero waits forever.
+	timeout time.Duration
+	// proceedOnTimeout registers anyway once the timeout passed, otherwise
+	// the Device Plugin stays unregistered until the file appears.
+	proceedOnTimeout bool
+	pollInterval     time.Duration
+}
+
+// waitForReadinessFile blocks until the readiness file exists, or the timeout
+// passed if the gate proceeds on timeout. It returns false if the Device
+// Plugin is stopped in the meantime.
+func (dp *dpServer) waitForReadinessFile() bool {
+	gate := dp.readiness
+	if gate == nil {
+		return true
+	}
+
+	start := dp.clock.Now()
+	timedOut := false
+	for {
+		if _, err := os.Stat(gate.path); err == nil {
+			dp.log.Info("Readiness file found, registering with Kubelet", "path", gate.path, "waited", dp.clock.Since(start))
+			return true
+		}
+		if !timedOut && gate.timeout > 0 && dp.clock.Since(start) >= gate.timeout {
+			timedOut = true
+			if gate.proceedOnTimeout {
+				dp.log.Info("Warning: readiness file didn't appear in time, registering with Kubelet anyway", "path", gate.path, "timeout", gate.timeout)
+				return true
+			}
+			dp.log.Info("Warning: readiness file didn't appear in time, not registering with Kubelet until it does", "path", gate.path, "timeout", gate.timeout)
+		}
+
+		select {
+		case <-dp.stopCh:
+			return false
+		case <-time.After(gate.pollInterval):
+		}
+	}
+}
+
+// WithReadinessFile doesn't register with Kubelet, and so doesn't advertise
+// any devices, until the file at path exists. After timeout, zero meaning
+// never, the Device Plugin registers anyway if proceedOnTimeout is set, or
+// else keeps waiting for the file.
+func WithReadinessFile(path string, timeout time.Duration, proceedOnTimeout bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.readiness = &readinessGate{
+			path:             path,
+			timeout:          timeout,
+			proceedOnTimeout: proceedOnTimeout,
+			pollInterval:     defaultReadinessPollInterval,
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package deviceplugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Readiness file", func() {
+	var (
+		dp        *dpServer
+		kubelet   *fakeKubelet
+		readyFile string
+	)
+
+	newDevicePlugin := func(timeout time.Duration, proceedOnTimeout bool) {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		readyFile = filepath.Join(root, "ready")
+
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(root), WithRegisterVerification(time.Second, 1),
+			WithReadinessFile(readyFile, timeout, proceedOnTimeout))
+		dp.readiness.pollInterval = 10 * time.Millisecond
+		DeferCleanup(func() { dp.stopOnce.Do(func() { close(dp.stopCh) }) })
+
+		kubelet = &fakeKubelet{dp: dp, contactFrom: 1}
+		socket := dp.pathManager.KubeletEndPoint()
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o755)).To(Succeed())
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		pluginapi.RegisterRegistrationServer(server, kubelet)
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+	}
+
+	register := func() chan error {
+		registered := make(chan error, 1)
+		go func() {
+			registered <- dp.registerWhenVendorCompatible()
+		}()
+		return registered
+	}
+
+	It("should only register once the file appears", func() {
+		newDevicePlugin(0, false)
+		registered := register()
+		Consistently(kubelet.count, 200*time.Millisecond).Should(BeZero())
+
+		Expect(os.WriteFile(readyFile, nil, 0o644)).To(Succeed())
+		Eventually(registered).Should(Receive(BeNil()))
+		Expect(kubelet.count()).To(Equal(1))
+	})
+
+	It("should register anyway after the timeout when configured to proceed", func() {
+		newDevicePlugin(100*time.Millisecond, true)
+		Eventually(register()).Should(Receive(BeNil()))
+		Expect(kubelet.count()).To(Equal(1))
+	})
+
+	It("should keep waiting after the timeout when configured to stay not ready", func() {
+		newDevicePlugin(50*time.Millisecond, false)
+		registered := register()
+		Consistently(registered, 300*time.Millisecond).ShouldNot(Receive())
+		Expect(kubelet.count()).To(BeZero())
+
+		dp.stopOnce.Do(func() { close(dp.stopCh) })
+		Eventually(registered).Should(Receive(BeNil()))
+		Expect(kubelet.count()).To(BeZero())
+	})
+})
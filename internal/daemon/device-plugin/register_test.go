@@ -0,0 +1,293 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// fakeKubelet accepts registrations, but only contacts the Device Plugin
+// from the registration number contactFrom on.
+type fakeKubelet struct {
+	dp          *dpServer
+	contactFrom int
+
+	// failFirst registrations fail with failErr.
+	failFirst int
+	failErr   error
+
+	mu            sync.Mutex
+	registrations int
+}
+
+func (k *fakeKubelet) Register(ctx context.Context, r *pluginapi.RegisterRequest) (*pluginapi.Empty, error) {
+	k.mu.Lock()
+	k.registrations++
+	if k.registrations <= k.failFirst {
+		k.mu.Unlock()
+		return nil, k.failErr
+	}
+	contact := k.contactFrom > 0 && k.registrations >= k.contactFrom
+	k.mu.Unlock()
+
+	if contact {
+		go k.dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+	}
+	return &pluginapi.Empty{}, nil
+}
+
+func (k *fakeKubelet) count() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.registrations
+}
+
+// breakingListener fails to accept connections once broken.
+type breakingListener struct {
+	net.Listener
+	broken atomic.Bool
+}
+
+func (l *breakingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && l.broken.Load() {
+		return nil, fmt.Errorf("listener broken")
+	}
+	return conn, err
+}
+
+func (l *breakingListener) breakNow() {
+	l.broken.Store(true)
+	l.Listener.Close()
+}
+
+// versionedVendorPlugin reports a version that tests can change.
+type versionedVendorPlugin struct {
+	plugin.VendorPlugin
+
+	mu      sync.Mutex
+	version string
+}
+
+func (v *versionedVendorPlugin) GetVersion(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.version, nil
+}
+
+func (v *versionedVendorPlugin) upgrade(version string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.version = version
+}
+
+var _ = Describe("Kubelet registration", func() {
+	var (
+		dp      *dpServer
+		kubelet *fakeKubelet
+	)
+
+	BeforeEach(func() {
+		// Keep the socket paths short, unix socket paths are limited to 108 bytes.
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(root), WithRegisterVerification(200*time.Millisecond, 3),
+			WithRegisterThrottle(0, time.Minute, 10))
+		dp.registerSettleDelay = 10 * time.Millisecond
+		kubelet = &fakeKubelet{dp: dp}
+
+		socket := dp.pathManager.KubeletEndPoint()
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o755)).To(Succeed())
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		pluginapi.RegisterRegistrationServer(server, kubelet)
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+	})
+
+	It("should succeed once Kubelet contacts the Device Plugin", func() {
+		kubelet.contactFrom = 1
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(kubelet.count()).To(Equal(1))
+	})
+
+	It("should report the sockets it registered with", func() {
+		Expect(dp.GetInfo().Registration).To(BeNil())
+
+		kubelet.contactFrom = 1
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(dp.GetInfo().Registration).To(Equal(&RegistrationInfo{
+			KubeletSocket: dp.pathManager.KubeletEndPoint(),
+			PluginSocket:  dp.pathManager.PluginEndpoint(),
+			Endpoint:      "dpuNet.sock",
+		}))
+	})
+
+	It("should register again when the first registration doesn't take", func() {
+		kubelet.contactFrom = 2
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(kubelet.count()).To(Equal(2))
+	})
+
+	It("should give up when Kubelet never contacts the Device Plugin", func() {
+		err := dp.registerWithKubelet()
+		Expect(err).To(MatchError(ContainSubstring("did not contact resource")))
+		Expect(kubelet.count()).To(Equal(3))
+	})
+
+	It("should not count a contact from before the registration", func() {
+		dp.markKubeletContact()
+		WithRegisterVerification(200*time.Millisecond, 1)(dp)
+		Expect(dp.registerWithKubelet()).NotTo(Succeed())
+	})
+
+	It("should retry registrations Kubelet fails transiently", func() {
+		kubelet.contactFrom = 1
+		kubelet.failFirst = 2
+		kubelet.failErr = status.Error(codes.Unavailable, "kubelet is restarting")
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(kubelet.count()).To(Equal(3))
+	})
+
+	It("should give up once the retry timeout passes", func() {
+		kubelet.failFirst = 100
+		kubelet.failErr = status.Error(codes.Unavailable, "kubelet is restarting")
+		WithRegisterRetry(250 * time.Millisecond)(dp)
+		err := dp.registerWithKubelet()
+		Expect(err).To(MatchError(ContainSubstring("after 2 attempts")))
+		Expect(kubelet.count()).To(Equal(2))
+	})
+
+	It("should wait between registrations on the clock of the retry timeout", func() {
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		kubelet.failFirst = 100
+		kubelet.failErr = status.Error(codes.Unavailable, "kubelet is restarting")
+		WithRegisterRetry(time.Minute)(dp)
+		done := make(chan error, 1)
+		go func() {
+			done <- dp.registerWithKubelet()
+		}()
+
+		Eventually(clock.HasWaiters).Should(BeTrue())
+		Consistently(kubelet.count, 300*time.Millisecond).Should(Equal(1))
+
+		var err error
+		Eventually(func() bool {
+			if clock.HasWaiters() {
+				clock.Step(registerRetryMaxDelay)
+			}
+			select {
+			case err = <-done:
+				return true
+			default:
+				return false
+			}
+		}).Should(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("unable to register resource")))
+	})
+
+	It("should not retry registrations Kubelet rejects", func() {
+		kubelet.failFirst = 100
+		kubelet.failErr = fmt.Errorf(`the ResourceName "openshift.io/dpu net" is invalid`)
+		err := dp.registerWithKubelet()
+		Expect(err).To(MatchError(ContainSubstring("Kubelet rejected resource")))
+		Expect(kubelet.count()).To(Equal(1))
+	})
+
+	It("should space out registrations", func() {
+		kubelet.contactFrom = 1
+		WithRegisterThrottle(300*time.Millisecond, time.Minute, 10)(dp)
+		// The interval is between the starts of the registrations.
+		start := time.Now()
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(dp.registerWithKubelet()).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 300*time.Millisecond))
+		Expect(kubelet.count()).To(Equal(2))
+	})
+
+	It("should stop serving and return when the server fails after registering", func() {
+		kubelet.contactFrom = 1
+		inner, err := net.Listen("unix", dp.pluginEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		lis := &breakingListener{Listener: inner}
+		pluginapi.RegisterDevicePluginServer(dp.grpcServer, dp)
+		healthpb.RegisterHealthServer(dp.grpcServer, dp.healthServer)
+		dp.startedWg.Add(1)
+		served := make(chan error, 1)
+		go func() {
+			served <- dp.Serve(lis)
+		}()
+		DeferCleanup(dp.Stop)
+
+		Eventually(kubelet.count).Should(Equal(1))
+		dp.setVendorConnected(true)
+		Expect(ProbeHealth(context.Background(), dp.pluginEndpoint)).To(Succeed())
+
+		lis.breakNow()
+		Eventually(served).Should(Receive(MatchError(ContainSubstring("listener broken"))))
+		resp, err := dp.healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Status).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+	})
+
+	Context("with a minimum vendor plugin version", func() {
+		var vsp *versionedVendorPlugin
+
+		BeforeEach(func() {
+			vsp = &versionedVendorPlugin{version: "1.1.4"}
+			dp.vsp = vsp
+			WithMinVendorVersion("1.2.0")(dp)
+			dp.vendorVersionRetryInterval = 10 * time.Millisecond
+			kubelet.contactFrom = 1
+		})
+
+		It("should only register once the vendor plugin is upgraded", func() {
+			done := make(chan error, 1)
+			go func() {
+				done <- dp.registerWhenVendorCompatible()
+			}()
+			Consistently(kubelet.count, 200*time.Millisecond).Should(BeZero())
+
+			vsp.upgrade("v1.2.1")
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(kubelet.count()).To(Equal(1))
+		})
+
+		It("should not register a vendor plugin that doesn't report its version", func() {
+			vsp.upgrade("")
+			Expect(dp.checkVendorVersion(context.Background())).To(MatchError(ContainSubstring("doesn't report its version")))
+		})
+
+		It("should give up waiting when the Device Plugin stops", func() {
+			dp.shuttingDown = true
+			Expect(dp.registerWhenVendorCompatible()).To(Succeed())
+			Expect(kubelet.count()).To(BeZero())
+		})
+
+		It("should reject an invalid minimum version", func() {
+			WithMinVendorVersion("latest")(dp)
+			_, err := dp.Listen()
+			Expect(err).To(MatchError(ContainSubstring(`invalid minimum vendor plugin version "latest"`)))
+		})
+	})
+})
@@ -0,0 +1,65 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	defaultRegisterRetryTimeout = time.Minute
+	registerRetryInitialDelay   = 100 * time.Millisecond
+	registerRetryMaxDelay       = 5 * time.Second
+	// registerCallTimeout bounds a single Register call, a hung Kubelet is
+	// retried like one refusing connections.
+	registerCallTimeout = 10 * time.Second
+)
+
+// isPermanentRegisterError returns whether Kubelet rejected the registration
+// itself, e.g. for an invalid resource name or an unsupported API version, in
+// which case registering again can't succeed. Kubelet reports these as plain
+// errors, so they are recognized by their message.
+func isPermanentRegisterError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Unimplemented, codes.PermissionDenied, codes.FailedPrecondition:
+		return true
+	case codes.Unknown:
+		msg := status.Convert(err).Message()
+		return strings.Contains(msg, "is not supported by kubelet") || strings.Contains(msg, "is invalid")
+	}
+	return false
+}
+
+// registerWithRetry registers with Kubelet, retrying with exponential backoff
+// while it fails transiently, e.g. because Kubelet is restarting, until the
+// retry timeout passes. Permanent rejections fail right away.
+func (dp *dpServer) registerWithRetry(client pluginapi.RegistrationClient, request *pluginapi.RegisterRequest) error {
+	deadline := dp.clock.Now().Add(dp.registerRetryTimeout)
+	delay := registerRetryInitialDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), registerCallTimeout)
+		_, err := client.Register(ctx, request)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if isPermanentRegisterError(err) {
+			return fmt.Errorf("Kubelet rejected resource %s: %v", dp.resourceName, err)
+		}
+		if dp.clock.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("unable to register resource %s with Kubelet after %d attempts: %v", dp.resourceName, attempt, err)
+		}
+		dp.log.Info("Registering with Kubelet failed, retrying", "attempt", attempt, "retryIn", delay, "err", err)
+		select {
+		case <-dp.stopCh:
+			return fmt.Errorf("resource %s stopped while registering with Kubelet: %v", dp.resourceName, err)
+		case <-dp.clock.After(delay):
+		}
+		delay = min(delay*2, registerRetryMaxDelay)
+	}
+}
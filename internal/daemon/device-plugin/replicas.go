@@ -0,0 +1,55 @@
+package deviceplugin
+
+import (
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// physicalDevice returns the device a logical device of a shared device
+// stands for, or the device itself when it isn't shared.
+func (dp *dpServer) physicalDevice(id string) string {
+	if handler, ok := dp.deviceHandler.(dh.ReplicaHandler); ok {
+		if physical := handler.GetPhysicalDevice(id); physical != "" {
+			return physical
+		}
+	}
+	return id
+}
+
+// physicalCapacity returns how many devices may be advertised for the
+// given devices: one per distinct physical device, or one per replica the
+// vendor plugin reported for a shared device. Logical devices beyond that
+// are a bug in sharing the devices.
+func (dp *dpServer) physicalCapacity(devices *dh.DeviceList) int {
+	handler, _ := dp.deviceHandler.(dh.ReplicaHandler)
+	seen := make(map[string]bool, len(*devices))
+	capacity := 0
+	for id := range *devices {
+		physical := dp.physicalDevice(id)
+		if seen[physical] {
+			continue
+		}
+		seen[physical] = true
+		replicas := 1
+		if handler != nil {
+			replicas = max(handler.GetDeviceReplicas(physical), 1)
+		}
+		capacity += replicas
+	}
+	return capacity
+}
+
+// physicalDevices returns the devices the given devices stand for, in order
+// and without duplicates, since a container may be allocated several logical
+// devices of the same shared device.
+func (dp *dpServer) physicalDevices(ids []string) []string {
+	physical := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		p := dp.physicalDevice(id)
+		if !seen[p] {
+			seen[p] = true
+			physical = append(physical, p)
+		}
+	}
+	return physical
+}
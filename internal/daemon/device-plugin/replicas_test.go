@@ -0,0 +1,136 @@
+package deviceplugin
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// replicaDeviceHandler serves logical devices standing for the physical
+// devices they map to, as the handler does with replicas enabled. Each
+// shared device reports as many replicas as it has logical devices.
+type replicaDeviceHandler struct {
+	changingDeviceHandler
+	physical map[string]string
+	replicas map[string]int
+}
+
+func newReplicaDeviceHandler(physical map[string]string) *replicaDeviceHandler {
+	replicas := make(map[string]int)
+	for id, p := range physical {
+		if id != p {
+			replicas[p]++
+		}
+	}
+	return &replicaDeviceHandler{
+		changingDeviceHandler: changingDeviceHandler{ids: slices.Sorted(maps.Keys(physical))},
+		physical:              physical,
+		replicas:              replicas,
+	}
+}
+
+func (h *replicaDeviceHandler) GetPhysicalDevice(id string) string {
+	if h.physical[id] == id {
+		return ""
+	}
+	return h.physical[id]
+}
+
+func (h *replicaDeviceHandler) GetDeviceReplicas(physical string) int {
+	return h.replicas[physical]
+}
+
+var _ = Describe("Shared device capacity", func() {
+	var dp *dpServer
+	var stream *lockedListAndWatchServer
+
+	listAndWatch := func(handler *replicaDeviceHandler) {
+		dp = newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		ctx, cancel := context.WithCancel(context.Background())
+		stream = &lockedListAndWatchServer{ctx: ctx}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	}
+
+	It("should advertise every replica the vendor plugin reports", func() {
+		listAndWatch(newReplicaDeviceHandler(map[string]string{"dev0_r0": "dev0", "dev0_r1": "dev0", "dev0_r2": "dev0", "dev1": "dev1"}))
+		Eventually(stream.sends).Should(Equal([][]string{{"dev0_r0", "dev0_r1", "dev0_r2", "dev1"}}))
+		Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonOverCapacity))).To(Equal(0.0))
+	})
+
+	It("should not advertise more logical devices than replicas reported", func() {
+		handler := newReplicaDeviceHandler(map[string]string{"dev0_r0": "dev0", "dev0_r1": "dev0", "dev0_r2": "dev0", "dev1": "dev1"})
+		handler.replicas["dev0"] = 2
+		listAndWatch(handler)
+		Eventually(stream.sends).Should(HaveLen(1))
+		Expect(stream.sends()[0]).To(HaveLen(3))
+		Expect(gaugeValue(droppedDevices.WithLabelValues(dp.resourceName, dropReasonOverCapacity))).To(Equal(1.0))
+	})
+})
+
+var _ = Describe("Shared devices", func() {
+	var (
+		dp      *dpServer
+		service *fakeDeviceService
+	)
+
+	newDevicePlugin := func(opts ...func(*plugin.GrpcPlugin)) {
+		pathManager := *utils.NewPathManager(GinkgoT().TempDir())
+		opts = append(opts, plugin.WithPathManager(pathManager), plugin.WithDeviceServiceClient(service))
+		vsp, err := plugin.NewGrpcPlugin(true, "", nil, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(vsp.Close)
+		dp = NewDevicePlugin(vsp, true, pathManager)
+		Expect(dp.SetupDevices()).To(Succeed())
+		devices, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		dp.setDeviceCache(devices)
+	}
+
+	BeforeEach(func() {
+		service = &fakeDeviceService{}
+		service.set(&pb.Device{ID: "dev0", Replicas: 3}, &pb.Device{ID: "dev1"})
+	})
+
+	It("should advertise a logical device per replica", func() {
+		newDevicePlugin(plugin.WithDeviceReplicas(true))
+		Expect(slices.Collect(maps.Keys(dp.cachedDevices()))).To(ConsistOf("dev0_r0", "dev0_r1", "dev0_r2", "dev1"))
+	})
+
+	It("should advertise devices whole unless enabled", func() {
+		newDevicePlugin()
+		Expect(slices.Collect(maps.Keys(dp.cachedDevices()))).To(ConsistOf("dev0", "dev1"))
+	})
+
+	It("should allocate the shared device for its logical devices", func() {
+		newDevicePlugin(plugin.WithDeviceReplicas(true))
+		WithAllocationPayloadEnv(true)(dp)
+		resp, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0_r1"}, []string{"dev0_r2", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKeyWithValue(devicesEnvName, "dev0"))
+		payload, err := DecodeAllocationPayload(resp.ContainerResponses[1].Envs[payloadEnvName])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payload.Devices).To(HaveLen(2))
+		Expect(payload.Devices[0].ID).To(Equal("dev0_r2"))
+		Expect(payload.Devices[0].PhysicalID).To(Equal("dev0"))
+		Expect(payload.Devices[1].ID).To(Equal("dev1"))
+		Expect(payload.Devices[1].PhysicalID).To(BeEmpty())
+	})
+})
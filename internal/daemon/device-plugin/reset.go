@@ -0,0 +1,49 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// resetDevices has the vendor plugin reset the devices of a starting
+// container, so that it doesn't see the queues or firmware context left by
+// the previous user. A shared device is only reset while no other container
+// holds one of its replicas, as a reset would wipe their state too.
+func (dp *dpServer) resetDevices(ctx context.Context, ids []string) error {
+	if !dp.resetOnStart || dp.vsp == nil {
+		return nil
+	}
+	for _, id := range dp.physicalDevices(ids) {
+		if others := dp.otherReplicasAllocated(id, ids); len(others) > 0 {
+			dp.log.Info("Not resetting shared device, other containers hold replicas of it", "id", id, "replicas", others)
+			continue
+		}
+		if err := dp.vsp.ResetDevice(ctx, id); err != nil {
+			return fmt.Errorf("failed to reset device %s: %v", id, err)
+		}
+		dp.log.V(1).Info("Reset device before container start", "id", id)
+	}
+	return nil
+}
+
+// otherReplicasAllocated returns the allocated logical devices of the
+// physical device that are not among ids.
+func (dp *dpServer) otherReplicasAllocated(physical string, ids []string) []string {
+	var others []string
+	for _, a := range dp.allocations.list() {
+		if dp.physicalDevice(a.DeviceID) == physical && !slices.Contains(ids, a.DeviceID) {
+			others = append(others, a.DeviceID)
+		}
+	}
+	return others
+}
+
+// WithDeviceReset makes Kubelet call PreStartContainer, which has the vendor
+// plugin reset every device of the container through ResetDevice before it
+// starts. A failed reset keeps the container from starting.
+func WithDeviceReset(enabled bool) func(*dpServer) {
+	return func(d *dpServer) {
+		d.resetOnStart = enabled
+	}
+}
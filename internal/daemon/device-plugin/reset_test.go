@@ -0,0 +1,86 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// resetVendorPlugin records the devices it resets, failing the given ones.
+type resetVendorPlugin struct {
+	plugin.VendorPlugin
+
+	mu     sync.Mutex
+	reset  []string
+	broken map[string]bool
+}
+
+func (v *resetVendorPlugin) ResetDevice(ctx context.Context, deviceID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.broken[deviceID] {
+		return fmt.Errorf("firmware context stuck")
+	}
+	v.reset = append(v.reset, deviceID)
+	return nil
+}
+
+var _ = Describe("Device reset", func() {
+	var (
+		dp  *dpServer
+		vsp *resetVendorPlugin
+	)
+
+	preStart := func(ids ...string) error {
+		_, err := dp.PreStartContainer(context.Background(), &pluginapi.PreStartContainerRequest{DevicesIDs: ids})
+		return err
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+		vsp = &resetVendorPlugin{broken: map[string]bool{"dev2": true}}
+		dp.vsp = vsp
+	})
+
+	It("should not reset devices by default", func() {
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.PreStartRequired).To(BeFalse())
+
+		Expect(preStart("dev0")).To(Succeed())
+		Expect(vsp.reset).To(BeEmpty())
+	})
+
+	It("should reset every device before the container starts", func() {
+		WithDeviceReset(true)(dp)
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.PreStartRequired).To(BeTrue())
+
+		Expect(preStart("dev1", "dev0")).To(Succeed())
+		Expect(vsp.reset).To(Equal([]string{"dev1", "dev0"}))
+	})
+
+	It("should not reset a shared device under the containers holding its other replicas", func() {
+		WithDeviceReset(true)(dp)
+		WithDeviceHandler(newReplicaDeviceHandler(map[string]string{"dev0_r0": "dev0", "dev0_r1": "dev0", "dev1": "dev1"}))(dp)
+
+		dp.allocations.record([]string{"dev0_r0"}, nil, dp.clock.Now())
+		Expect(preStart("dev0_r0")).To(Succeed())
+		Expect(vsp.reset).To(Equal([]string{"dev0"}))
+
+		dp.allocations.record([]string{"dev0_r1", "dev1"}, nil, dp.clock.Now())
+		Expect(preStart("dev0_r1", "dev1")).To(Succeed())
+		Expect(vsp.reset).To(Equal([]string{"dev0", "dev1"}))
+	})
+
+	It("should keep the container from starting when a reset fails", func() {
+		WithDeviceReset(true)(dp)
+		Expect(preStart("dev0", "dev2")).To(MatchError(ContainSubstring("failed to reset device dev2: firmware context stuck")))
+	})
+})
@@ -0,0 +1,29 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validateResourceName checks that Kubelet will accept the name as an
+// extended resource, the same way Kubernetes validates it: a domain-prefixed
+// qualified name outside of the kubernetes.io domains, which stays valid as a
+// resource quota name with the "requests." prefix.
+func validateResourceName(name string) error {
+	domain, _, found := strings.Cut(name, "/")
+	if !found {
+		return fmt.Errorf("invalid resource name %q: extended resources must be prefixed with a domain, e.g. %s", name, DpuResourceName)
+	}
+	if domain == "kubernetes.io" || strings.HasSuffix(domain, ".kubernetes.io") {
+		return fmt.Errorf("invalid resource name %q: the %s domain is reserved for native resources", name, domain)
+	}
+	if strings.HasPrefix(name, "requests.") {
+		return fmt.Errorf("invalid resource name %q: the requests. prefix is reserved for resource quotas", name)
+	}
+	if errs := validation.IsQualifiedName("requests." + name); len(errs) > 0 {
+		return fmt.Errorf("invalid resource name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package deviceplugin
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+)
+
+var _ = Describe("Resource names", func() {
+	DescribeTable("should accept extended resource names",
+		func(name string) {
+			Expect(validateResourceName(name)).To(Succeed())
+		},
+		Entry("the default resource", DpuResourceName),
+		Entry("a resource per SKU", "openshift.io/dpu-a"),
+		Entry("another domain", "example.com/accelerator"),
+	)
+
+	DescribeTable("should reject names Kubelet won't register",
+		func(name, reason string) {
+			Expect(validateResourceName(name)).To(MatchError(ContainSubstring(reason)))
+		},
+		Entry("without a domain", "dpu", "must be prefixed with a domain"),
+		Entry("a native resource", "kubernetes.io/dpu", "reserved for native resources"),
+		Entry("a native subdomain", "node.kubernetes.io/dpu", "reserved for native resources"),
+		Entry("the quota prefix", "requests.openshift.io/dpu", "reserved for resource quotas"),
+		Entry("an invalid name", "openshift.io/dpu_", "invalid resource name"),
+		Entry("a nested name", "openshift.io/dpu/a", "invalid resource name"),
+	)
+
+	It("should refuse to listen with an invalid resource name", func() {
+		dp := NewDevicePlugin(nil, true, *utils.NewPathManager(GinkgoT().TempDir()), WithResourceName("dpu", "dpu.sock"))
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring(`invalid resource name "dpu"`)))
+	})
+})
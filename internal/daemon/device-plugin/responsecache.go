@@ -0,0 +1,166 @@
+package deviceplugin
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// responseCacheTTL bounds how long a cached response is served. The vendor
+// plugin may change the mounts, device nodes or annotations of a device
+// without the device itself changing, a retrying Kubelet still gets the
+// cached response.
+const responseCacheTTL = 30 * time.Second
+
+// responseCache keeps the computed response of each set of devices, Kubelet
+// tends to allocate the same devices over and over while retrying a pod.
+// Entries are dropped as soon as one of their devices changes or goes away,
+// when the vendor plugin reconnects, and after responseCacheTTL.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]responseCacheEntry
+	// byDevice maps a device to the keys of the entries it is part of.
+	byDevice map[string]map[string]bool
+}
+
+type responseCacheEntry struct {
+	resp     *pluginapi.ContainerAllocateResponse
+	storedAt time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries:  make(map[string]responseCacheEntry),
+		byDevice: make(map[string]map[string]bool),
+	}
+}
+
+func responseCacheKey(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// get returns a copy of the cached response for the devices, if any and
+// stored less than responseCacheTTL before now.
+func (c *responseCache) get(ids []string, now time.Time) (*pluginapi.ContainerAllocateResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[responseCacheKey(ids)]
+	if !ok || now.Sub(entry.storedAt) >= responseCacheTTL {
+		return nil, false
+	}
+	return copyContainerResponse(entry.resp), true
+}
+
+func (c *responseCache) put(ids []string, resp *pluginapi.ContainerAllocateResponse, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := responseCacheKey(ids)
+	c.entries[key] = responseCacheEntry{resp: copyContainerResponse(resp), storedAt: now}
+	for _, id := range ids {
+		if c.byDevice[id] == nil {
+			c.byDevice[id] = make(map[string]bool)
+		}
+		c.byDevice[id][key] = true
+	}
+}
+
+// invalidate drops every entry the device is part of.
+func (c *responseCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.byDevice[id] {
+		delete(c.entries, key)
+	}
+	delete(c.byDevice, id)
+}
+
+// clear drops every entry.
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	clear(c.byDevice)
+}
+
+func (c *responseCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// copyContainerResponse copies the maps of a response, so that the caller can
+// add to them without changing the cached response.
+func copyContainerResponse(resp *pluginapi.ContainerAllocateResponse) *pluginapi.ContainerAllocateResponse {
+	c := *resp
+	if resp.Envs != nil {
+		c.Envs = make(map[string]string, len(resp.Envs))
+		for k, v := range resp.Envs {
+			c.Envs[k] = v
+		}
+	}
+	if resp.Annotations != nil {
+		c.Annotations = make(map[string]string, len(resp.Annotations))
+		for k, v := range resp.Annotations {
+			c.Annotations[k] = v
+		}
+	}
+	return &c
+}
+
+// invalidateChangedResponses drops the cached responses of the devices that
+// were removed or changed, e.g. became unhealthy, between old and new.
+func (dp *dpServer) invalidateChangedResponses(old, new map[string]pluginapi.Device) {
+	for id, oldDev := range old {
+		if newDev, ok := new[id]; !ok || !reflect.DeepEqual(oldDev, newDev) {
+			dp.responses.invalidate(id)
+		}
+	}
+}
+
+// containerResponse returns the response for the devices of a container,
+// from the cache if the same devices were allocated before.
+func (dp *dpServer) containerResponse(ctx context.Context, ids []string) (*pluginapi.ContainerAllocateResponse, error) {
+	if resp, ok := dp.responses.get(ids, dp.clock.Now()); ok {
+		allocateResponseCacheHitsTotal.WithLabelValues(dp.resourceName).Inc()
+		return resp, nil
+	}
+
+	resp, err := dp.buildContainerResponse(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	dp.responses.put(ids, resp, dp.clock.Now())
+	return resp, nil
+}
+
+// buildContainerResponse computes the parts of the response that only depend
+// on the devices of the container.
+func (dp *dpServer) buildContainerResponse(ctx context.Context, ids []string) (*pluginapi.ContainerAllocateResponse, error) {
+	containerResp := new(pluginapi.ContainerAllocateResponse)
+	containerResp.Envs = make(map[string]string)
+	if dp.numaEnv {
+		containerResp.Envs[numaEnvName] = dp.numaEnvValue(ids)
+	}
+
+	// Vendor plugins only know the shared devices, not their replicas.
+	physical := dp.physicalDevices(ids)
+	annotations, err := dp.containerAnnotations(ctx, physical)
+	if err != nil {
+		return nil, err
+	}
+	if len(annotations) > 0 {
+		containerResp.Annotations = annotations
+	}
+
+	if err := dp.addAllocateInfo(ctx, containerResp, physical); err != nil {
+		return nil, err
+	}
+	return containerResp, nil
+}
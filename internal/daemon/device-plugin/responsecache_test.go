@@ -0,0 +1,88 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Allocate response cache", func() {
+	var dp *dpServer
+
+	useTemplate := func(value string) {
+		t, err := newAnnotationTemplate(map[string]string{"dpu.example.com/dev-{{.Name}}": value})
+		Expect(err).NotTo(HaveOccurred())
+		dp.annotations = t
+	}
+
+	annotationOf := func(ids ...string) string {
+		resp, err := dp.Allocate(context.Background(), allocateRequest(ids))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ContainerResponses[0].Envs).To(HaveKey("NF-DEV"))
+		return resp.ContainerResponses[0].Annotations["dpu.example.com/dev-dev0"]
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		useTemplate("first")
+	})
+
+	It("should return the cached response for the same devices within the TTL", func() {
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		Expect(annotationOf("dev0", "dev1")).To(Equal("first"))
+		hits := counterValue(allocateResponseCacheHitsTotal.WithLabelValues(dp.resourceName))
+
+		// The template changing shows whether the response is computed
+		// again.
+		useTemplate("second")
+		clock.SetTime(clock.Now().Add(responseCacheTTL / 2))
+		Expect(annotationOf("dev1", "dev0")).To(Equal("first"))
+		Expect(counterValue(allocateResponseCacheHitsTotal.WithLabelValues(dp.resourceName))).To(Equal(hits + 1))
+
+		clock.SetTime(clock.Now().Add(responseCacheTTL / 2))
+		Expect(annotationOf("dev1", "dev0")).To(Equal("second"))
+		Expect(counterValue(allocateResponseCacheHitsTotal.WithLabelValues(dp.resourceName))).To(Equal(hits + 1))
+	})
+
+	It("should compute the response again once the vendor plugin reconnects", func() {
+		dp.setVendorConnected(true)
+		Expect(annotationOf("dev0", "dev1")).To(Equal("first"))
+		useTemplate("second")
+
+		dp.setVendorConnected(false)
+		dp.setVendorConnected(true)
+		Expect(annotationOf("dev0", "dev1")).To(Equal("second"))
+	})
+
+	It("should compute the response again once a device changes", func() {
+		Expect(annotationOf("dev0", "dev1")).To(Equal("first"))
+		useTemplate("second")
+
+		devices := dh.DeviceList{
+			"dev0": {ID: "dev0", Health: pluginapi.Healthy},
+			"dev1": {ID: "dev1", Health: pluginapi.Unhealthy},
+		}
+		dp.setDeviceCache(&devices)
+		Expect(dp.responses.len()).To(BeZero())
+
+		devices["dev1"] = pluginapi.Device{ID: "dev1", Health: pluginapi.Healthy}
+		dp.setDeviceCache(&devices)
+		Expect(annotationOf("dev0", "dev1")).To(Equal("second"))
+	})
+
+	It("should not let callers change the cached response", func() {
+		first, err := dp.containerResponse(context.Background(), []string{"dev0"})
+		Expect(err).NotTo(HaveOccurred())
+		first.Envs["NF-DEV"] = "dev0,"
+
+		second, err := dp.containerResponse(context.Background(), []string{"dev0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Envs).NotTo(HaveKey("NF-DEV"))
+	})
+})
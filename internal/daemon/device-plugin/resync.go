@@ -0,0 +1,96 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+const defaultResyncInterval = 30 * time.Second
+
+// watchResync gets the devices from the vendor plugin every resync interval,
+// independently of the ListAndWatch poll, which may be configured long or
+// backing off. Devices hot-plugged, or VFs created, since ListAndWatch last
+// got them wake it up, so that they're advertised without a restart, and
+// removed ones withdrawn. ListAndWatch stays the only writer of the device
+// cache, so that it matches what was advertised.
+func (dp *dpServer) watchResync() {
+	if dp.resyncInterval <= 0 {
+		return
+	}
+	dp.resyncWg.Add(1)
+	go func() {
+		defer dp.resyncWg.Done()
+		ctx, cancel := dp.stopContext(context.Background())
+		defer cancel()
+		timer := dp.clock.NewTimer(dp.resyncInterval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-dp.stopCh:
+				return
+			case <-timer.C():
+				if _, err := dp.resyncDevices(ctx); err != nil {
+					dp.log.Error(err, "Failed to resync the devices")
+				}
+				timer.Reset(dp.resyncInterval)
+			}
+		}
+	}()
+}
+
+// resyncDevices gets the devices from the vendor plugin and wakes up
+// ListAndWatch if devices were added or removed since it last got them.
+func (dp *dpServer) resyncDevices(ctx context.Context) (bool, error) {
+	devices, err := dp.getDevices(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get devices: %v", err)
+	}
+
+	dp.devicesMutex.RLock()
+	var added, removed []string
+	for id := range *devices {
+		if !dp.vendorDevices[id] {
+			added = append(added, id)
+		}
+	}
+	for id := range dp.vendorDevices {
+		if _, ok := (*devices)[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	dp.devicesMutex.RUnlock()
+
+	if len(added) == 0 && len(removed) == 0 {
+		return false, nil
+	}
+	dp.log.Info("Resync found added or removed devices", "added", added, "removed", removed)
+	dp.triggerUpdate()
+	return true, nil
+}
+
+// recordVendorDevices remembers the devices the vendor plugin reported to
+// ListAndWatch, before they're filtered, for resyncDevices to compare with.
+func (dp *dpServer) recordVendorDevices(devices *dh.DeviceList) {
+	ids := make(map[string]bool, len(*devices))
+	for id := range *devices {
+		ids[id] = true
+	}
+	dp.devicesMutex.Lock()
+	dp.vendorDevices = ids
+	dp.devicesMutex.Unlock()
+}
+
+// WithDevicesResync sets how often the devices are resynced with the vendor
+// plugin besides the ListAndWatch poll, every 30 seconds by default. A zero
+// interval uses the default and a negative one disables the resync.
+func WithDevicesResync(interval time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		if interval == 0 {
+			interval = defaultResyncInterval
+		}
+		d.resyncInterval = interval
+	}
+}
@@ -0,0 +1,120 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Device resync", func() {
+	It("should advertise hot-plugged devices and withdraw removed ones on the next poll", func() {
+		handler := &changingDeviceHandler{ids: []string{"dev0"}}
+		stream := &lockedListAndWatchServer{}
+		dp := newTestDevicePlugin()
+		WithDeviceHandler(handler)(dp)
+		WithCoalesceWindow(0)(dp)
+		// Below the floor, which only NewDevicePlugin enforces, so that the
+		// test doesn't wait for seconds.
+		dp.pollInterval = 50 * time.Millisecond
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			handler.set()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(HaveLen(1))
+
+		// No triggerUpdate: the poll alone must pick the changes up.
+		handler.set("dev0", "dev1")
+		Eventually(stream.sends).Should(HaveLen(2))
+		Expect(stream.sends()[1]).To(Equal([]string{"dev0", "dev1"}))
+
+		handler.set("dev1")
+		Eventually(stream.sends).Should(HaveLen(3))
+		Expect(stream.sends()[2]).To(Equal([]string{"dev1"}))
+	})
+
+	Context("between polls", func() {
+		var (
+			dp      *dpServer
+			handler *changingDeviceHandler
+			stream  *lockedListAndWatchServer
+		)
+
+		BeforeEach(func() {
+			handler = &changingDeviceHandler{ids: []string{"dev0"}}
+			dp = newTestDevicePlugin()
+			WithDeviceHandler(handler)(dp)
+			WithCoalesceWindow(0)(dp)
+			// Only the resync can pick the changes up.
+			dp.pollInterval = time.Hour
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stream = &lockedListAndWatchServer{ctx: ctx}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(dp.ListAndWatch(&pluginapi.Empty{}, stream)).To(Succeed())
+			}()
+			DeferCleanup(func() {
+				cancel()
+				Eventually(done).Should(BeClosed())
+			})
+			Eventually(stream.sends).Should(Equal([][]string{{"dev0"}}))
+		})
+
+		It("should advertise hot-plugged devices and withdraw removed ones", func() {
+			handler.set("dev0", "dev1")
+			Expect(dp.resyncDevices(context.Background())).To(BeTrue())
+			Eventually(stream.sends).Should(Equal([][]string{{"dev0"}, {"dev0", "dev1"}}))
+
+			handler.set("dev1")
+			Expect(dp.resyncDevices(context.Background())).To(BeTrue())
+			Eventually(stream.sends).Should(Equal([][]string{{"dev0"}, {"dev0", "dev1"}, {"dev1"}}))
+		})
+
+		It("should not wake up ListAndWatch while the devices didn't change", func() {
+			Expect(dp.resyncDevices(context.Background())).To(BeFalse())
+			Consistently(stream.sends, 100*time.Millisecond).Should(HaveLen(1))
+		})
+
+		It("should resync every interval", func() {
+			clock := clocktesting.NewFakeClock(time.Now())
+			WithClock(clock)(dp)
+			WithDevicesResync(time.Minute)(dp)
+			dp.watchResync()
+			DeferCleanup(dp.Stop)
+
+			handler.set("dev0", "dev1")
+			Eventually(clock.HasWaiters).Should(BeTrue())
+			clock.Step(time.Minute)
+			Eventually(stream.sends).Should(Equal([][]string{{"dev0"}, {"dev0", "dev1"}}))
+		})
+	})
+
+	It("should resync every 30 seconds by default and be disabled by a negative interval", func() {
+		dp := newTestDevicePlugin()
+		Expect(dp.resyncInterval).To(Equal(defaultResyncInterval))
+		WithDevicesResync(0)(dp)
+		Expect(dp.resyncInterval).To(Equal(defaultResyncInterval))
+
+		clock := clocktesting.NewFakeClock(time.Now())
+		WithClock(clock)(dp)
+		WithDevicesResync(-1)(dp)
+		dp.watchResync()
+		Consistently(clock.HasWaiters, 100*time.Millisecond).Should(BeFalse())
+	})
+})
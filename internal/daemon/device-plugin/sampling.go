@@ -0,0 +1,45 @@
+package deviceplugin
+
+import (
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// logSampler limits how often high-frequency informational events are
+// logged: only the 1st, (N+1)th, (2N+1)th... occurrence of each event is let
+// through. Errors must never go through the sampler.
+type logSampler struct {
+	every uint64
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func newLogSampler(every int) *logSampler {
+	if every < 1 {
+		every = 1
+	}
+	return &logSampler{
+		every:  uint64(every),
+		counts: make(map[string]uint64),
+	}
+}
+
+// allow reports whether this occurrence of event should be logged.
+func (s *logSampler) allow(event string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.counts[event]
+	s.counts[event] = n + 1
+	return n%s.every == 0
+}
+
+// sampledInfo logs an informational message of a high-frequency log site,
+// subject to sampling. Every message is sampled independently.
+func (dp *dpServer) sampledInfo(log logr.Logger, msg string, keysAndValues ...any) {
+	if dp.logSampler.allow(msg) {
+		log.Info(msg, keysAndValues...)
+	}
+}
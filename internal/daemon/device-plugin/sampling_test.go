@@ -0,0 +1,57 @@
+package deviceplugin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log sampling", func() {
+	var (
+		dp   *dpServer
+		logs []string
+	)
+
+	countLogs := func(substr string) int {
+		return strings.Count(strings.Join(logs, "\n"), substr)
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		logs = nil
+		dp.log = funcr.New(func(prefix, args string) {
+			logs = append(logs, args)
+		}, funcr.Options{Verbosity: 1})
+	})
+
+	It("should log every event by default", func() {
+		for i := 0; i < 4; i++ {
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(countLogs("DeviceID in Allocate")).To(Equal(4))
+	})
+
+	It("should reduce repeated log output while preserving errors", func() {
+		WithLogSampling(3)(dp)
+		for i := 0; i < 6; i++ {
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}, []string{"dev1"}))
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(countLogs("DeviceID in Allocate")).To(Equal(2))
+		Expect(countLogs("Rejecting allocation")).To(Equal(6))
+	})
+
+	It("should sample each event independently", func() {
+		s := newLogSampler(2)
+		Expect(s.allow("a")).To(BeTrue())
+		Expect(s.allow("b")).To(BeTrue())
+		Expect(s.allow("a")).To(BeFalse())
+		Expect(s.allow("a")).To(BeTrue())
+	})
+})
@@ -0,0 +1,97 @@
+package deviceplugin
+
+import (
+	"fmt"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// SchedulabilityReason says why pods can or can't currently be allocated
+// devices, the first applicable reason is reported.
+type SchedulabilityReason string
+
+const (
+	ReasonSchedulable       SchedulabilityReason = "Schedulable"
+	ReasonShuttingDown      SchedulabilityReason = "ShuttingDown"
+	ReasonVendorUnreachable SchedulabilityReason = "VendorUnreachable"
+	ReasonNotRegistered     SchedulabilityReason = "NotRegistered"
+	ReasonFrozen            SchedulabilityReason = "Frozen"
+	ReasonNoDevices         SchedulabilityReason = "NoDevices"
+	ReasonAllDrained        SchedulabilityReason = "AllDrained"
+	ReasonAllUnhealthy      SchedulabilityReason = "AllUnhealthy"
+	ReasonAllAllocated      SchedulabilityReason = "AllAllocated"
+)
+
+// DeviceCounts breaks the known devices down by what keeps them from being
+// allocated. Drained devices are only counted as drained.
+type DeviceCounts struct {
+	Total     int `json:"total"`
+	Drained   int `json:"drained"`
+	Unhealthy int `json:"unhealthy"`
+	Allocated int `json:"allocated"`
+	Available int `json:"available"`
+}
+
+// Schedulability is the response of the introspection "/schedulability"
+// endpoint.
+type Schedulability struct {
+	Schedulable bool                 `json:"schedulable"`
+	Reason      SchedulabilityReason `json:"reason"`
+	Message     string               `json:"message"`
+	Devices     DeviceCounts         `json:"devices"`
+}
+
+func (dp *dpServer) deviceCounts() DeviceCounts {
+	var counts DeviceCounts
+	for id, dev := range dp.cachedDevices() {
+		counts.Total++
+		switch {
+		case dp.isDrained(id):
+			counts.Drained++
+		case dev.Health != pluginapi.Healthy:
+			counts.Unhealthy++
+		case dp.allocations.isAllocated(id):
+			counts.Allocated++
+		default:
+			counts.Available++
+		}
+	}
+	return counts
+}
+
+// GetSchedulability explains why the node does or doesn't offer devices to
+// new pods, e.g. when it shows no allocatable devices despite having them.
+func (dp *dpServer) GetSchedulability() Schedulability {
+	dp.shutdownMutex.RLock()
+	shuttingDown := dp.shuttingDown
+	dp.shutdownMutex.RUnlock()
+	dp.servingMutex.Lock()
+	registered, vendorConnected := dp.registered, dp.vendorConnected
+	dp.servingMutex.Unlock()
+
+	s := Schedulability{Devices: dp.deviceCounts()}
+	counts := s.Devices
+	switch {
+	case shuttingDown:
+		s.Reason, s.Message = ReasonShuttingDown, "the Device Plugin is shutting down"
+	case !vendorConnected:
+		s.Reason, s.Message = ReasonVendorUnreachable, "the last attempt to get the devices from the vendor plugin failed"
+	case !registered:
+		s.Reason, s.Message = ReasonNotRegistered, "the Device Plugin isn't registered with Kubelet"
+	case dp.isFrozen():
+		s.Reason, s.Message = ReasonFrozen, fmt.Sprintf("allocations are frozen by %s", dp.freeze.path)
+	case counts.Total == 0:
+		s.Reason, s.Message = ReasonNoDevices, "the vendor plugin reports no devices"
+	case counts.Available > 0:
+		s.Schedulable = true
+		s.Reason, s.Message = ReasonSchedulable, fmt.Sprintf("%d of %d devices are available", counts.Available, counts.Total)
+	case counts.Drained == counts.Total:
+		s.Reason, s.Message = ReasonAllDrained, fmt.Sprintf("all %d devices are drained", counts.Total)
+	case counts.Allocated == 0:
+		s.Reason, s.Message = ReasonAllUnhealthy, fmt.Sprintf("%d devices are unhealthy and %d drained", counts.Unhealthy, counts.Drained)
+	default:
+		s.Reason, s.Message = ReasonAllAllocated, fmt.Sprintf("%d devices are allocated, %d unhealthy and %d drained", counts.Allocated,
+			counts.Unhealthy, counts.Drained)
+	}
+	return s
+}
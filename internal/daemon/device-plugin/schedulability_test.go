@@ -0,0 +1,75 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Schedulability", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1")
+		dp.setRegistered(true)
+		dp.setVendorConnected(true)
+	})
+
+	It("should be schedulable with available devices", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schedulability", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var s Schedulability
+		Expect(json.NewDecoder(rec.Body).Decode(&s)).To(Succeed())
+		Expect(s.Schedulable).To(BeTrue())
+		Expect(s.Reason).To(Equal(ReasonSchedulable))
+		Expect(s.Devices).To(Equal(DeviceCounts{Total: 2, Available: 2}))
+	})
+
+	It("should report the vendor plugin being down first", func() {
+		_, err := dp.DrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		dp.setVendorConnected(false)
+		s := dp.GetSchedulability()
+		Expect(s.Schedulable).To(BeFalse())
+		Expect(s.Reason).To(Equal(ReasonVendorUnreachable))
+	})
+
+	It("should report all devices being drained", func() {
+		for _, id := range []string{"dev0", "dev1"} {
+			_, err := dp.DrainDevice(id)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		s := dp.GetSchedulability()
+		Expect(s.Schedulable).To(BeFalse())
+		Expect(s.Reason).To(Equal(ReasonAllDrained))
+		Expect(s.Devices).To(Equal(DeviceCounts{Total: 2, Drained: 2}))
+	})
+
+	It("should tell unhealthy from allocated devices", func() {
+		_, err := dp.DrainDevice("dev0")
+		Expect(err).NotTo(HaveOccurred())
+		dev := dp.devices["dev1"]
+		dev.Health = pluginapi.Unhealthy
+		dp.devices["dev1"] = dev
+		Expect(dp.GetSchedulability().Reason).To(Equal(ReasonAllUnhealthy))
+
+		dev.Health = pluginapi.Healthy
+		dp.devices["dev1"] = dev
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+		s := dp.GetSchedulability()
+		Expect(s.Reason).To(Equal(ReasonAllAllocated))
+		Expect(s.Devices).To(Equal(DeviceCounts{Total: 2, Drained: 1, Allocated: 1}))
+	})
+
+	It("should report a Device Plugin that isn't registered", func() {
+		dp.setRegistered(false)
+		Expect(dp.GetSchedulability().Reason).To(Equal(ReasonNotRegistered))
+	})
+})
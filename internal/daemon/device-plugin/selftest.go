@@ -0,0 +1,49 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// selfTest checks that the Device Plugin can create a socket next to its
+// endpoint, serve on it, connect to it and round-trip GetDevicePluginOptions.
+// This catches environment problems (missing or read-only Kubelet directory,
+// broken gRPC setup) before the real socket is created and Kubelet is told
+// about it, where they would be much harder to diagnose.
+func (dp *dpServer) selfTest() error {
+	socket := dp.pluginEndpoint + ".selftest"
+	// A stale socket that can't be removed makes the Listen below fail.
+	_ = os.Remove(socket)
+
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("self-test failed to listen on %s, make sure the Kubelet device plugin directory exists and is writable: %v", socket, err)
+	}
+	defer os.Remove(socket)
+
+	server := grpc.NewServer()
+	pluginapi.RegisterDevicePluginServer(server, dp)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := dp.connectWithRetry("unix:" + socket)
+	if err != nil {
+		return fmt.Errorf("self-test failed to connect to %s: %v", socket, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pluginapi.NewDevicePluginClient(conn).GetDevicePluginOptions(ctx, &pluginapi.Empty{}); err != nil {
+		return fmt.Errorf("self-test failed to call GetDevicePluginOptions on %s: %v", socket, err)
+	}
+
+	dp.log.Info("Device Plugin self-test succeeded")
+	return nil
+}
@@ -0,0 +1,54 @@
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+)
+
+var _ = Describe("Self-test", func() {
+	var (
+		root string
+		dp   *dpServer
+	)
+
+	BeforeEach(func() {
+		// Keep the socket paths short, unix socket paths are limited to 108 bytes.
+		var err error
+		root, err = os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		dp = NewDevicePlugin(nil, true, *utils.NewPathManager(root))
+	})
+
+	It("should succeed when the socket directory is usable", func() {
+		Expect(os.MkdirAll(filepath.Dir(dp.pathManager.PluginEndpoint()), 0o755)).To(Succeed())
+		Expect(dp.selfTest()).To(Succeed())
+		Expect(dp.pathManager.PluginEndpoint() + ".selftest").NotTo(BeAnExistingFile())
+	})
+
+	It("should fail Listen with the socket directory diagnostic before running", func() {
+		// A file where the directory should be can't be written to, even by root.
+		dir := filepath.Dir(dp.pathManager.PluginEndpoint())
+		Expect(os.MkdirAll(filepath.Dir(dir), 0o755)).To(Succeed())
+		Expect(os.WriteFile(dir, nil, 0o644)).To(Succeed())
+
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("socket directory " + dir + " is not a directory")))
+		Expect(err).NotTo(MatchError(ContainSubstring("self-test")))
+	})
+
+	It("should fail Listen when the socket directory is read-only", func() {
+		if os.Geteuid() == 0 {
+			Skip("root can write to read-only directories")
+		}
+		dir := filepath.Dir(dp.pathManager.PluginEndpoint())
+		Expect(os.MkdirAll(dir, 0o555)).To(Succeed())
+
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("is not writable")))
+	})
+})
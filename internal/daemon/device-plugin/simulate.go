@@ -0,0 +1,72 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// Simulation is the response of the introspection "/simulate" endpoint.
+type Simulation struct {
+	// DeviceIDs are the devices GetPreferredAllocation would pick.
+	DeviceIDs []string `json:"deviceIDs"`
+	// Available is the number of devices that could currently be allocated.
+	Available   int  `json:"available"`
+	Satisfiable bool `json:"satisfiable"`
+}
+
+// availableDeviceIDs returns the devices Kubelet could currently allocate:
+// the healthy ones that are neither drained nor allocated.
+func (dp *dpServer) availableDeviceIDs() []string {
+	devices := dp.cachedDevices()
+	var available []string
+	for _, id := range sortedDeviceIDs(&devices) {
+		if devices[id].Health == pluginapi.Healthy && !dp.isDrained(id) && !dp.allocations.isAllocated(id) {
+			available = append(available, id)
+		}
+	}
+	return available
+}
+
+// SimulateAllocate returns the devices a container requesting count devices
+// would be allocated, without allocating them. The selector is matched
+// against the device attributes like DeviceSelectorAnnotation, when empty the
+// one of the pending pods is used as for an actual allocation.
+func (dp *dpServer) SimulateAllocate(ctx context.Context, count int, mustInclude []string, selector string) (Simulation, error) {
+	if count <= 0 {
+		return Simulation{}, fmt.Errorf("invalid device count %d", count)
+	}
+	available := dp.availableDeviceIDs()
+	isAvailable := make(map[string]bool, len(available))
+	for _, id := range available {
+		isAvailable[id] = true
+	}
+	for _, id := range mustInclude {
+		if !isAvailable[id] {
+			return Simulation{}, fmt.Errorf("required device %s is not available", id)
+		}
+	}
+
+	req := &pluginapi.ContainerPreferredAllocationRequest{
+		AvailableDeviceIDs:   available,
+		MustIncludeDeviceIDs: mustInclude,
+		AllocationSize:       int32(count),
+	}
+	var ids []string
+	if selector == "" {
+		ids = dp.preferredDevices(ctx, req)
+	} else {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			return Simulation{}, fmt.Errorf("invalid selector: %v", err)
+		}
+		ids = dp.pickDevices(req, parsed)
+	}
+	return Simulation{
+		DeviceIDs:   ids,
+		Available:   len(available),
+		Satisfiable: len(ids) == count,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Allocation simulation", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev00", "dev01", "dev02", "dev03", "dev04")
+		WithDeviceHandler(attributeDeviceHandler{
+			"dev00": {"pool": "fast"},
+			"dev01": {"pool": "slow"},
+			"dev02": {"pool": "slow"},
+			"dev03": {"pool": "fast"},
+			"dev04": {"pool": "fast"},
+		})(dp)
+		WithDeviceSelectors(fakePodLister{pendingPod("fast", 2, "pool=fast", time.Now())})(dp)
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev00"}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dp.DrainDevice("dev04")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should match the actual preferred allocation without side effects", func() {
+		allocations := dp.GetAllocations()
+		devices := dp.devices
+
+		simulation, err := dp.SimulateAllocate(context.Background(), 2, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation).To(Equal(Simulation{DeviceIDs: []string{"dev03", "dev01"}, Available: 3, Satisfiable: true}))
+
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs: []string{"dev01", "dev02", "dev03"},
+				AllocationSize:     2,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.DeviceIDs).To(Equal(resp.ContainerResponses[0].DeviceIDs))
+
+		Expect(dp.GetAllocations()).To(Equal(allocations))
+		Expect(dp.devices).To(Equal(devices))
+		Expect(dp.isDrained("dev04")).To(BeTrue())
+	})
+
+	It("should apply an explicit selector and required devices", func() {
+		simulation, err := dp.SimulateAllocate(context.Background(), 2, []string{"dev03"}, "pool=slow")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.DeviceIDs).To(Equal([]string{"dev03", "dev01"}))
+	})
+
+	It("should report requests that can't be satisfied", func() {
+		simulation, err := dp.SimulateAllocate(context.Background(), 4, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(simulation.DeviceIDs).To(HaveLen(3))
+		Expect(simulation.Satisfiable).To(BeFalse())
+
+		_, err = dp.SimulateAllocate(context.Background(), 1, []string{"dev00"}, "")
+		Expect(err).To(MatchError(ContainSubstring("required device dev00 is not available")))
+	})
+
+	It("should be served by the introspection API", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/simulate?count=1&selector=pool%3Dslow", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var simulation Simulation
+		Expect(json.NewDecoder(rec.Body).Decode(&simulation)).To(Succeed())
+		Expect(simulation.DeviceIDs).To(Equal([]string{"dev01"}))
+	})
+})
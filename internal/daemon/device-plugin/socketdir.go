@@ -0,0 +1,57 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// liveSocketDialTimeout bounds the check whether another process listens on
+// the plugin socket.
+const liveSocketDialTimeout = 200 * time.Millisecond
+
+// checkSocketDir fails with an actionable error unless the directory of the
+// plugin socket exists and is writable, which it isn't e.g. when Kubelet runs
+// with a non-default root directory. Writability is checked by creating a
+// file, which also catches read-only mounts.
+func checkSocketDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("Device Plugin socket directory %s does not exist, check the root directory of Kubelet", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check Device Plugin socket directory %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("Device Plugin socket directory %s is not a directory", dir)
+	}
+	probe, err := os.CreateTemp(dir, ".dpu-write-check-")
+	if err != nil {
+		return fmt.Errorf("Device Plugin socket directory %s is not writable: %v", dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// checkSocketNotLive fails if another process accepts connections on the
+// socket at path, whose file must then not be removed. A socket file nobody
+// listens on is left behind by a process that didn't clean up and is stale.
+func checkSocketNotLive(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, liveSocketDialTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	return fmt.Errorf("Device Plugin socket %s is in use by another process, is another instance of the daemon running?", path)
+}
+
+// socketDir returns the directory of the plugin socket.
+func (dp *dpServer) socketDir() string {
+	return filepath.Dir(dp.pluginEndpoint)
+}
@@ -0,0 +1,74 @@
+package deviceplugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plugin socket directory", func() {
+	var (
+		dp   *dpServer
+		root string
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		dp = newTestDevicePlugin()
+		WithSelfTest(false)(dp)
+		WithIntrospection(false)(dp)
+	})
+
+	It("should explain a missing directory", func() {
+		dp.pluginEndpoint = filepath.Join(root, "missing", "dpuNet.sock")
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("socket directory " + filepath.Join(root, "missing") + " does not exist")))
+	})
+
+	It("should reject a file in place of the directory", func() {
+		Expect(os.WriteFile(filepath.Join(root, "file"), nil, 0o600)).To(Succeed())
+		dp.pluginEndpoint = filepath.Join(root, "file", "dpuNet.sock")
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("is not a directory")))
+	})
+
+	It("should reject a read-only directory", func() {
+		if os.Geteuid() == 0 {
+			Skip("root can write to read-only directories")
+		}
+		Expect(os.Chmod(root, 0o500)).To(Succeed())
+		DeferCleanup(os.Chmod, root, os.FileMode(0o700))
+		dp.pluginEndpoint = filepath.Join(root, "dpuNet.sock")
+		_, err := dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("is not writable")))
+	})
+
+	It("should replace a stale socket", func() {
+		dp.pluginEndpoint = filepath.Join(root, "dpuNet.sock")
+		stale, err := net.Listen("unix", dp.pluginEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		lis, err := dp.Listen()
+		Expect(err).NotTo(HaveOccurred())
+		lis.Close()
+	})
+
+	It("should not remove a socket another process serves on", func() {
+		dp.pluginEndpoint = filepath.Join(root, "dpuNet.sock")
+		live, err := net.Listen("unix", dp.pluginEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(live.Close)
+
+		_, err = dp.Listen()
+		Expect(err).To(MatchError(ContainSubstring("is in use by another process")))
+		Expect(dp.pluginEndpoint).To(BeAnExistingFile())
+	})
+})
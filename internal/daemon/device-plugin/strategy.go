@@ -0,0 +1,180 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"sort"
+
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+)
+
+// AllocationStrategy decides which of the available devices
+// GetPreferredAllocation prefers. Devices matching the selector of the pod
+// are preferred regardless of the strategy.
+type AllocationStrategy string
+
+const (
+	// StrategyPacked prefers the devices with the lowest IDs.
+	StrategyPacked AllocationStrategy = "packed"
+	// StrategyNuma prefers devices on the NUMA node of the devices Kubelet
+	// requires, or else on the NUMA node with the most available devices.
+	StrategyNuma AllocationStrategy = "numa"
+	// StrategySpread takes devices from each NUMA node in turn.
+	StrategySpread AllocationStrategy = "spread"
+	// StrategyPFAffinity prefers VFs of the PF of the devices Kubelet
+	// requires, or else of the PF with the most available VFs.
+	StrategyPFAffinity AllocationStrategy = "pf-affinity"
+	// StrategyLocality prefers devices of the locality group the vendor
+	// plugin reports, e.g. the PCIe switch, of the devices Kubelet requires,
+	// or else of the group with the most available devices.
+	StrategyLocality AllocationStrategy = "locality"
+)
+
+var allocationStrategies = []AllocationStrategy{StrategyPacked, StrategyNuma, StrategySpread, StrategyPFAffinity, StrategyLocality}
+
+func parseAllocationStrategy(name string) (AllocationStrategy, error) {
+	for _, strategy := range allocationStrategies {
+		if name == string(strategy) {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown allocation strategy %q, expected one of %v", name, allocationStrategies)
+}
+
+// StrategyStatus is the response of the introspection "/strategy" endpoint.
+type StrategyStatus struct {
+	Strategy  AllocationStrategy   `json:"strategy"`
+	Available []AllocationStrategy `json:"available"`
+}
+
+func (dp *dpServer) allocationStrategy() AllocationStrategy {
+	dp.strategyMutex.RLock()
+	defer dp.strategyMutex.RUnlock()
+	return dp.strategy
+}
+
+func (dp *dpServer) StrategyStatus() StrategyStatus {
+	return StrategyStatus{Strategy: dp.allocationStrategy(), Available: allocationStrategies}
+}
+
+// preferredAllocationAvailable returns whether Kubelet is told to call
+// GetPreferredAllocation. Kubelet only asks at registration, so this can't
+// change at runtime.
+func (dp *dpServer) preferredAllocationAvailable() bool {
+	return dp.podLister != nil || dp.strategyEnabled || dp.warm != nil
+}
+
+// SetStrategy switches the allocation strategy at runtime, it's used from the
+// next GetPreferredAllocation on.
+func (dp *dpServer) SetStrategy(name string) (StrategyStatus, error) {
+	strategy, err := parseAllocationStrategy(name)
+	if err != nil {
+		return StrategyStatus{}, err
+	}
+	if !dp.preferredAllocationAvailable() {
+		return StrategyStatus{}, fmt.Errorf("Kubelet doesn't ask for preferred allocations, configure an allocation strategy or device selectors first")
+	}
+
+	dp.strategyMutex.Lock()
+	previous := dp.strategy
+	dp.strategy = strategy
+	dp.strategyMutex.Unlock()
+
+	if previous != strategy {
+		dp.log.Info("Allocation strategy changed", "from", previous, "to", strategy)
+	}
+	return dp.StrategyStatus(), nil
+}
+
+// orderCandidates orders the candidates, sorted by ID, by preference of the
+// given strategy. Ties keep the ID order.
+func (dp *dpServer) orderCandidates(strategy AllocationStrategy, candidates, mustInclude []string) {
+	switch strategy {
+	case StrategyNuma:
+		packGroups(candidates, mustInclude, dp.numaGroup)
+	case StrategyPFAffinity:
+		packGroups(candidates, mustInclude, dp.pfGroup)
+	case StrategyLocality:
+		packGroups(candidates, mustInclude, dp.localityGroup)
+	case StrategySpread:
+		spreadGroups(candidates, dp.numaGroup)
+	}
+}
+
+// numaGroup is zero-padded so that NUMA nodes order numerically, devices
+// without NUMA affinity come last.
+func (dp *dpServer) numaGroup(id string) string {
+	dev, _ := dp.device(id)
+	return fmt.Sprintf("%019d", lowestNumaNode(dev))
+}
+
+func (dp *dpServer) pfGroup(id string) string {
+	return dp.deviceInfoFor(dp.physicalDevice(id)).PFName
+}
+
+// localityGroup puts each device the vendor plugin reports no group for in
+// a group of its own, prefixed with NUL which vendor plugins don't use in
+// group names.
+func (dp *dpServer) localityGroup(id string) string {
+	if handler, ok := dp.deviceHandler.(dh.LocalityHandler); ok {
+		if group := handler.GetDeviceLocalityGroup(id); group != "" {
+			return group
+		}
+	}
+	return "\x00" + id
+}
+
+// packGroups orders the candidates of the groups of the required devices
+// first, then the candidates of the larger groups, so that an allocation
+// spans as few groups as possible.
+func packGroups(candidates, mustInclude []string, group func(id string) string) {
+	required := make(map[string]bool)
+	for _, id := range mustInclude {
+		required[group(id)] = true
+	}
+	groups := make(map[string]string, len(candidates))
+	sizes := make(map[string]int)
+	for _, id := range candidates {
+		groups[id] = group(id)
+		sizes[groups[id]]++
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		gi, gj := groups[candidates[i]], groups[candidates[j]]
+		if required[gi] != required[gj] {
+			return required[gi]
+		}
+		if sizes[gi] != sizes[gj] {
+			return sizes[gi] > sizes[gj]
+		}
+		return gi < gj
+	})
+}
+
+// spreadGroups orders the candidates so that each group contributes one
+// device in turn.
+func spreadGroups(candidates []string, group func(id string) string) {
+	groups := make(map[string]string, len(candidates))
+	turns := make(map[string]int, len(candidates))
+	taken := make(map[string]int)
+	for _, id := range candidates {
+		groups[id] = group(id)
+		turns[id] = taken[groups[id]]
+		taken[groups[id]]++
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if turns[ci] != turns[cj] {
+			return turns[ci] < turns[cj]
+		}
+		return groups[ci] < groups[cj]
+	})
+}
+
+// WithAllocationStrategy sets the initial allocation strategy and makes
+// Kubelet ask for preferred allocations, see SetStrategy to change it at
+// runtime.
+func WithAllocationStrategy(strategy AllocationStrategy) func(*dpServer) {
+	return func(d *dpServer) {
+		d.strategy = strategy
+		d.strategyEnabled = true
+	}
+}
@@ -0,0 +1,150 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Allocation strategy", func() {
+	var dp *dpServer
+
+	preferred := func(size int32, mustInclude ...string) []string {
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs:   []string{"dev04", "dev03", "dev02", "dev01", "dev00"},
+				MustIncludeDeviceIDs: mustInclude,
+				AllocationSize:       size,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.ContainerResponses[0].DeviceIDs
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev00", "dev01", "dev02", "dev03", "dev04")
+		for id, node := range map[string]int64{"dev00": 0, "dev01": 1, "dev02": 1, "dev03": 0, "dev04": 1} {
+			dev := dp.devices[id]
+			dev.Topology = &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+			dp.devices[id] = dev
+		}
+		dp.deviceInfoFor = func(id string) DeviceInfo {
+			if id == "dev00" || id == "dev02" {
+				return DeviceInfo{ID: id, PFName: "pf0"}
+			}
+			return DeviceInfo{ID: id, PFName: "pf1"}
+		}
+		WithAllocationStrategy(StrategyPacked)(dp)
+	})
+
+	It("should make Kubelet ask for preferred allocations", func() {
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.GetPreferredAllocationAvailable).To(BeTrue())
+	})
+
+	It("should use the new strategy from the next preferred allocation on", func() {
+		Expect(preferred(2)).To(Equal([]string{"dev00", "dev01"}))
+
+		status, err := dp.SetStrategy("numa")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Strategy).To(Equal(StrategyNuma))
+		Expect(preferred(2)).To(Equal([]string{"dev01", "dev02"}))
+		Expect(preferred(2, "dev00")).To(Equal([]string{"dev00", "dev03"}))
+
+		_, err = dp.SetStrategy("spread")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(preferred(4)).To(Equal([]string{"dev00", "dev01", "dev03", "dev02"}))
+
+		_, err = dp.SetStrategy("pf-affinity")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(preferred(2, "dev02")).To(Equal([]string{"dev02", "dev00"}))
+		Expect(dp.GetInfo().Config.AllocationStrategy).To(Equal("pf-affinity"))
+	})
+
+	It("should reject unknown strategies", func() {
+		_, err := dp.SetStrategy("cheapest")
+		Expect(err).To(MatchError(ContainSubstring(`unknown allocation strategy "cheapest"`)))
+		Expect(dp.StrategyStatus().Strategy).To(Equal(StrategyPacked))
+	})
+
+	It("should reject switching while Kubelet doesn't ask for preferred allocations", func() {
+		dp = newTestDevicePlugin()
+		_, err := dp.SetStrategy("numa")
+		Expect(err).To(MatchError(ContainSubstring("doesn't ask for preferred allocations")))
+	})
+
+	It("should be switched through the introspection socket", func() {
+		rec := httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/strategy?name=spread", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/strategy", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var status StrategyStatus
+		Expect(json.NewDecoder(rec.Body).Decode(&status)).To(Succeed())
+		Expect(status.Strategy).To(Equal(StrategySpread))
+
+		rec = httptest.NewRecorder()
+		dp.introspection.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/strategy?name=cheapest", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
+
+// localityDeviceHandler reports the given locality group of each device.
+type localityDeviceHandler struct {
+	attributeDeviceHandler
+	groups map[string]string
+}
+
+func (h localityDeviceHandler) GetDeviceLocalityGroup(id string) string {
+	return h.groups[id]
+}
+
+var _ = Describe("Locality allocation strategy", func() {
+	var dp *dpServer
+
+	preferred := func(size int32, available []string, mustInclude ...string) []string {
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs:   available,
+				MustIncludeDeviceIDs: mustInclude,
+				AllocationSize:       size,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.ContainerResponses[0].DeviceIDs
+	}
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev00", "dev01", "dev02", "dev03", "dev04", "dev05")
+		WithDeviceHandler(localityDeviceHandler{groups: map[string]string{
+			"dev00": "switch0", "dev01": "switch1", "dev02": "switch1",
+			"dev03": "switch0", "dev04": "switch1",
+		}})(dp)
+		WithAllocationStrategy(StrategyLocality)(dp)
+	})
+
+	It("should keep the devices within the largest group", func() {
+		all := []string{"dev00", "dev01", "dev02", "dev03", "dev04", "dev05"}
+		Expect(preferred(2, all)).To(Equal([]string{"dev01", "dev02"}))
+		Expect(preferred(3, all)).To(Equal([]string{"dev01", "dev02", "dev04"}))
+	})
+
+	It("should keep the devices within the group of the required devices", func() {
+		all := []string{"dev00", "dev01", "dev02", "dev03", "dev04", "dev05"}
+		Expect(preferred(2, all, "dev00")).To(Equal([]string{"dev00", "dev03"}))
+	})
+
+	It("should spill over to other groups when the available devices don't permit", func() {
+		got := preferred(3, []string{"dev00", "dev01", "dev03", "dev05"})
+		Expect(got).To(HaveLen(3))
+		Expect(got[:2]).To(Equal([]string{"dev00", "dev03"}))
+	})
+})
@@ -0,0 +1,80 @@
+package deviceplugin
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultRegisterMinInterval = time.Second
+	defaultRegisterWindow      = time.Minute
+	defaultRegisterMaxAttempts = 10
+)
+
+// registrationThrottle protects Kubelet from a Device Plugin registering over
+// and over, e.g. while Kubelet keeps restarting. Successful registrations are
+// at least minInterval apart, and at most maxAttempts attempts are made per
+// window.
+type registrationThrottle struct {
+	minInterval time.Duration
+	window      time.Duration
+	maxAttempts int
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	// attempts holds the times of the attempts within the last window.
+	attempts []time.Time
+}
+
+func newRegistrationThrottle(minInterval, window time.Duration, maxAttempts int) *registrationThrottle {
+	return &registrationThrottle{
+		minInterval: minInterval,
+		window:      window,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// reserve books the next registration attempt and returns how long to wait
+// before making it.
+func (t *registrationThrottle) reserve(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at := now
+	if !t.lastSuccess.IsZero() && at.Before(t.lastSuccess.Add(t.minInterval)) {
+		at = t.lastSuccess.Add(t.minInterval)
+	}
+	if t.maxAttempts > 0 {
+		// Forget about the attempts that can't be in the window anymore.
+		for len(t.attempts) > 0 && !t.attempts[0].After(at.Add(-t.window)) {
+			t.attempts = t.attempts[1:]
+		}
+		if len(t.attempts) >= t.maxAttempts {
+			at = t.attempts[len(t.attempts)-t.maxAttempts].Add(t.window)
+		}
+	}
+	t.attempts = append(t.attempts, at)
+	return at.Sub(now)
+}
+
+// success records a successful registration at the time it was reserved for.
+func (t *registrationThrottle) success(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastSuccess = at
+}
+
+// throttledRegister registers with Kubelet once the throttle allows it.
+func (dp *dpServer) throttledRegister() error {
+	now := dp.clock.Now()
+	wait := dp.registerThrottle.reserve(now)
+	if wait > 0 {
+		dp.log.Info("Throttling registration with Kubelet", "wait", wait)
+		time.Sleep(wait)
+	}
+	if err := dp.register(); err != nil {
+		return err
+	}
+	dp.registerThrottle.success(now.Add(wait))
+	return nil
+}
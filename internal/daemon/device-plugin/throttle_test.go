@@ -0,0 +1,49 @@
+package deviceplugin
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registration throttle", func() {
+	It("should hold the rate limit when triggered many times at once", func() {
+		throttle := newRegistrationThrottle(time.Second, 10*time.Second, 3)
+		now := time.Now()
+
+		var attempts []time.Time
+		for i := 0; i < 12; i++ {
+			at := now.Add(throttle.reserve(now))
+			throttle.success(at)
+			attempts = append(attempts, at)
+		}
+
+		Expect(attempts[0]).To(Equal(now))
+		for i := 1; i < len(attempts); i++ {
+			Expect(attempts[i].Sub(attempts[i-1])).To(BeNumerically(">=", time.Second))
+		}
+		for i := 3; i < len(attempts); i++ {
+			Expect(attempts[i].Sub(attempts[i-3])).To(BeNumerically(">=", 10*time.Second), "attempt %d", i)
+		}
+	})
+
+	It("should cap the attempts per window without successful registrations", func() {
+		throttle := newRegistrationThrottle(time.Second, 10*time.Second, 2)
+		now := time.Now()
+
+		Expect(throttle.reserve(now)).To(BeZero())
+		Expect(throttle.reserve(now)).To(BeZero())
+		Expect(throttle.reserve(now)).To(Equal(10 * time.Second))
+	})
+
+	It("should not delay registrations spread out in time", func() {
+		throttle := newRegistrationThrottle(time.Second, 10*time.Second, 3)
+		now := time.Now()
+		for i := 0; i < 10; i++ {
+			Expect(throttle.reserve(now)).To(BeZero())
+			throttle.success(now)
+			now = now.Add(5 * time.Second)
+		}
+	})
+})
@@ -0,0 +1,102 @@
+package deviceplugin
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	dh "github.com/openshift/dpu-operator/internal/daemon/device-handler"
+	"github.com/prometheus/client_golang/prometheus"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	numaEnvName = "NF_DEV_NUMA"
+	// noNumaLabel is the metric label of devices without NUMA affinity.
+	noNumaLabel = "none"
+)
+
+// deviceNumaNodes returns the NUMA nodes a device is attached to. The
+// advertised topology is used when present, otherwise devices identified by
+// their PCI address are looked up in sysfs. Unknown affinity yields nil.
+func deviceNumaNodes(dev pluginapi.Device) []int64 {
+	if dev.Topology != nil && len(dev.Topology.Nodes) > 0 {
+		nodes := make([]int64, 0, len(dev.Topology.Nodes))
+		for _, node := range dev.Topology.Nodes {
+			nodes = append(nodes, node.ID)
+		}
+		return nodes
+	}
+
+	if sriovutils.IsValidPCIAddress(dev.ID) {
+		if node := dh.GetNumaNode(dev.ID); node >= 0 {
+			return []int64{int64(node)}
+		}
+	}
+	return nil
+}
+
+// numaEnvValue returns the sorted, comma separated list of NUMA nodes the
+// given devices are attached to.
+func (dp *dpServer) numaEnvValue(ids []string) string {
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		dev, _ := dp.device(id)
+		for _, node := range deviceNumaNodes(dev) {
+			seen[node] = true
+		}
+	}
+
+	nodes := make([]int64, 0, len(seen))
+	for node := range seen {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, strconv.FormatInt(node, 10))
+	}
+	return strings.Join(values, ",")
+}
+
+// reportNumaAvailability updates the per NUMA node device metrics from the
+// advertised devices. Devices attached to several nodes count for each of them.
+func (dp *dpServer) reportNumaAvailability(advertised *dh.DeviceList) {
+	healthy := make(map[string]int)
+	allocatable := make(map[string]int)
+	for id, dev := range *advertised {
+		labels := []string{noNumaLabel}
+		if nodes := deviceNumaNodes(dev); len(nodes) > 0 {
+			labels = labels[:0]
+			for _, node := range nodes {
+				labels = append(labels, strconv.FormatInt(node, 10))
+			}
+		}
+		isHealthy := dev.Health == pluginapi.Healthy
+		isAllocatable := isHealthy && !dp.allocations.isAllocated(id)
+		for _, label := range labels {
+			// Nodes without any healthy device are reported with 0.
+			healthy[label] += boolToInt(isHealthy)
+			allocatable[label] += boolToInt(isAllocatable)
+		}
+	}
+
+	// Delete the series of this resource so that nodes whose devices all
+	// disappeared don't linger, the other resources report their own.
+	resource := prometheus.Labels{"resource": dp.resourceName}
+	numaHealthyDevices.DeletePartialMatch(resource)
+	numaAllocatableDevices.DeletePartialMatch(resource)
+	for label, n := range healthy {
+		numaHealthyDevices.WithLabelValues(dp.resourceName, label).Set(float64(n))
+		numaAllocatableDevices.WithLabelValues(dp.resourceName, label).Set(float64(allocatable[label]))
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
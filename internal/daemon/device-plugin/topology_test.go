@@ -0,0 +1,87 @@
+package deviceplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/protobuf/proto"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// numaVendorPlugin reports the NUMA node of some of its devices.
+type numaVendorPlugin struct {
+	plugin.VendorPlugin
+}
+
+func (v *numaVendorPlugin) SetNumVfs(ctx context.Context, count int32) (*pb.VfCount, error) {
+	return &pb.VfCount{VfCnt: count}, nil
+}
+
+func (v *numaVendorPlugin) GetDevices(ctx context.Context) (*pb.DeviceListResponse, error) {
+	return &pb.DeviceListResponse{Devices: map[string]*pb.Device{
+		"dev0": {ID: "dev0", NumaNode: proto.Int32(0)},
+		"dev1": {ID: "dev1", NumaNode: proto.Int32(1)},
+		"dev2": {ID: "dev2"},
+	}}, nil
+}
+
+var _ = Describe("NUMA availability metrics", func() {
+	It("should reflect a device set spread across NUMA nodes", func() {
+		dp := newTestDevicePlugin("dev0", "dev1", "dev2", "dev3", "dev4")
+		for id, node := range map[string]int64{"dev0": 0, "dev1": 0, "dev2": 1, "dev3": 1} {
+			dev := dp.devices[id]
+			dev.Topology = &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+			dp.devices[id] = dev
+		}
+		dev := dp.devices["dev3"]
+		dev.Health = pluginapi.Unhealthy
+		dp.devices["dev3"] = dev
+
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+		Expect(err).NotTo(HaveOccurred())
+
+		advertised := advertisedCache(dp)
+		dp.reportNumaAvailability(&advertised)
+
+		Expect(gaugeValue(numaHealthyDevices.WithLabelValues(dp.resourceName, "0"))).To(Equal(2.0))
+		Expect(gaugeValue(numaAllocatableDevices.WithLabelValues(dp.resourceName, "0"))).To(Equal(1.0))
+		Expect(gaugeValue(numaHealthyDevices.WithLabelValues(dp.resourceName, "1"))).To(Equal(1.0))
+		Expect(gaugeValue(numaAllocatableDevices.WithLabelValues(dp.resourceName, "1"))).To(Equal(1.0))
+		Expect(gaugeValue(numaHealthyDevices.WithLabelValues(dp.resourceName, noNumaLabel))).To(Equal(1.0))
+		Expect(gaugeValue(numaAllocatableDevices.WithLabelValues(dp.resourceName, noNumaLabel))).To(Equal(1.0))
+
+		// Another resource reporting doesn't wipe ours.
+		other := newTestDevicePlugin("dev9")
+		other.resourceName = "openshift.io/other"
+		otherAdvertised := advertisedCache(other)
+		other.reportNumaAvailability(&otherAdvertised)
+		Expect(gaugeValue(numaHealthyDevices.WithLabelValues(dp.resourceName, "0"))).To(Equal(2.0))
+		Expect(gaugeValue(numaHealthyDevices.WithLabelValues(other.resourceName, noNumaLabel))).To(Equal(1.0))
+	})
+})
+
+var _ = Describe("Vendor reported topology", func() {
+	It("should advertise the NUMA node and omit unknown affinity", func() {
+		dp := NewDevicePlugin(&numaVendorPlugin{}, true, *utils.NewPathManager(GinkgoT().TempDir()))
+		Expect(dp.SetupDevices()).To(Succeed())
+
+		devices, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		stream := &fakeListAndWatchServer{}
+		Expect(dp.sendDevices(stream, devices)).To(Succeed())
+
+		topology := make(map[string]*pluginapi.TopologyInfo)
+		for _, dev := range stream.sent[0].Devices {
+			topology[dev.ID] = dev.Topology
+		}
+		Expect(topology).To(Equal(map[string]*pluginapi.TopologyInfo{
+			"dev0": {Nodes: []*pluginapi.NUMANode{{ID: 0}}},
+			"dev1": {Nodes: []*pluginapi.NUMANode{{ID: 1}}},
+			"dev2": nil,
+		}))
+	})
+})
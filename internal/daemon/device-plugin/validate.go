@@ -0,0 +1,125 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	// validationSocketSuffix names the socket Validate listens on next to the
+	// Device Plugin socket, so that validating doesn't disturb a Device
+	// Plugin already serving.
+	validationSocketSuffix = ".validate"
+	kubeletReachTimeout    = 5 * time.Second
+)
+
+// ValidationStep is the outcome of a step of Validate, Error is empty when
+// it succeeded.
+type ValidationStep struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// ValidationReport lists the steps Validate took, up to the first one that
+// failed.
+type ValidationReport struct {
+	ResourceName string           `json:"resourceName"`
+	Steps        []ValidationStep `json:"steps"`
+}
+
+// Err returns the error of the failed step, if any.
+func (r ValidationReport) Err() error {
+	for _, step := range r.Steps {
+		if step.Error != "" {
+			return fmt.Errorf("%s: %s", step.Name, step.Error)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the Device Plugin could register with Kubelet
+// without registering: it listens on a socket next to the Device Plugin
+// socket, makes the test connection to it and connects to Kubelet, with the
+// functions Listen, Serve and the registration use. Everything is cleaned up
+// afterwards.
+func (dp *dpServer) Validate() ValidationReport {
+	endpoint := dp.pluginEndpoint + validationSocketSuffix
+	server := grpc.NewServer()
+	var lis net.Listener
+	defer func() {
+		server.Stop()
+		if lis != nil {
+			lis.Close()
+		}
+		os.Remove(endpoint)
+	}()
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"resource name", func() error { return validateResourceName(dp.resourceName) }},
+		{"socket directory", func() error { return checkSocketDir(dp.socketDir()) }},
+		{"listen", func() error {
+			if err := os.Remove(endpoint); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			var err error
+			lis, err = dp.listenWithRetry(endpoint)
+			if err != nil {
+				return err
+			}
+			go server.Serve(lis)
+			return nil
+		}},
+		{"test connection", func() error { return dp.checkServing(endpoint) }},
+		{"connect to Kubelet", dp.checkKubeletReachable},
+	}
+
+	report := ValidationReport{ResourceName: dp.resourceName}
+	for _, step := range steps {
+		err := step.run()
+		result := ValidationStep{Name: step.name}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Steps = append(report.Steps, result)
+		if err != nil {
+			break
+		}
+	}
+	return report
+}
+
+// checkKubeletReachable connects to Kubelet without calling it.
+func (dp *dpServer) checkKubeletReachable() error {
+	conn, err := dp.dialKubelet()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), kubeletReachTimeout)
+	defer cancel()
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("Kubelet at %s is not reachable, connection is %s", dp.pathManager.KubeletEndPoint(), state)
+		}
+	}
+	return nil
+}
+
+// ValidateRegistration validates that the Device Plugin of the default
+// resource could register with Kubelet, see Validate. It's meant for
+// debugging node onboarding from the daemon command line.
+func ValidateRegistration(pm utils.PathManager) ValidationReport {
+	return NewDevicePlugin(nil, false, pm).Validate()
+}
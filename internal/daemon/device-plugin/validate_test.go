@@ -0,0 +1,69 @@
+package deviceplugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+var _ = Describe("Registration validation", func() {
+	var (
+		pm      utils.PathManager
+		kubelet *fakeKubelet
+	)
+
+	startKubelet := func() {
+		socket := pm.KubeletEndPoint()
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		pluginapi.RegisterRegistrationServer(server, kubelet)
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+	}
+
+	stepNames := func(report ValidationReport) []string {
+		var names []string
+		for _, step := range report.Steps {
+			names = append(names, step.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		// Keep the socket paths short, unix socket paths are limited to 108 bytes.
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		pm = *utils.NewPathManager(root)
+		Expect(os.MkdirAll(filepath.Dir(pm.KubeletEndPoint()), 0o755)).To(Succeed())
+		kubelet = &fakeKubelet{}
+	})
+
+	It("should go through every step without registering", func() {
+		startKubelet()
+		report := ValidateRegistration(pm)
+		Expect(report.Err()).NotTo(HaveOccurred())
+		Expect(stepNames(report)).To(Equal([]string{"resource name", "socket directory", "listen", "test connection", "connect to Kubelet"}))
+		Expect(kubelet.count()).To(BeZero())
+		Expect(pm.PluginEndpoint() + validationSocketSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("should report that Kubelet isn't reachable", func() {
+		report := ValidateRegistration(pm)
+		Expect(report.Err()).To(MatchError(ContainSubstring("connect to Kubelet: Kubelet at")))
+		Expect(report.Steps[len(report.Steps)-2].Error).To(BeEmpty())
+	})
+
+	It("should stop at the first failed step", func() {
+		report := NewDevicePlugin(nil, false, pm, WithResourceName("not a resource", pm.PluginEndpoint())).Validate()
+		Expect(stepNames(report)).To(Equal([]string{"resource name"}))
+		Expect(report.Err()).To(HaveOccurred())
+	})
+})
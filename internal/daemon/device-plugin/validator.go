@@ -0,0 +1,122 @@
+package deviceplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// defaultDenialReason explains a denial whose verdict has no reason.
+const defaultDenialReason = "denied by allocation validator"
+
+// AllocationReview is what an AllocateValidator decides upon: the devices
+// Kubelet asks to allocate to each container of a pod.
+type AllocationReview struct {
+	ResourceName string     `json:"resourceName"`
+	Containers   [][]string `json:"containers"`
+}
+
+// AllocateValidator enforces a policy on allocations, e.g. a site policy or
+// a decision made by an external service. It runs after the Device Plugin's
+// own checks passed, and rejects the allocation by returning an error
+// explaining why.
+type AllocateValidator interface {
+	ValidateAllocate(ctx context.Context, review AllocationReview) error
+}
+
+// allowAllValidator accepts every allocation.
+type allowAllValidator struct{}
+
+func (allowAllValidator) ValidateAllocate(ctx context.Context, review AllocationReview) error {
+	return nil
+}
+
+func (dp *dpServer) validateAllocate(ctx context.Context, rqt *pluginapi.AllocateRequest) error {
+	review := AllocationReview{ResourceName: dp.resourceName}
+	for _, container := range rqt.ContainerRequests {
+		review.Containers = append(review.Containers, container.DevicesIDs)
+	}
+	if err := dp.allocateValidator.ValidateAllocate(ctx, review); err != nil {
+		return fmt.Errorf("allocation rejected by validator: %v", err)
+	}
+	return nil
+}
+
+// AllocationVerdict is the response of an HTTP allocate validator.
+type AllocationVerdict struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// HTTPAllocateValidator posts the AllocationReview as JSON to a URL, which
+// answers with an AllocationVerdict. When the service can't be reached in
+// time or answers with an error, the allocation is accepted if failOpen is
+// set and rejected otherwise. Accepted allocations the service couldn't
+// review are logged and counted, so that the policy being bypassed is visible.
+type HTTPAllocateValidator struct {
+	log      logr.Logger
+	url      string
+	client   *http.Client
+	failOpen bool
+}
+
+func NewHTTPAllocateValidator(url string, timeout time.Duration, failOpen bool) *HTTPAllocateValidator {
+	return &HTTPAllocateValidator{
+		log:      ctrl.Log.WithName("HTTPAllocateValidator"),
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		failOpen: failOpen,
+	}
+}
+
+func (v *HTTPAllocateValidator) ValidateAllocate(ctx context.Context, review AllocationReview) error {
+	verdict, err := v.review(ctx, review)
+	if err != nil {
+		if v.failOpen {
+			allocateValidatorBypassesTotal.WithLabelValues(review.ResourceName).Inc()
+			v.log.Error(err, "Accepting allocation without review, failing open", "resource", review.ResourceName, "containers", review.Containers)
+			return nil
+		}
+		return err
+	}
+	if !verdict.Allowed {
+		if verdict.Reason == "" {
+			return fmt.Errorf("%s", defaultDenialReason)
+		}
+		return fmt.Errorf("%s", verdict.Reason)
+	}
+	return nil
+}
+
+func (v *HTTPAllocateValidator) review(ctx context.Context, review AllocationReview) (*AllocationVerdict, error) {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode allocation review: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allocation review request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach allocate validator: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("allocate validator answered %s", resp.Status)
+	}
+	var verdict AllocationVerdict
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("failed to decode allocate validator verdict: %v", err)
+	}
+	return &verdict, nil
+}
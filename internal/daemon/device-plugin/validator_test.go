@@ -0,0 +1,123 @@
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// denyDevicesValidator rejects allocations including any of its devices.
+type denyDevicesValidator map[string]bool
+
+func (v denyDevicesValidator) ValidateAllocate(ctx context.Context, review AllocationReview) error {
+	for _, ids := range review.Containers {
+		for _, id := range ids {
+			if v[id] {
+				return fmt.Errorf("device %s is reserved by site policy", id)
+			}
+		}
+	}
+	return nil
+}
+
+var _ = Describe("Allocate validation", func() {
+	var dp *dpServer
+
+	BeforeEach(func() {
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2")
+	})
+
+	It("should accept every allocation by default", func() {
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0", "dev1"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject the device sets refused by the validator", func() {
+		WithAllocateValidator(denyDevicesValidator{"dev1": true})(dp)
+
+		_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev1", "dev2"}))
+		Expect(err).To(MatchError(ContainSubstring("allocation rejected by validator: device dev1 is reserved by site policy")))
+		Expect(dp.GetAllocations()).To(BeEmpty())
+
+		_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}, []string{"dev2"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("over HTTP", func() {
+		var (
+			reviews []AllocationReview
+			handler http.HandlerFunc
+		)
+
+		BeforeEach(func() {
+			reviews = nil
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				var review AllocationReview
+				Expect(json.NewDecoder(r.Body).Decode(&review)).To(Succeed())
+				reviews = append(reviews, review)
+				verdict := AllocationVerdict{Allowed: true}
+				if len(review.Containers[0]) > 1 {
+					verdict = AllocationVerdict{Allowed: false, Reason: "at most one device per container"}
+				}
+				json.NewEncoder(w).Encode(verdict)
+			}
+		})
+
+		validator := func(timeout time.Duration, failOpen bool) *HTTPAllocateValidator {
+			server := httptest.NewServer(handler)
+			DeferCleanup(server.Close)
+			return NewHTTPAllocateValidator(server.URL, timeout, failOpen)
+		}
+
+		It("should follow the verdict of the service", func() {
+			WithAllocateValidator(validator(time.Second, false))(dp)
+
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = dp.Allocate(context.Background(), allocateRequest([]string{"dev1", "dev2"}))
+			Expect(err).To(MatchError(ContainSubstring("at most one device per container")))
+			Expect(reviews).To(Equal([]AllocationReview{
+				{ResourceName: DpuResourceName, Containers: [][]string{{"dev0"}}},
+				{ResourceName: DpuResourceName, Containers: [][]string{{"dev1", "dev2"}}},
+			}))
+		})
+
+		Context("when the service is too slow", func() {
+			BeforeEach(func() {
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					time.Sleep(500 * time.Millisecond)
+				}
+			})
+
+			It("should reject when failing closed", func() {
+				WithAllocateValidator(validator(50*time.Millisecond, false))(dp)
+				_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+				Expect(err).To(MatchError(ContainSubstring("failed to reach allocate validator")))
+			})
+
+			It("should accept and count the bypass when failing open", func() {
+				bypasses := counterValue(allocateValidatorBypassesTotal.WithLabelValues(dp.resourceName))
+				WithAllocateValidator(validator(50*time.Millisecond, true))(dp)
+				_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(counterValue(allocateValidatorBypassesTotal.WithLabelValues(dp.resourceName))).To(Equal(bypasses + 1))
+			})
+		})
+
+		It("should explain a denial without a reason", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(AllocationVerdict{Allowed: false})
+			}
+			WithAllocateValidator(validator(time.Second, false))(dp)
+
+			_, err := dp.Allocate(context.Background(), allocateRequest([]string{"dev0"}))
+			Expect(err).To(MatchError("allocation rejected by validator: " + defaultDenialReason))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	"github.com/openshift/dpu-operator/internal/utils"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthReportingVendorPlugin reports devices with the given vendor health,
+// or fails GetDevices while down.
+type healthReportingVendorPlugin struct {
+	plugin.VendorPlugin
+
+	mu     sync.Mutex
+	health map[string]string
+	down   bool
+}
+
+func (v *healthReportingVendorPlugin) SetNumVfs(ctx context.Context, count int32) (*pb.VfCount, error) {
+	return &pb.VfCount{VfCnt: count}, nil
+}
+
+func (v *healthReportingVendorPlugin) GetDevices(ctx context.Context) (*pb.DeviceListResponse, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.down {
+		return nil, fmt.Errorf("connection refused")
+	}
+	resp := &pb.DeviceListResponse{Devices: map[string]*pb.Device{}}
+	for id, health := range v.health {
+		resp.Devices[id] = &pb.Device{ID: id, Health: health}
+	}
+	return resp, nil
+}
+
+func (v *healthReportingVendorPlugin) set(down bool, health map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.down = down
+	if health != nil {
+		v.health = health
+	}
+}
+
+var _ = Describe("Vendor reported health", func() {
+	var (
+		dp     *dpServer
+		vsp    *healthReportingVendorPlugin
+		stream *lockedListAndWatchServer
+	)
+
+	BeforeEach(func() {
+		vsp = &healthReportingVendorPlugin{health: map[string]string{"dev0": "Healthy", "dev1": ""}}
+		dp = NewDevicePlugin(vsp, true, *utils.NewPathManager(GinkgoT().TempDir()), WithHealthCacheTTL(0))
+		Expect(dp.SetupDevices()).To(Succeed())
+	})
+
+	It("should map the vendor health to the advertised health", func() {
+		vsp.set(false, map[string]string{"dev0": "Healthy", "dev1": "", "dev2": "Degraded", "dev3": "Overheated"})
+		devices, err := dp.deviceHandler.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect((*devices)["dev0"].Health).To(Equal(pluginapi.Healthy))
+		Expect((*devices)["dev1"].Health).To(Equal(pluginapi.Healthy))
+		Expect((*devices)["dev2"].Health).To(Equal(pluginapi.Unhealthy))
+		Expect((*devices)["dev3"].Health).To(Equal(pluginapi.Unhealthy))
+	})
+
+	It("should advertise health transitions but not flap during an outage", func() {
+		stream = &lockedListAndWatchServer{}
+		WithCoalesceWindow(0)(dp)
+		dp.pollInterval = time.Hour
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = dp.ListAndWatch(&pluginapi.Empty{}, stream)
+		}()
+		DeferCleanup(func() {
+			stream.mu.Lock()
+			stream.closed = true
+			stream.mu.Unlock()
+			vsp.set(false, map[string]string{})
+			dp.triggerUpdate()
+			Eventually(done).Should(BeClosed())
+		})
+		Eventually(stream.sends).Should(HaveLen(1))
+
+		vsp.set(true, nil)
+		dp.triggerUpdate()
+		Consistently(stream.sends, 300*time.Millisecond).Should(HaveLen(1))
+
+		vsp.set(false, map[string]string{"dev0": "LinkDegraded", "dev1": ""})
+		dp.triggerUpdate()
+		Eventually(stream.sends).Should(HaveLen(2))
+	})
+})
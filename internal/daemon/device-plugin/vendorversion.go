@@ -0,0 +1,87 @@
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func (dp *dpServer) validateMinVendorVersion() error {
+	if dp.minVendorVersion == "" {
+		return nil
+	}
+	if _, err := version.ParseGeneric(dp.minVendorVersion); err != nil {
+		return fmt.Errorf("invalid minimum vendor plugin version %q: %v", dp.minVendorVersion, err)
+	}
+	return nil
+}
+
+// checkVendorVersion returns an error unless the vendor plugin is at least
+// the minimum version. Vendor plugins that don't report their version can't
+// be vouched for and are rejected too.
+func (dp *dpServer) checkVendorVersion(ctx context.Context) error {
+	if dp.minVendorVersion == "" {
+		return nil
+	}
+	minVersion, err := version.ParseGeneric(dp.minVendorVersion)
+	if err != nil {
+		return fmt.Errorf("invalid minimum vendor plugin version %q: %v", dp.minVendorVersion, err)
+	}
+	reported, err := dp.vsp.GetVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the vendor plugin version: %v", err)
+	}
+	if reported == "" {
+		return fmt.Errorf("vendor plugin doesn't report its version, at least %s is required", minVersion)
+	}
+	vendorVersion, err := version.ParseGeneric(reported)
+	if err != nil {
+		return fmt.Errorf("invalid vendor plugin version %q: %v", reported, err)
+	}
+	if !vendorVersion.AtLeast(minVersion) {
+		return fmt.Errorf("vendor plugin version %s is older than the minimum version %s", vendorVersion, minVersion)
+	}
+	return nil
+}
+
+// waitForCompatibleVendor blocks until the vendor plugin is recent enough.
+// It returns false if the Device Plugin is stopped in the meantime.
+func (dp *dpServer) waitForCompatibleVendor() bool {
+	ctx, cancel := dp.stopContext(context.Background())
+	defer cancel()
+	lastErr := ""
+	for {
+		err := dp.checkVendorVersion(ctx)
+		if err == nil {
+			if lastErr != "" {
+				dp.log.Info("Vendor plugin is now compatible, registering with Kubelet")
+			}
+			return true
+		}
+		// A vendor plugin that stays too old would flood the log otherwise.
+		if err.Error() != lastErr {
+			dp.log.Error(err, "Not registering with Kubelet until a compatible vendor plugin is running", "retryIn", dp.vendorVersionRetryInterval)
+			lastErr = err.Error()
+		}
+
+		dp.shutdownMutex.RLock()
+		shuttingDown := dp.shuttingDown
+		dp.shutdownMutex.RUnlock()
+		if shuttingDown {
+			return false
+		}
+		time.Sleep(dp.vendorVersionRetryInterval)
+	}
+}
+
+// registerWhenVendorCompatible registers with Kubelet once the readiness file
+// exists, if any, and the vendor plugin is recent enough. Until then no
+// devices are advertised at all, Kubelet doesn't know about the resource.
+func (dp *dpServer) registerWhenVendorCompatible() error {
+	if !dp.waitForReadinessFile() || !dp.waitForCompatibleVendor() {
+		return nil
+	}
+	return dp.registerWithKubelet()
+}
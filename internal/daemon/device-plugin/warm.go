@@ -0,0 +1,61 @@
+package deviceplugin
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// warmDevices remembers when devices were last prepared for a container or
+// released by one. Handing out such a warm device again is faster than
+// preparing a cold one.
+type warmDevices struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	warmedAt map[string]time.Time
+}
+
+func (dp *dpServer) markWarm(ids ...string) {
+	w := dp.warm
+	if w == nil {
+		return
+	}
+	now := dp.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		w.warmedAt[id] = now
+	}
+}
+
+// preferWarm orders the candidates warmed within the window first, the most
+// recently warmed one first. Ties keep their order.
+func (dp *dpServer) preferWarm(candidates []string) {
+	w := dp.warm
+	if w == nil {
+		return
+	}
+	now := dp.clock.Now()
+	w.mu.Lock()
+	warmedAt := make(map[string]time.Time, len(candidates))
+	for _, id := range candidates {
+		if at, ok := w.warmedAt[id]; ok && now.Sub(at) < w.window {
+			warmedAt[id] = at
+		}
+	}
+	w.mu.Unlock()
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return warmedAt[candidates[i]].After(warmedAt[candidates[j]])
+	})
+}
+
+// WithWarmFirst makes GetPreferredAllocation prefer, among otherwise equally
+// suitable devices, the ones prepared or released within window, so that
+// containers less often wait for a cold device to be prepared.
+func WithWarmFirst(window time.Duration) func(*dpServer) {
+	return func(d *dpServer) {
+		d.warm = &warmDevices{window: window, warmedAt: make(map[string]time.Time)}
+	}
+}
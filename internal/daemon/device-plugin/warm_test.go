@@ -0,0 +1,69 @@
+package deviceplugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("Warm devices", func() {
+	var (
+		dp    *dpServer
+		clock *clocktesting.FakeClock
+	)
+
+	preferred := func(size int32) []string {
+		resp, err := dp.GetPreferredAllocation(context.Background(), &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs: []string{"dev0", "dev1", "dev2", "dev3"},
+				AllocationSize:     size,
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.ContainerResponses[0].DeviceIDs
+	}
+
+	BeforeEach(func() {
+		clock = clocktesting.NewFakeClock(time.Now())
+		dp = newTestDevicePlugin("dev0", "dev1", "dev2", "dev3")
+		WithClock(clock)(dp)
+	})
+
+	It("should not change the preference by default", func() {
+		dp.markWarm("dev3")
+		Expect(preferred(1)).To(Equal([]string{"dev0"}))
+	})
+
+	It("should prefer the most recently prepared or released devices", func() {
+		WithWarmFirst(time.Minute)(dp)
+		opts, err := dp.GetDevicePluginOptions(context.Background(), &pluginapi.Empty{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.GetPreferredAllocationAvailable).To(BeTrue())
+
+		_, err = dp.PreStartContainer(context.Background(), &pluginapi.PreStartContainerRequest{DevicesIDs: []string{"dev2"}})
+		Expect(err).NotTo(HaveOccurred())
+		clock.SetTime(clock.Now().Add(time.Second))
+		dp.releaseAllocation("dev3")
+		Expect(preferred(3)).To(Equal([]string{"dev3", "dev2", "dev0"}))
+
+		clock.SetTime(clock.Now().Add(time.Minute))
+		Expect(preferred(1)).To(Equal([]string{"dev0"}))
+	})
+
+	It("should only break ties of the allocation strategy", func() {
+		WithWarmFirst(time.Minute)(dp)
+		WithAllocationStrategy(StrategyPFAffinity)(dp)
+		dp.deviceInfoFor = func(id string) DeviceInfo {
+			if id == "dev0" {
+				return DeviceInfo{ID: id, PFName: "pf0"}
+			}
+			return DeviceInfo{ID: id, PFName: "pf1"}
+		}
+		dp.markWarm("dev0", "dev3")
+		Expect(preferred(2)).To(Equal([]string{"dev3", "dev1"}))
+	})
+})
@@ -49,6 +49,8 @@ type DpuSideManager struct {
 	pathManager  utils.PathManager
 	lastPingTime time.Time
 	pingMutex    sync.RWMutex
+
+	devicePluginOpts []deviceplugin.Option
 }
 
 func (s *DpuSideManager) CreateBridgePort(context context.Context, bpr *pb.CreateBridgePortRequest) (*pb.BridgePort, error) {
@@ -113,7 +115,9 @@ func NewDpuSideManager(vsp plugin.VendorPlugin, config *rest.Config, opts ...fun
 		opt(d)
 	}
 
-	d.dp = deviceplugin.NewDevicePlugin(vsp, true, d.pathManager)
+	// Devices are advertised as the resource of the pool the vendor plugin
+	// reports for them, openshift.io/dpu when it reports none.
+	d.dp = deviceplugin.NewManager(vsp, true, d.pathManager, "", deviceplugin.WithDevicePluginOptions(d.devicePluginOpts...))
 
 	return d, nil
 }
@@ -124,6 +128,13 @@ func WithPathManager(pathManager utils.PathManager) func(*DpuSideManager) {
 	}
 }
 
+// WithDpuDevicePluginOptions configures the Device Plugin of every resource.
+func WithDpuDevicePluginOptions(opts ...deviceplugin.Option) func(*DpuSideManager) {
+	return func(d *DpuSideManager) {
+		d.devicePluginOpts = opts
+	}
+}
+
 func (d *DpuSideManager) StartVsp(ctx context.Context) error {
 	addr, port, err := d.vsp.Start(ctx)
 	if err != nil {
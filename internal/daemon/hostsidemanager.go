@@ -49,6 +49,8 @@ type HostSideManager struct {
 	pathManager        utils.PathManager
 	stopRequested      bool
 	dpListener         net.Listener
+
+	devicePluginOpts []deviceplugin.Option
 }
 
 func (d *HostSideManager) CreateBridgePort(pf int, vf int, vlan int, mac string) (*pb.BridgePort, error) {
@@ -100,7 +102,9 @@ func NewHostSideManager(vsp plugin.VendorPlugin, opts ...func(*HostSideManager))
 		opt(h)
 	}
 
-	h.dp = deviceplugin.NewDevicePlugin(vsp, false, h.pathManager)
+	// Devices are advertised as the resource of the pool the vendor plugin
+	// reports for them, openshift.io/dpu when it reports none.
+	h.dp = deviceplugin.NewManager(vsp, false, h.pathManager, "", deviceplugin.WithDevicePluginOptions(h.devicePluginOpts...))
 	if h.config == nil {
 		h.config = ctrl.GetConfigOrDie()
 	}
@@ -125,6 +129,13 @@ func WithClient(client *rest.Config) func(*HostSideManager) {
 	}
 }
 
+// WithHostDevicePluginOptions configures the Device Plugin of every resource.
+func WithHostDevicePluginOptions(opts ...deviceplugin.Option) func(*HostSideManager) {
+	return func(d *HostSideManager) {
+		d.devicePluginOpts = opts
+	}
+}
+
 func (d *HostSideManager) StartVsp(ctx context.Context) error {
 	addr, port, err := d.vsp.Start(ctx)
 	if err != nil {
@@ -146,18 +146,34 @@ func (d *DummyDpuDaemon) Serve(ctx context.Context, listen net.Listener) error {
 	return nil
 }
 
-func (d *DummyPlugin) GetDevices() (*pb2.DeviceListResponse, error) {
+func (d *DummyPlugin) GetDevices(ctx context.Context) (*pb2.DeviceListResponse, error) {
 	ret := pb2.DeviceListResponse{}
 	return &ret, nil
 }
 
-func (g *DummyPlugin) SetNumVfs(count int32) (*pb2.VfCount, error) {
+func (g *DummyPlugin) SetNumVfs(ctx context.Context, count int32) (*pb2.VfCount, error) {
 	c := &pb2.VfCount{
 		VfCnt: count,
 	}
 	return c, nil
 }
 
+func (g *DummyPlugin) GetAnnotationTemplate(ctx context.Context) (*pb2.AnnotationTemplate, error) {
+	return &pb2.AnnotationTemplate{}, nil
+}
+
+func (g *DummyPlugin) GetAllocateInfo(ctx context.Context, deviceID string) (*pb2.AllocateInfo, error) {
+	return &pb2.AllocateInfo{}, nil
+}
+
+func (g *DummyPlugin) ResetDevice(ctx context.Context, deviceID string) error {
+	return nil
+}
+
+func (g *DummyPlugin) GetVersion(ctx context.Context) (string, error) {
+	return "", nil
+}
+
 func PrepArgs(cniVersion string, command string) *skel.CmdArgs {
 	cniConfig := "{\"cniVersion\": \"" + cniVersion + "\",\"name\": \"dpucni\",\"type\": \"dpucni\", \"OrigVfState\": {\"EffectiveMac\": \"00:11:22:33:44:55\"}, \"vlan\": 7}"
 	cmdArgs := &skel.CmdArgs{
@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// idempotentMethods are the vendor plugin calls that can safely be repeated
+// when unsure whether the vendor plugin saw them.
+var idempotentMethods = map[string]bool{
+	pb.LifeCycleService_GetVersion_FullMethodName:         true,
+	pb.DeviceService_GetDevices_FullMethodName:            true,
+	pb.DeviceService_GetDevicesPage_FullMethodName:        true,
+	pb.DeviceService_GetAnnotationTemplate_FullMethodName: true,
+	pb.DeviceService_GetAllocateInfo_FullMethodName:       true,
+}
+
+// WithIdleTimeout lets the connection to the vendor plugin go idle after
+// timeout without calls, it's redialed on the next call. Zero keeps the gRPC
+// default.
+func WithIdleTimeout(timeout time.Duration) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.idleTimeout = timeout
+	}
+}
+
+// reconnectOnIdleClose repeats a call that failed because the vendor plugin
+// closed the connection, e.g. enforcing its own idle timeout. The connection
+// is idle again in that case and the repeated call redials it, so the close
+// doesn't surface as an error. Only idempotent calls are repeated.
+func (g *GrpcPlugin) reconnectOnIdleClose(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if status.Code(err) != codes.Unavailable || !idempotentMethods[method] || cc.GetState() != connectivity.Idle {
+		return err
+	}
+	g.log.Info("Vendor plugin closed the connection, reconnecting", "method", method)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
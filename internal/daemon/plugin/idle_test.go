@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Vendor plugin idle close", func() {
+	It("should transparently reconnect after the vendor plugin closed the idle connection", func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		pathManager := utils.NewPathManager(root)
+		socket := pathManager.VendorPluginSocket()
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o700)).To(Succeed())
+
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer(grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionIdle: 100 * time.Millisecond}))
+		pb.RegisterLifeCycleServiceServer(server, versionServer{})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		g, err := NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		_, err = g.GetVersion(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		conn := g.conn
+		// Let the vendor plugin close the connection for being idle.
+		time.Sleep(500 * time.Millisecond)
+
+		version, err := g.GetVersion(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1.2.3"))
+		Expect(g.conn).To(BeIdenticalTo(conn))
+	})
+
+	Context("reconnectOnIdleClose", func() {
+		var (
+			g     *GrpcPlugin
+			cc    *grpc.ClientConn
+			calls int
+		)
+
+		// failOnce fails the first call as if the vendor plugin closed the
+		// connection under it.
+		failOnce := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			if calls == 1 {
+				return status.Error(codes.Unavailable, "transport is closing")
+			}
+			return nil
+		}
+
+		BeforeEach(func() {
+			var err error
+			g, err = NewGrpcPlugin(false, "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			// A new client stays idle until the first call.
+			cc, err = grpc.NewClient("passthrough:///vendor-plugin", grpc.WithTransportCredentials(insecure.NewCredentials()))
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(cc.Close)
+			calls = 0
+		})
+
+		It("should repeat an idempotent call once", func() {
+			err := g.reconnectOnIdleClose(context.Background(), pb.DeviceService_GetDevices_FullMethodName, nil, nil, cc, failOnce)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal(2))
+		})
+
+		It("should not repeat a call with side effects", func() {
+			err := g.reconnectOnIdleClose(context.Background(), pb.NetworkFunctionService_CreateNetworkFunction_FullMethodName, nil, nil, cc, failOnce)
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+			Expect(calls).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// deviceServer serves a single device through GetDevicesPage.
+type deviceServer struct {
+	pb.UnimplementedDeviceServiceServer
+}
+
+func (deviceServer) GetDevicesPage(ctx context.Context, in *pb.DeviceListRequest) (*pb.DeviceListResponse, error) {
+	return &pb.DeviceListResponse{Devices: map[string]*pb.Device{"dev0": {ID: "dev0"}}}, nil
+}
+
+var _ = Describe("Vendor plugin restart", func() {
+	It("should redial a stale connection and resume device discovery", func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		pathManager := utils.NewPathManager(root)
+		socket := pathManager.VendorPluginSocket()
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o700)).To(Succeed())
+
+		serve := func() *grpc.Server {
+			lis, err := net.Listen("unix", socket)
+			Expect(err).NotTo(HaveOccurred())
+			server := grpc.NewServer()
+			pb.RegisterDeviceServiceServer(server, deviceServer{})
+			go server.Serve(lis)
+			return server
+		}
+
+		server := serve()
+		g, err := NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+		devices, err := g.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devices.Devices).To(HaveKey("dev0"))
+
+		// Kill the vendor plugin, which also removes its socket, until gRPC
+		// gives up on the connection and backs off.
+		server.Stop()
+		Eventually(func() connectivity.State {
+			_, err := g.GetDevices(context.Background())
+			Expect(err).To(HaveOccurred())
+			return g.conn.GetState()
+		}).Should(Equal(connectivity.TransientFailure))
+		conn := g.conn
+
+		server = serve()
+		DeferCleanup(server.Stop)
+		devices, err = g.GetDevices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devices.Devices).To(HaveKey("dev0"))
+		Expect(g.conn).NotTo(BeIdenticalTo(conn))
+	})
+})
@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+)
+
+// applyReplicas ignores the replicas the vendor plugin reports unless sharing
+// devices is enabled, so that each device is allocated whole.
+func (g *GrpcPlugin) applyReplicas(devices *pb.DeviceListResponse) {
+	if g.replicas || devices == nil {
+		return
+	}
+	for _, device := range devices.Devices {
+		device.Replicas = 0
+	}
+}
+
+// WithDeviceReplicas shares the devices the vendor plugin reports replicas
+// for between that many containers, each of which is allocated a logical
+// device standing for the shared one. Otherwise every device is allocated
+// whole to a single container.
+func WithDeviceReplicas(enabled bool) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.replicas = enabled
+	}
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+)
+
+// Roles vendor plugins report in Device.role.
+const (
+	RoleData       = "data"
+	RoleManagement = "management"
+)
+
+// roleFilter decides by their role which devices are advertised.
+type roleFilter struct {
+	include []string
+	exclude []string
+
+	// filtered are the IDs of the devices filtered out by the last
+	// GetDevices, to only log when they change.
+	mu       sync.Mutex
+	filtered []string
+}
+
+func (f *roleFilter) allows(role string) bool {
+	if len(f.include) > 0 && !slices.Contains(f.include, role) {
+		return false
+	}
+	return !slices.Contains(f.exclude, role)
+}
+
+// filterRoles drops the devices whose role isn't advertised.
+func (g *GrpcPlugin) filterRoles(devices *pb.DeviceListResponse) {
+	f := g.roles
+	if f == nil || devices == nil {
+		return
+	}
+	var filtered []string
+	for id, device := range devices.Devices {
+		if !f.allows(device.Role) {
+			filtered = append(filtered, id)
+			delete(devices.Devices, id)
+		}
+	}
+	sort.Strings(filtered)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !slices.Equal(filtered, f.filtered) {
+		g.log.Info("Devices not advertised because of their role changed", "count", len(filtered), "ids", strings.Join(filtered, ","))
+		f.filtered = filtered
+	}
+}
+
+// WithDeviceRoles only advertises the devices whose role is in include, or
+// any role when include is empty, and not in exclude, e.g. to keep the
+// management functions of the DPU from being allocated to workloads. The
+// empty role stands for devices the vendor plugin reports no role for.
+func WithDeviceRoles(include, exclude []string) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.roles = &roleFilter{include: include, exclude: exclude}
+	}
+}
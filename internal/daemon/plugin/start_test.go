@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+)
+
+// lifeCycleServer initializes successfully.
+type lifeCycleServer struct {
+	pb.UnimplementedLifeCycleServiceServer
+}
+
+func (lifeCycleServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpPort, error) {
+	return &pb.IpPort{Ip: "192.0.2.1", Port: 50051}, nil
+}
+
+var _ = Describe("Vendor plugin start", func() {
+	var pathManager *utils.PathManager
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		pathManager = utils.NewPathManager(root)
+		Expect(os.MkdirAll(filepath.Dir(pathManager.VendorPluginSocket()), 0o700)).To(Succeed())
+	})
+
+	It("should wait for a vendor plugin that comes up late", func() {
+		server := grpc.NewServer()
+		pb.RegisterLifeCycleServiceServer(server, lifeCycleServer{})
+		DeferCleanup(server.Stop)
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(300 * time.Millisecond)
+			lis, err := net.Listen("unix", pathManager.VendorPluginSocket())
+			Expect(err).NotTo(HaveOccurred())
+			server.Serve(lis)
+		}()
+
+		g, err := NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+		ip, port, err := g.Start(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ip).To(Equal("192.0.2.1"))
+		Expect(port).To(Equal(int32(50051)))
+		Expect(g.IsInitialized()).To(BeTrue())
+	})
+
+	It("should give up with a clear error once the start timeout passes", func() {
+		g, err := NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager), WithStartTimeout(500*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		_, _, err = g.Start(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("vendor plugin did not become ready within 500ms")))
+	})
+
+	It("should return the context error when canceled", func() {
+		g, err := NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, _, err = g.Start(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultRPCTimeout = 30 * time.Second
+
+// RPCTimeoutError is returned when a call to the vendor plugin didn't
+// complete within the RPC timeout, so that callers can tell a hung vendor
+// plugin from one that failed the call.
+type RPCTimeoutError struct {
+	Method  string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *RPCTimeoutError) Error() string {
+	return fmt.Sprintf("vendor plugin call %s timed out after %v: %v", e.Method, e.Timeout, e.Err)
+}
+
+func (e *RPCTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// untimedMethods aren't bounded by the RPC timeout. Init brings up the DPU,
+// which takes as long as the vendor needs, Start's context bounds it instead.
+var untimedMethods = map[string]bool{
+	pb.LifeCycleService_Init_FullMethodName: true,
+}
+
+// WithRPCTimeout bounds every call to the vendor plugin to timeout, unless
+// the caller's context expires earlier. Zero disables the timeout.
+func WithRPCTimeout(timeout time.Duration) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.rpcTimeout = timeout
+	}
+}
+
+// enforceRPCTimeout applies the RPC timeout to a call and reports it running
+// out as an RPCTimeoutError.
+func (g *GrpcPlugin) enforceRPCTimeout(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if g.rpcTimeout <= 0 || untimedMethods[method] {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	callCtx, cancel := g.rpcTimeoutContext(ctx)
+	defer cancel()
+	err := invoker(callCtx, method, req, reply, cc, opts...)
+	if timeoutErr := g.rpcTimeoutError(ctx, method, err); timeoutErr != nil {
+		return timeoutErr
+	}
+	return err
+}
+
+// rpcTimeoutContext bounds a call made with ctx to the RPC timeout.
+func (g *GrpcPlugin) rpcTimeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.rpcTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.rpcTimeout)
+}
+
+// rpcTimeoutError returns an RPCTimeoutError if err is the RPC timeout of a
+// call made with ctx running out, rather than ctx itself, or else nil.
+func (g *GrpcPlugin) rpcTimeoutError(ctx context.Context, method string, err error) error {
+	if status.Code(err) == codes.DeadlineExceeded && ctx.Err() == nil {
+		return &RPCTimeoutError{Method: method, Timeout: g.rpcTimeout, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+)
+
+// hungDeviceServer never answers until the call is cancelled.
+type hungDeviceServer struct {
+	pb.UnimplementedDeviceServiceServer
+}
+
+func (hungDeviceServer) GetDevicesPage(ctx context.Context, in *pb.DeviceListRequest) (*pb.DeviceListResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var _ = Describe("Vendor plugin RPC timeout", func() {
+	var g *GrpcPlugin
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		pathManager := utils.NewPathManager(root)
+		socket := pathManager.VendorPluginSocket()
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o700)).To(Succeed())
+
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		pb.RegisterDeviceServiceServer(server, hungDeviceServer{})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+
+		g, err = NewGrpcPlugin(false, "", nil, WithPathManager(*pathManager), WithRPCTimeout(100*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+	})
+
+	It("should fail a hung call with the method that timed out", func() {
+		_, err := g.GetDevices(context.Background())
+		var timeoutErr *RPCTimeoutError
+		Expect(errors.As(err, &timeoutErr)).To(BeTrue(), "unexpected error %v", err)
+		Expect(timeoutErr.Method).To(Equal(pb.DeviceService_GetDevicesPage_FullMethodName))
+		Expect(timeoutErr.Timeout).To(Equal(100 * time.Millisecond))
+	})
+
+	It("should honor an earlier deadline of the caller", func() {
+		WithRPCTimeout(time.Hour)(g)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := g.GetDevices(ctx)
+		Expect(err).To(HaveOccurred())
+		var timeoutErr *RPCTimeoutError
+		Expect(errors.As(err, &timeoutErr)).To(BeFalse())
+	})
+})
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type versionServer struct {
+	pb.UnimplementedLifeCycleServiceServer
+}
+
+func (versionServer) GetVersion(ctx context.Context, in *pb.Empty) (*pb.VersionInfo, error) {
+	return &pb.VersionInfo{Version: "1.2.3"}, nil
+}
+
+// selfSignedCert returns a certificate for "vendor-plugin" and its PEM encoding.
+func selfSignedCert() (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "vendor-plugin"},
+		DNSNames:              []string{"vendor-plugin"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// writeKeyPair writes the certificate and its key to PEM files.
+func writeKeyPair(cert tls.Certificate, certPEM []byte) (string, string) {
+	dir := GinkgoT().TempDir()
+	der, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	Expect(err).NotTo(HaveOccurred())
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	Expect(os.WriteFile(certFile, certPEM, 0o600)).To(Succeed())
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+	return certFile, keyFile
+}
+
+var _ = Describe("Vendor plugin TLS", func() {
+	var (
+		address   string
+		trustedCA string
+	)
+
+	BeforeEach(func() {
+		cert, certPEM := selfSignedCert()
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}})))
+		pb.RegisterLifeCycleServiceServer(server, versionServer{})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+		address = lis.Addr().String()
+
+		trustedCA = filepath.Join(GinkgoT().TempDir(), "ca.pem")
+		Expect(os.WriteFile(trustedCA, certPEM, 0o600)).To(Succeed())
+	})
+
+	It("should connect to a vendor plugin with a trusted certificate", func() {
+		config, err := LoadVendorTLSConfig(trustedCA, "", "", "vendor-plugin")
+		Expect(err).NotTo(HaveOccurred())
+		g, err := NewGrpcPlugin(false, "", nil, WithVendorAddress(address, config))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		version, err := g.GetVersion(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1.2.3"))
+	})
+
+	It("should refuse a vendor plugin with an untrusted certificate", func() {
+		_, otherCA := selfSignedCert()
+		caFile := filepath.Join(GinkgoT().TempDir(), "other-ca.pem")
+		Expect(os.WriteFile(caFile, otherCA, 0o600)).To(Succeed())
+
+		config, err := LoadVendorTLSConfig(caFile, "", "", "vendor-plugin")
+		Expect(err).NotTo(HaveOccurred())
+		g, err := NewGrpcPlugin(false, "", nil, WithVendorAddress(address, config))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		_, err = g.GetVersion(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("certificate")))
+	})
+
+	It("should authenticate with a client certificate to a vendor plugin requiring one", func() {
+		client, clientPEM := selfSignedCert()
+		clientCAs := x509.NewCertPool()
+		Expect(clientCAs.AppendCertsFromPEM(clientPEM)).To(BeTrue())
+		serverCert, serverPEM := selfSignedCert()
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		})))
+		pb.RegisterLifeCycleServiceServer(server, versionServer{})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+		Expect(os.WriteFile(caFile, serverPEM, 0o600)).To(Succeed())
+		certFile, keyFile := writeKeyPair(client, clientPEM)
+
+		config, err := LoadVendorTLSConfig(caFile, certFile, keyFile, "vendor-plugin")
+		Expect(err).NotTo(HaveOccurred())
+		g, err := NewGrpcPlugin(false, "", nil, WithVendorAddress(lis.Addr().String(), config))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+
+		version, err := g.GetVersion(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1.2.3"))
+	})
+
+	It("should reject partial TLS material", func() {
+		_, err := LoadVendorTLSConfig("", "", "", "vendor-plugin")
+		Expect(err).To(MatchError(ContainSubstring("needs a CA certificate")))
+		_, err = LoadVendorTLSConfig(trustedCA, "tls.crt", "", "vendor-plugin")
+		Expect(err).To(MatchError(ContainSubstring("tls.crt given without its key")))
+		_, err = LoadVendorTLSConfig(trustedCA, "", "tls.key", "vendor-plugin")
+		Expect(err).To(MatchError(ContainSubstring("tls.key given without its certificate")))
+	})
+
+	It("should reject a CA file without certificates", func() {
+		caFile := filepath.Join(GinkgoT().TempDir(), "empty.pem")
+		Expect(os.WriteFile(caFile, []byte("not a certificate"), 0o600)).To(Succeed())
+		_, err := LoadVendorTLSConfig(caFile, "", "", "vendor-plugin")
+		Expect(err).To(MatchError(ContainSubstring("no certificate found")))
+	})
+})
@@ -2,10 +2,17 @@ package plugin
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"maps"
 	"net"
+	"os"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -13,12 +20,26 @@ import (
 	"github.com/openshift/dpu-operator/internal/utils"
 	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const ReadyConditionType = "Ready"
+const (
+	ReadyConditionType = "Ready"
+
+	defaultDevicesPageSize = 256
+
+	// defaultStartTimeout bounds how long Start waits for the vendor plugin,
+	// which comes up concurrently with the daemon.
+	defaultStartTimeout = 5 * time.Minute
+	initialStartBackoff = 100 * time.Millisecond
+	maxStartBackoff     = 5 * time.Second
+)
 
 type DpuIdentifier string
 
@@ -29,8 +50,12 @@ type VendorPlugin interface {
 	DeleteBridgePort(bpr *opi.DeleteBridgePortRequest) error
 	CreateNetworkFunction(input string, output string) error
 	DeleteNetworkFunction(input string, output string) error
-	GetDevices() (*pb.DeviceListResponse, error)
-	SetNumVfs(vfCount int32) (*pb.VfCount, error)
+	GetDevices(ctx context.Context) (*pb.DeviceListResponse, error)
+	SetNumVfs(ctx context.Context, vfCount int32) (*pb.VfCount, error)
+	GetAnnotationTemplate(ctx context.Context) (*pb.AnnotationTemplate, error)
+	GetAllocateInfo(ctx context.Context, deviceID string) (*pb.AllocateInfo, error)
+	ResetDevice(ctx context.Context, deviceID string) error
+	GetVersion(ctx context.Context) (string, error)
 }
 
 type GrpcPlugin struct {
@@ -46,26 +71,65 @@ type GrpcPlugin struct {
 	pathManager   utils.PathManager
 	initialized   bool
 	initMutex     sync.RWMutex
+	// connMutex serializes dialing the vendor plugin.
+	connMutex sync.Mutex
+	// deviceClient replaces the DeviceService client of the connection, see
+	// WithDeviceServiceClient.
+	deviceClient pb.DeviceServiceClient
+
+	devicesPageSize int32
+	maxDevices      int
+	// vendorSocket is the unix socket of the vendor plugin, by default the
+	// one of the path manager.
+	vendorSocket string
+	// vendorAddress is the TCP address of a vendor plugin that isn't reached
+	// over the local unix socket, and tlsConfig secures the connection to it.
+	vendorAddress string
+	tlsConfig     *tls.Config
+	idleTimeout   time.Duration
+	rpcTimeout    time.Duration
+	startTimeout  time.Duration
+
+	// noStreaming and noPagination are set once the vendor plugin turned out
+	// not to implement GetDevicesStream or GetDevicesPage, so that we don't
+	// ask again on every poll. GetDevices may be called concurrently.
+	noStreaming  atomic.Bool
+	noPagination atomic.Bool
+
+	roles *roleFilter
+	// replicas is whether devices are shared as the vendor plugin reports.
+	replicas bool
 }
 
+// Start connects to the vendor plugin and initializes it. The vendor plugin
+// comes up concurrently with the daemon, so it's retried with exponential
+// backoff until it answers or the start timeout passes.
 func (g *GrpcPlugin) Start(ctx context.Context) (string, int32, error) {
 	start := time.Now()
-	interval := 100 * time.Millisecond
-
-	for {
-		select {
-		case <-ctx.Done():
-			return "", 0, ctx.Err()
-		default:
-		}
+	interval := initialStartBackoff
+	parent := ctx
+	if g.startTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.startTimeout)
+		defer cancel()
+	}
 
-		err := g.ensureConnected()
-		if err != nil {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if lastErr != nil {
 			select {
 			case <-ctx.Done():
-				return "", 0, ctx.Err()
+				if parent.Err() != nil {
+					return "", 0, parent.Err()
+				}
+				return "", 0, fmt.Errorf("vendor plugin did not become ready within %v after %d attempts: %v", g.startTimeout, attempt-1, lastErr)
 			case <-time.After(interval):
 			}
+			interval = min(2*interval, maxStartBackoff)
+		}
+
+		if err := g.ensureConnected(ctx); err != nil {
+			lastErr = err
 			continue
 		}
 
@@ -76,11 +140,10 @@ func (g *GrpcPlugin) Start(ctx context.Context) (string, int32, error) {
 				g.SetInitDone(false)
 				return "", 0, err
 			}
-			select {
-			case <-ctx.Done():
-				return "", 0, ctx.Err()
-			case <-time.After(interval):
+			if attempt == 1 {
+				g.log.Info("Vendor plugin is not ready yet, waiting for it", "err", err, "timeout", g.startTimeout)
 			}
+			lastErr = err
 			continue
 		}
 
@@ -94,6 +157,8 @@ func (g *GrpcPlugin) Start(ctx context.Context) (string, int32, error) {
 }
 
 func (g *GrpcPlugin) Close() {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
 	if g.conn != nil {
 		g.conn.Close()
 		g.conn = nil
@@ -110,6 +175,108 @@ func WithPathManager(pathManager utils.PathManager) func(*GrpcPlugin) {
 	}
 }
 
+// WithStartTimeout bounds how long Start waits for the vendor plugin to come
+// up, zero waits until the context of Start is done.
+func WithStartTimeout(timeout time.Duration) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.startTimeout = timeout
+	}
+}
+
+// WithLogLevel only logs messages up to the given verbosity, e.g. 0 to drop
+// the V(1) debug messages of the vendor plugin client.
+func WithLogLevel(level int) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.log = utils.LimitVerbosity(g.log, level)
+	}
+}
+
+// WithVendorSocket connects to the vendor plugin on the given unix socket
+// instead of the one of the path manager.
+func WithVendorSocket(path string) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.vendorSocket = path
+	}
+}
+
+// WithDeviceServiceClient serves the device calls from the given client
+// instead of the vendor plugin, e.g. a fake in tests. Connecting is lazy, so
+// without other calls no vendor plugin needs to be listening.
+func WithDeviceServiceClient(client pb.DeviceServiceClient) func(*GrpcPlugin) {
+	return func(g *GrpcPlugin) {
+		g.deviceClient = client
+	}
+}
+
+// WithDevicesPageSize sets how many devices are requested per GetDevicesPage
+// call or GetDevicesStream batch.
+func WithDevicesPageSize(pageSize int32) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.devicesPageSize = pageSize
+	}
+}
+
+// WithMaxDevices stops fetching further device pages or batches once
+// maxDevices devices were received, and keeps only the maxDevices devices
+// with the lowest IDs. Zero means no limit.
+func WithMaxDevices(maxDevices int) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.maxDevices = maxDevices
+	}
+}
+
+// WithVendorAddress reaches the vendor plugin over TCP at address instead of
+// the local unix socket. The connection is secured with tlsConfig, which
+// should only be nil on trusted networks.
+func WithVendorAddress(address string, tlsConfig *tls.Config) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.vendorAddress = address
+		d.tlsConfig = tlsConfig
+	}
+}
+
+// LoadVendorTLSConfig builds the TLS configuration to reach the vendor plugin
+// from PEM files. The vendor plugin's certificate is verified against caFile,
+// certFile and keyFile are optional and authenticate the daemon, but must be
+// given together.
+func LoadVendorTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, fmt.Errorf("vendor plugin TLS needs a CA certificate to verify the vendor plugin")
+	}
+	if certFile == "" && keyFile != "" {
+		return nil, fmt.Errorf("vendor plugin client key %s given without its certificate", keyFile)
+	}
+	if certFile != "" && keyFile == "" {
+		return nil, fmt.Errorf("vendor plugin client certificate %s given without its key", certFile)
+	}
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vendor plugin CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in vendor plugin CA %s", caFile)
+	}
+	config := &tls.Config{
+		RootCAs:    pool,
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vendor plugin client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewGrpcPlugin returns a client of the vendor plugin. Every device is
+// allocated whole to a single container unless sharing is enabled with
+// WithDeviceReplicas, e.g.:
+//
+//	NewGrpcPlugin(dpuMode, identifier, client, WithDeviceReplicas(true))
 func NewGrpcPlugin(dpuMode bool, dpuIdentifier DpuIdentifier, client client.Client, opts ...func(*GrpcPlugin)) (*GrpcPlugin, error) {
 	gp := &GrpcPlugin{
 		dpuMode:       dpuMode,
@@ -117,43 +284,92 @@ func NewGrpcPlugin(dpuMode bool, dpuIdentifier DpuIdentifier, client client.Clie
 		k8sClient:     client,
 		log:           ctrl.Log.WithName("GrpcPlugin"),
 		pathManager:   *utils.NewPathManager("/"),
+
+		devicesPageSize: defaultDevicesPageSize,
+		rpcTimeout:      defaultRPCTimeout,
+		startTimeout:    defaultStartTimeout,
 	}
 
 	for _, opt := range opts {
 		opt(gp)
 	}
+	if gp.vendorSocket == "" {
+		gp.vendorSocket = gp.pathManager.VendorPluginSocket()
+	}
 
 	return gp, nil
 }
 
-func (g *GrpcPlugin) ensureConnected() error {
-	if g.client != nil {
+// ensureConnected dials the vendor plugin unless already connected. A
+// connection that failed or was shut down is dialed again right away rather
+// than waiting for the gRPC reconnect backoff, which grows up to two minutes
+// while e.g. the vendor plugin pod restarts.
+func (g *GrpcPlugin) ensureConnected(ctx context.Context) error {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
+
+	if g.client != nil && !g.connectionStale() {
 		return nil
 	}
-	dialOptions := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-			return net.Dial("unix", addr)
-		}),
+	target, dialOptions := g.dialTarget()
+	// The timeout applies to each repeated call on its own.
+	dialOptions = append(dialOptions, grpc.WithChainUnaryInterceptor(g.reconnectOnIdleClose, g.enforceRPCTimeout))
+	if g.idleTimeout > 0 {
+		dialOptions = append(dialOptions, grpc.WithIdleTimeout(g.idleTimeout))
 	}
-
-	conn, err := grpc.DialContext(context.Background(), g.pathManager.VendorPluginSocket(), dialOptions...)
+	conn, err := grpc.DialContext(ctx, target, dialOptions...)
 
 	if err != nil {
 		g.log.Error(err, "Failed to connect to vendor plugin")
 		return err
 	}
+	if g.conn != nil {
+		g.log.Info("Connection to the vendor plugin went stale, redialed it", "state", g.conn.GetState())
+		g.conn.Close()
+	}
 	g.conn = conn
 
 	g.client = pb.NewLifeCycleServiceClient(conn)
 	g.nfclient = pb.NewNetworkFunctionServiceClient(conn)
 	g.opiClient = opi.NewBridgePortServiceClient(conn)
 	g.dsClient = pb.NewDeviceServiceClient(conn)
+	if g.deviceClient != nil {
+		g.dsClient = g.deviceClient
+	}
 	return nil
 }
 
+// connectionStale returns whether the connection can't be used anymore
+// without redialing. Clients set up without a connection are never stale.
+func (g *GrpcPlugin) connectionStale() bool {
+	if g.conn == nil {
+		return false
+	}
+	state := g.conn.GetState()
+	return state == connectivity.TransientFailure || state == connectivity.Shutdown
+}
+
+// dialTarget returns where and how to connect to the vendor plugin. The local
+// unix socket needs no transport security, it's only accessible to root.
+func (g *GrpcPlugin) dialTarget() (string, []grpc.DialOption) {
+	if g.vendorAddress == "" {
+		return g.vendorSocket, []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return net.Dial("unix", addr)
+			}),
+		}
+	}
+
+	if g.tlsConfig == nil {
+		g.log.Info("Warning: connecting to the vendor plugin over TCP without TLS", "address", g.vendorAddress)
+		return g.vendorAddress, []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	return g.vendorAddress, []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(g.tlsConfig))}
+}
+
 func (g *GrpcPlugin) CreateBridgePort(createRequest *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
-	err := g.ensureConnected()
+	err := g.ensureConnected(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("CreateBridgePort failed to ensure GRPC connection: %v", err)
 	}
@@ -161,7 +377,7 @@ func (g *GrpcPlugin) CreateBridgePort(createRequest *opi.CreateBridgePortRequest
 }
 
 func (g *GrpcPlugin) DeleteBridgePort(deleteRequest *opi.DeleteBridgePortRequest) error {
-	err := g.ensureConnected()
+	err := g.ensureConnected(context.Background())
 	if err != nil {
 		return fmt.Errorf("DeleteBridgePort failed to ensure GRPC connection: %v", err)
 	}
@@ -171,7 +387,7 @@ func (g *GrpcPlugin) DeleteBridgePort(deleteRequest *opi.DeleteBridgePortRequest
 
 func (g *GrpcPlugin) CreateNetworkFunction(input string, output string) error {
 	g.log.Info("CreateNetworkFunction", "input", input, "output", output)
-	err := g.ensureConnected()
+	err := g.ensureConnected(context.Background())
 	if err != nil {
 		return fmt.Errorf("CreateNetworkFunction failed to ensure GRPC connection: %v", err)
 	}
@@ -182,7 +398,7 @@ func (g *GrpcPlugin) CreateNetworkFunction(input string, output string) error {
 
 func (g *GrpcPlugin) DeleteNetworkFunction(input string, output string) error {
 	g.log.Info("DeleteNetworkFunction", "input", input, "output", output)
-	err := g.ensureConnected()
+	err := g.ensureConnected(context.Background())
 	if err != nil {
 		return fmt.Errorf("DeleteNetworkFunction failed to ensure GRPC connection: %v", err)
 	}
@@ -191,23 +407,205 @@ func (g *GrpcPlugin) DeleteNetworkFunction(input string, output string) error {
 	return err
 }
 
-func (g *GrpcPlugin) GetDevices() (*pb.DeviceListResponse, error) {
-	err := g.ensureConnected()
+// GetDevices returns the devices of the vendor plugin that have one of the
+// advertised roles, see WithDeviceRoles. Replicas are only reported when
+// sharing devices is enabled, see WithDeviceReplicas.
+func (g *GrpcPlugin) GetDevices(ctx context.Context) (*pb.DeviceListResponse, error) {
+	devices, err := g.inventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.filterRoles(devices)
+	g.applyReplicas(devices)
+	return devices, nil
+}
+
+// inventory gets the inventory through GetDevicesStream, falling back to
+// GetDevicesPage and then GetDevices for vendor plugins that don't implement
+// them.
+func (g *GrpcPlugin) inventory(ctx context.Context) (*pb.DeviceListResponse, error) {
+	devices, err := g.fetchInventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.truncateDevices(devices)
+	return devices, nil
+}
+
+func (g *GrpcPlugin) fetchInventory(ctx context.Context) (*pb.DeviceListResponse, error) {
+	err := g.ensureConnected(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("GetDevices failed to ensure GRPC connection: %v", err)
 	}
-	return g.dsClient.GetDevices(context.Background(), &pb.Empty{})
+	if !g.noStreaming.Load() {
+		devices, err := g.streamDevices(ctx)
+		if status.Code(err) != codes.Unimplemented {
+			return devices, err
+		}
+		g.log.Info("Vendor plugin does not support streaming GetDevices, falling back to pages")
+		g.noStreaming.Store(true)
+	}
+	if g.noPagination.Load() {
+		return g.dsClient.GetDevices(ctx, &pb.Empty{})
+	}
+
+	devices := &pb.DeviceListResponse{Devices: make(map[string]*pb.Device)}
+	pageToken := ""
+	for {
+		page, err := g.dsClient.GetDevicesPage(ctx, &pb.DeviceListRequest{
+			PageSize:  g.devicesPageSize,
+			PageToken: pageToken,
+		})
+		if status.Code(err) == codes.Unimplemented && pageToken == "" {
+			g.log.Info("Vendor plugin does not support paginated GetDevices, falling back to a single page")
+			g.noPagination.Store(true)
+			return g.dsClient.GetDevices(ctx, &pb.Empty{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetDevicesPage failed after %d devices: %w", len(devices.Devices), err)
+		}
+
+		for id, device := range page.Devices {
+			devices.Devices[id] = device
+		}
+
+		if page.NextPageToken == "" {
+			return devices, nil
+		}
+		if page.NextPageToken == pageToken {
+			return nil, fmt.Errorf("GetDevicesPage returned the same page token %q twice", pageToken)
+		}
+		if g.maxDevices > 0 && len(devices.Devices) >= g.maxDevices {
+			g.log.Info("Reached the maximum number of devices, not fetching further pages", "maxDevices", g.maxDevices)
+			return devices, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// truncateDevices keeps the maxDevices devices with the lowest IDs. Pages and
+// batches are received whole, so the last one may overshoot the limit.
+func (g *GrpcPlugin) truncateDevices(devices *pb.DeviceListResponse) {
+	if g.maxDevices <= 0 || len(devices.GetDevices()) <= g.maxDevices {
+		return
+	}
+	for _, id := range slices.Sorted(maps.Keys(devices.Devices))[g.maxDevices:] {
+		delete(devices.Devices, id)
+	}
+}
+
+// streamDevices receives the inventory in batches from GetDevicesStream. The
+// whole stream is bounded by the RPC timeout, which the interceptor only
+// applies to unary calls. Unimplemented is returned as is.
+func (g *GrpcPlugin) streamDevices(ctx context.Context) (*pb.DeviceListResponse, error) {
+	streamCtx, cancel := g.rpcTimeoutContext(ctx)
+	defer cancel()
+	stream, err := g.dsClient.GetDevicesStream(streamCtx, &pb.DeviceListRequest{PageSize: g.devicesPageSize})
+	if err != nil {
+		return nil, g.streamError(ctx, err, 0)
+	}
+
+	devices := &pb.DeviceListResponse{Devices: make(map[string]*pb.Device)}
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return devices, nil
+		}
+		if err != nil {
+			return nil, g.streamError(ctx, err, len(devices.Devices))
+		}
+		for id, device := range batch.Devices {
+			devices.Devices[id] = device
+		}
+		if g.maxDevices > 0 && len(devices.Devices) >= g.maxDevices {
+			g.log.Info("Reached the maximum number of devices, not receiving further batches", "maxDevices", g.maxDevices)
+			return devices, nil
+		}
+	}
 }
 
-func (g *GrpcPlugin) SetNumVfs(count int32) (*pb.VfCount, error) {
-	err := g.ensureConnected()
+// streamError describes a failed GetDevicesStream. Unimplemented is only
+// reported before any device was received, vendor plugins don't fail with
+// it midway.
+func (g *GrpcPlugin) streamError(ctx context.Context, err error, received int) error {
+	if status.Code(err) == codes.Unimplemented && received == 0 {
+		return err
+	}
+	if timeoutErr := g.rpcTimeoutError(ctx, pb.DeviceService_GetDevicesStream_FullMethodName, err); timeoutErr != nil {
+		err = timeoutErr
+	}
+	return fmt.Errorf("GetDevicesStream failed after %d devices: %w", received, err)
+}
+
+func (g *GrpcPlugin) SetNumVfs(ctx context.Context, count int32) (*pb.VfCount, error) {
+	err := g.ensureConnected(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("SetNumvfs failed to ensure GRPC connection: %v", err)
 	}
 	c := &pb.VfCount{
 		VfCnt: count,
 	}
-	return g.dsClient.SetNumVfs(context.Background(), c)
+	return g.dsClient.SetNumVfs(ctx, c)
+}
+
+// GetAnnotationTemplate returns the per-device container annotations of the
+// vendor plugin. Vendor plugins that don't implement it get no annotations.
+func (g *GrpcPlugin) GetAnnotationTemplate(ctx context.Context) (*pb.AnnotationTemplate, error) {
+	err := g.ensureConnected(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetAnnotationTemplate failed to ensure GRPC connection: %v", err)
+	}
+	template, err := g.dsClient.GetAnnotationTemplate(ctx, &pb.Empty{})
+	if status.Code(err) == codes.Unimplemented {
+		return &pb.AnnotationTemplate{}, nil
+	}
+	return template, err
+}
+
+// GetAllocateInfo returns the device nodes and mounts of a device, none for
+// vendor plugins that don't implement it.
+func (g *GrpcPlugin) GetAllocateInfo(ctx context.Context, deviceID string) (*pb.AllocateInfo, error) {
+	err := g.ensureConnected(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetAllocateInfo failed to ensure GRPC connection: %v", err)
+	}
+	info, err := g.dsClient.GetAllocateInfo(ctx, &pb.AllocateInfoRequest{DeviceId: deviceID})
+	if status.Code(err) == codes.Unimplemented {
+		return &pb.AllocateInfo{}, nil
+	}
+	return info, err
+}
+
+// ResetDevice asks the vendor plugin to reset a device before a container
+// starts using it. Vendor plugins that don't implement it fail the reset, as
+// the Device Plugin only asks when configured to.
+func (g *GrpcPlugin) ResetDevice(ctx context.Context, deviceID string) error {
+	err := g.ensureConnected(ctx)
+	if err != nil {
+		return fmt.Errorf("ResetDevice failed to ensure GRPC connection: %v", err)
+	}
+	_, err = g.dsClient.ResetDevice(ctx, &pb.ResetDeviceRequest{DeviceId: deviceID})
+	if status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("vendor plugin doesn't implement ResetDevice: %v", err)
+	}
+	return err
+}
+
+// GetVersion returns the version of the vendor plugin, empty for vendor
+// plugins that don't report it.
+func (g *GrpcPlugin) GetVersion(ctx context.Context) (string, error) {
+	err := g.ensureConnected(ctx)
+	if err != nil {
+		return "", fmt.Errorf("GetVersion failed to ensure GRPC connection: %v", err)
+	}
+	info, err := g.client.GetVersion(ctx, &pb.Empty{})
+	if status.Code(err) == codes.Unimplemented {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return info.Version, nil
 }
 
 // IsInitialized returns true if the VSP has been successfully initialized
@@ -0,0 +1,333 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDeviceServiceClient serves numDevices devices, batchSize at a time
+// through GetDevicesStream, or else pageSize at a time through GetDevicesPage,
+// or else all at once through GetDevices.
+type fakeDeviceServiceClient struct {
+	numDevices  int
+	batchSize   int
+	pageSize    int
+	streamCalls int
+	pageCalls   int
+	unaryCalls  int
+	// streamErr fails GetDevicesStream after the first batch.
+	streamErr error
+	// roles are the roles of the devices in turn, none when empty.
+	roles []string
+
+	// annotations is returned by GetAnnotationTemplate, nil means unimplemented.
+	annotations map[string]string
+	// allocateInfo is returned by GetAllocateInfo, nil means unimplemented.
+	allocateInfo map[string]*pb.AllocateInfo
+	// reset records the devices passed to ResetDevice, nil means
+	// unimplemented.
+	reset []string
+}
+
+func (f *fakeDeviceServiceClient) device(i int) *pb.Device {
+	id := fmt.Sprintf("dev%d", i)
+	d := &pb.Device{ID: id, Health: "Healthy"}
+	if len(f.roles) > 0 {
+		d.Role = f.roles[i%len(f.roles)]
+	}
+	return d
+}
+
+func (f *fakeDeviceServiceClient) GetDevices(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.DeviceListResponse, error) {
+	f.unaryCalls++
+	resp := &pb.DeviceListResponse{Devices: map[string]*pb.Device{}}
+	for i := 0; i < f.numDevices; i++ {
+		d := f.device(i)
+		resp.Devices[d.ID] = d
+	}
+	return resp, nil
+}
+
+func (f *fakeDeviceServiceClient) GetDevicesPage(ctx context.Context, in *pb.DeviceListRequest, opts ...grpc.CallOption) (*pb.DeviceListResponse, error) {
+	f.pageCalls++
+	if f.pageSize == 0 {
+		return nil, status.Error(codes.Unimplemented, "method GetDevicesPage not implemented")
+	}
+	start := 0
+	if in.PageToken != "" {
+		start, _ = strconv.Atoi(in.PageToken)
+	}
+	resp := &pb.DeviceListResponse{Devices: map[string]*pb.Device{}}
+	end := min(start+f.pageSize, f.numDevices)
+	for i := start; i < end; i++ {
+		d := f.device(i)
+		resp.Devices[d.ID] = d
+	}
+	if end < f.numDevices {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+// fakeDeviceStream sends the batches and then fails with err, or ends.
+type fakeDeviceStream struct {
+	grpc.ClientStream
+	batches []*pb.DeviceListResponse
+	err     error
+}
+
+func (s *fakeDeviceStream) Recv() (*pb.DeviceListResponse, error) {
+	if len(s.batches) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	batch := s.batches[0]
+	s.batches = s.batches[1:]
+	return batch, nil
+}
+
+func (f *fakeDeviceServiceClient) GetDevicesStream(ctx context.Context, in *pb.DeviceListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.DeviceListResponse], error) {
+	f.streamCalls++
+	if f.batchSize == 0 {
+		// Like gRPC, report the missing method on the first receive.
+		return &fakeDeviceStream{err: status.Error(codes.Unimplemented, "method GetDevicesStream not implemented")}, nil
+	}
+	stream := &fakeDeviceStream{}
+	for start := 0; start < f.numDevices; start += f.batchSize {
+		batch := &pb.DeviceListResponse{Devices: map[string]*pb.Device{}}
+		for i := start; i < min(start+f.batchSize, f.numDevices); i++ {
+			d := f.device(i)
+			batch.Devices[d.ID] = d
+		}
+		stream.batches = append(stream.batches, batch)
+		if f.streamErr != nil {
+			stream.err = f.streamErr
+			break
+		}
+	}
+	return stream, nil
+}
+
+func (f *fakeDeviceServiceClient) SetNumVfs(ctx context.Context, in *pb.VfCount, opts ...grpc.CallOption) (*pb.VfCount, error) {
+	return in, nil
+}
+
+func (f *fakeDeviceServiceClient) GetAnnotationTemplate(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.AnnotationTemplate, error) {
+	if f.annotations == nil {
+		return nil, status.Error(codes.Unimplemented, "method GetAnnotationTemplate not implemented")
+	}
+	return &pb.AnnotationTemplate{Annotations: f.annotations}, nil
+}
+
+func (f *fakeDeviceServiceClient) GetAllocateInfo(ctx context.Context, in *pb.AllocateInfoRequest, opts ...grpc.CallOption) (*pb.AllocateInfo, error) {
+	if f.allocateInfo == nil {
+		return nil, status.Error(codes.Unimplemented, "method GetAllocateInfo not implemented")
+	}
+	return f.allocateInfo[in.DeviceId], nil
+}
+
+func (f *fakeDeviceServiceClient) ResetDevice(ctx context.Context, in *pb.ResetDeviceRequest, opts ...grpc.CallOption) (*pb.Empty, error) {
+	if f.reset == nil {
+		return nil, status.Error(codes.Unimplemented, "method ResetDevice not implemented")
+	}
+	f.reset = append(f.reset, in.DeviceId)
+	return &pb.Empty{}, nil
+}
+
+func newTestGrpcPlugin(ds pb.DeviceServiceClient, opts ...func(*GrpcPlugin)) *GrpcPlugin {
+	g, err := NewGrpcPlugin(false, "", nil, opts...)
+	Expect(err).NotTo(HaveOccurred())
+	// Pretend we are already connected so that ensureConnected doesn't dial.
+	g.client = pb.NewLifeCycleServiceClient(nil)
+	g.dsClient = ds
+	return g
+}
+
+var _ = Describe("GrpcPlugin", func() {
+	Context("GetDevices", func() {
+		It("should assemble the inventory from a stream of batches", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, batchSize: 4, pageSize: 4}
+			g := newTestGrpcPlugin(fake)
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Devices).To(HaveLen(10))
+			Expect(fake.streamCalls).To(Equal(1))
+			Expect(fake.pageCalls).To(Equal(0))
+		})
+
+		It("should stop receiving batches once the maximum is reached", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, batchSize: 4}
+			g := newTestGrpcPlugin(fake, WithMaxDevices(5))
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slices.Sorted(maps.Keys(resp.Devices))).To(Equal([]string{"dev0", "dev1", "dev2", "dev3", "dev4"}))
+		})
+
+		It("should fail rather than return part of the inventory when the stream breaks", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, batchSize: 4, pageSize: 4, streamErr: status.Error(codes.Unavailable, "connection reset")}
+			g := newTestGrpcPlugin(fake)
+
+			_, err := g.GetDevices(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("GetDevicesStream failed after 4 devices")))
+			Expect(fake.pageCalls).To(Equal(0))
+		})
+
+		It("should fall back to pages when streaming is not supported", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, pageSize: 4}
+			g := newTestGrpcPlugin(fake)
+
+			for i := 0; i < 2; i++ {
+				resp, err := g.GetDevices(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.Devices).To(HaveLen(10))
+			}
+			Expect(fake.streamCalls).To(Equal(1))
+			Expect(fake.pageCalls).To(Equal(6))
+		})
+
+		It("should assemble the inventory from multiple pages", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, pageSize: 4}
+			g := newTestGrpcPlugin(fake)
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Devices).To(HaveLen(10))
+			Expect(fake.pageCalls).To(Equal(3))
+			Expect(fake.unaryCalls).To(Equal(0))
+		})
+
+		It("should stop fetching pages once the maximum is reached", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 10, pageSize: 4}
+			g := newTestGrpcPlugin(fake, WithMaxDevices(5))
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slices.Sorted(maps.Keys(resp.Devices))).To(Equal([]string{"dev0", "dev1", "dev2", "dev3", "dev4"}))
+			Expect(fake.pageCalls).To(Equal(2))
+		})
+
+		It("should fall back to a single page when pagination is not supported", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 3}
+			g := newTestGrpcPlugin(fake)
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Devices).To(HaveLen(3))
+
+			_, err = g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.pageCalls).To(Equal(1))
+			Expect(fake.unaryCalls).To(Equal(2))
+		})
+
+		It("should keep at most the maximum of devices from a single page", func() {
+			fake := &fakeDeviceServiceClient{numDevices: 8}
+			g := newTestGrpcPlugin(fake, WithMaxDevices(5))
+
+			resp, err := g.GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slices.Sorted(maps.Keys(resp.Devices))).To(Equal([]string{"dev0", "dev1", "dev2", "dev3", "dev4"}))
+		})
+	})
+
+	Context("role filter", func() {
+		var fake *fakeDeviceServiceClient
+
+		BeforeEach(func() {
+			// dev0 and dev3 are management functions, dev2 and dev5 have no role
+			fake = &fakeDeviceServiceClient{numDevices: 6, roles: []string{RoleManagement, RoleData, ""}}
+		})
+
+		ids := func(resp *pb.DeviceListResponse) []string {
+			var ids []string
+			for id := range resp.Devices {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return ids
+		}
+
+		DescribeTable("should only advertise the allowed roles",
+			func(include, exclude []string, expected []string) {
+				g := newTestGrpcPlugin(fake, WithDeviceRoles(include, exclude))
+				for i := 0; i < 2; i++ {
+					resp, err := g.GetDevices(context.Background())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ids(resp)).To(Equal(expected))
+				}
+				Expect(g.roles.filtered).To(HaveLen(6 - len(expected)))
+			},
+			Entry("including data", []string{RoleData}, nil, []string{"dev1", "dev4"}),
+			Entry("including data and unknown roles", []string{RoleData, ""}, nil, []string{"dev1", "dev2", "dev4", "dev5"}),
+			Entry("excluding management", nil, []string{RoleManagement}, []string{"dev1", "dev2", "dev4", "dev5"}),
+			Entry("including data but excluding it", []string{RoleData}, []string{RoleData}, nil),
+		)
+
+		It("should advertise all devices without a filter", func() {
+			resp, err := newTestGrpcPlugin(fake).GetDevices(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Devices).To(HaveLen(6))
+		})
+	})
+
+	Context("GetAnnotationTemplate", func() {
+		It("should return the template of the vendor plugin", func() {
+			fake := &fakeDeviceServiceClient{annotations: map[string]string{"hook": "{{.ID}}"}}
+			template, err := newTestGrpcPlugin(fake).GetAnnotationTemplate(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(template.Annotations).To(Equal(fake.annotations))
+		})
+
+		It("should return no annotations when the vendor plugin doesn't implement it", func() {
+			template, err := newTestGrpcPlugin(&fakeDeviceServiceClient{}).GetAnnotationTemplate(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(template.Annotations).To(BeEmpty())
+		})
+	})
+
+	Context("GetAllocateInfo", func() {
+		It("should return the device nodes and mounts of the device", func() {
+			info := &pb.AllocateInfo{Devices: []*pb.DeviceNode{{HostPath: "/dev/vfio/12"}}}
+			fake := &fakeDeviceServiceClient{allocateInfo: map[string]*pb.AllocateInfo{"dev0": info}}
+			got, err := newTestGrpcPlugin(fake).GetAllocateInfo(context.Background(), "dev0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(info))
+		})
+
+		It("should return nothing when the vendor plugin doesn't implement it", func() {
+			info, err := newTestGrpcPlugin(&fakeDeviceServiceClient{}).GetAllocateInfo(context.Background(), "dev0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Devices).To(BeEmpty())
+			Expect(info.Mounts).To(BeEmpty())
+		})
+	})
+
+	Context("ResetDevice", func() {
+		It("should reset the device", func() {
+			fake := &fakeDeviceServiceClient{reset: []string{}}
+			Expect(newTestGrpcPlugin(fake).ResetDevice(context.Background(), "dev0")).To(Succeed())
+			Expect(fake.reset).To(Equal([]string{"dev0"}))
+		})
+
+		It("should fail when the vendor plugin doesn't implement it", func() {
+			err := newTestGrpcPlugin(&fakeDeviceServiceClient{}).ResetDevice(context.Background(), "dev0")
+			Expect(err).To(MatchError(ContainSubstring("doesn't implement ResetDevice")))
+		})
+	})
+})
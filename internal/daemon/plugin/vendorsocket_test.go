@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+)
+
+var _ = Describe("Vendor plugin socket override", func() {
+	var (
+		root   string
+		socket string
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp("", "dp")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, root)
+		socket = filepath.Join(root, "elsewhere", "vsp.sock")
+		Expect(os.MkdirAll(filepath.Dir(socket), 0o700)).To(Succeed())
+
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		server := grpc.NewServer()
+		pb.RegisterDeviceServiceServer(server, deviceServer{})
+		go server.Serve(lis)
+		DeferCleanup(server.Stop)
+	})
+
+	getDevices := func(opts ...func(*GrpcPlugin)) error {
+		g, err := NewGrpcPlugin(false, "", nil, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(g.Close)
+		_, err = g.GetDevices(context.Background())
+		return err
+	}
+
+	It("should connect to the socket of the path manager override", func() {
+		pathManager := utils.NewPathManager(root, utils.WithVendorPluginSocket(socket))
+		Expect(pathManager.VendorPluginSocket()).To(Equal(socket))
+		Expect(getDevices(WithPathManager(*pathManager))).To(Succeed())
+	})
+
+	It("should connect to the socket given to the plugin", func() {
+		Expect(getDevices(WithPathManager(*utils.NewPathManager(root)), WithVendorSocket(socket))).To(Succeed())
+	})
+
+	It("should not find a vendor plugin listening elsewhere without the override", func() {
+		Expect(getDevices(WithPathManager(*utils.NewPathManager(root)), WithRPCTimeout(time.Second))).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// verbositySink drops the messages of a logger above a verbosity, on top of
+// what the underlying sink drops.
+type verbositySink struct {
+	logr.LogSink
+	level int
+}
+
+func (s *verbositySink) Enabled(level int) bool {
+	return level <= s.level && s.LogSink.Enabled(level)
+}
+
+func (s *verbositySink) Info(level int, msg string, keysAndValues ...any) {
+	if level <= s.level {
+		s.LogSink.Info(level, msg, keysAndValues...)
+	}
+}
+
+func (s *verbositySink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &verbositySink{LogSink: s.LogSink.WithValues(keysAndValues...), level: s.level}
+}
+
+func (s *verbositySink) WithName(name string) logr.LogSink {
+	return &verbositySink{LogSink: s.LogSink.WithName(name), level: s.level}
+}
+
+func (s *verbositySink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &verbositySink{LogSink: sink.WithCallDepth(depth), level: s.level}
+	}
+	return s
+}
+
+// LimitVerbosity returns a logger that only logs messages up to the given
+// verbosity, e.g. 0 to drop the V(1) debug messages of a component while the
+// rest of the process logs them. Errors are always logged. The verbosity of
+// the process, set when creating its logger, can't be raised this way.
+func LimitVerbosity(log logr.Logger, level int) logr.Logger {
+	sink := log.GetSink()
+	if sink == nil {
+		return log
+	}
+	// Skip the frame of verbositySink when reporting the caller.
+	if withDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withDepth.WithCallDepth(1)
+	}
+	return log.WithSink(&verbositySink{LogSink: sink, level: level})
+}
@@ -0,0 +1,42 @@
+package utils_test
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/utils"
+)
+
+var _ = Describe("LimitVerbosity", func() {
+	var logs []string
+
+	BeforeEach(func() {
+		logs = nil
+	})
+
+	newLogger := func(verbosity int) logr.Logger {
+		return funcr.New(func(prefix, args string) {
+			logs = append(logs, prefix+" "+args)
+		}, funcr.Options{Verbosity: verbosity})
+	}
+
+	It("should drop the messages above the verbosity", func() {
+		log := utils.LimitVerbosity(newLogger(2), 0).WithName("test").WithValues("k", "v")
+		log.Info("info")
+		log.V(1).Info("debug")
+		log.Error(errors.New("failed"), "error")
+		Expect(logs).To(HaveLen(2))
+		Expect(logs[0]).To(ContainSubstring(`"msg"="info"`))
+		Expect(logs[0]).To(ContainSubstring(`"k"="v"`))
+		Expect(logs[1]).To(ContainSubstring(`"msg"="error"`))
+	})
+
+	It("should not log more than the underlying logger", func() {
+		log := utils.LimitVerbosity(newLogger(0), 2)
+		log.V(1).Info("debug")
+		Expect(logs).To(BeEmpty())
+	})
+})
@@ -11,10 +11,25 @@ import (
 
 type PathManager struct {
 	rootDir string
+	// vendorPluginSocket overrides where the vendor plugin listens.
+	vendorPluginSocket string
 }
 
-func NewPathManager(rootDir string) *PathManager {
-	return &PathManager{rootDir: rootDir}
+func NewPathManager(rootDir string, opts ...func(*PathManager)) *PathManager {
+	p := &PathManager{rootDir: rootDir}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithVendorPluginSocket makes the vendor plugin listen on, and the daemon
+// connect to, the given socket instead of the default one. The path isn't
+// relative to the root directory.
+func WithVendorPluginSocket(path string) func(*PathManager) {
+	return func(p *PathManager) {
+		p.vendorPluginSocket = path
+	}
 }
 
 func (p *PathManager) CNIServerPath() string {
@@ -26,6 +41,10 @@ func (p *PathManager) KubeletEndPoint() string {
 	return p.wrap("/var/lib/kubelet/device-plugins/kubelet.sock")
 }
 
+func (p *PathManager) KubeletPodResourcesEndPoint() string {
+	return p.wrap("/var/lib/kubelet/pod-resources/kubelet.sock")
+}
+
 func (p *PathManager) PluginEndpoint() string {
 	return p.wrap("/var/lib/kubelet/device-plugins/dpuNet.sock")
 }
@@ -34,6 +53,32 @@ func (p *PathManager) PluginEndpointFilename() string {
 	return filepath.Base(p.PluginEndpoint())
 }
 
+func (p *PathManager) DevicePluginIntrospectionPath() string {
+	return p.wrap("/var/run/dpu-daemon/device-plugin/introspection.sock")
+}
+
+// DevicePluginDeviceInfoDir holds a file per allocated device, see
+// deviceplugin.DeviceInfo.
+func (p *PathManager) DevicePluginDeviceInfoDir() string {
+	return p.wrap("/var/run/dpu-daemon/device-plugin/devinfo")
+}
+
+// DevicePluginCheckpointDir holds a checkpoint of the allocations per
+// resource, see deviceplugin.Allocation.
+func (p *PathManager) DevicePluginCheckpointDir() string {
+	return p.wrap("/var/run/dpu-daemon/device-plugin/checkpoints")
+}
+
+// DevicePluginCapacityDir holds the advertised capacity per resource, see
+// deviceplugin.CapacityStatus.
+func (p *PathManager) DevicePluginCapacityDir() string {
+	return p.wrap("/var/run/dpu-daemon/device-plugin/capacity")
+}
+
+func (p *PathManager) DevicePluginDiagnosticsPath() string {
+	return p.wrap("/var/run/dpu-daemon/device-plugin/diagnostics.json")
+}
+
 func (p *PathManager) CniPath() string {
 	return "/var/lib/cni/bin/dpu-cni"
 }
@@ -56,6 +101,9 @@ func (p *PathManager) CniHostDir(clusterFlavour Flavour, filesystemMode Filesyst
 }
 
 func (p *PathManager) VendorPluginSocket() string {
+	if p.vendorPluginSocket != "" {
+		return p.vendorPluginSocket
+	}
 	return p.wrap("/var/run/dpu-daemon/vendor-plugin/vendor-plugin.sock")
 }
 
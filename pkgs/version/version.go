@@ -0,0 +1,8 @@
+package version
+
+// Version and Commit describe the build of the running binary. They are
+// overridden at build time through -ldflags, see taskfiles/binaries.yaml.
+var (
+	Version = "unknown"
+	Commit  = "unknown"
+)